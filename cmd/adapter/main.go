@@ -24,6 +24,7 @@ import (
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/cache"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/lexicon"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/server"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/telemetry"
 )
@@ -47,6 +48,20 @@ func (l *lazyTTSServer) StreamSynthesis(req *napv1.StreamSynthesisRequest, strea
 	return (*srv).StreamSynthesis(req, stream)
 }
 
+// Synthesize serves the duplex synthesis service once the underlying server
+// is set.
+func (l *lazyTTSServer) Synthesize(stream server.DuplexSynthesisService_SynthesizeServer) error {
+	srv := l.server.Load()
+	if srv == nil {
+		return status.Error(codes.Unavailable, "TTS service is initializing, please retry in a moment")
+	}
+	duplex, ok := (*srv).(server.DuplexSynthesisServiceServer)
+	if !ok {
+		return status.Error(codes.Unimplemented, "duplex synthesis is not supported")
+	}
+	return duplex.Synthesize(stream)
+}
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -65,13 +80,48 @@ func main() {
 		"listen_addr", cfg.ListenAddr,
 		"voice_id", cfg.VoiceID,
 		"model", cfg.Model,
+		"synthesis_backend", cfg.Backend,
+		"output_format", cfg.OutputFormat,
+		"text_normalization", cfg.TextNormalization,
+		"sentence_segmentation", cfg.SentenceSegmentation,
+		"synthesis_concurrency", cfg.SynthesisConcurrency,
+		"timestamps", cfg.Timestamps,
+		"visemes", cfg.Visemes,
+		"request_stitching", cfg.RequestStitching,
+		"trim_silence", cfg.TrimSilence,
+		"silence_threshold_db", logFloatPtrField(cfg.SilenceThresholdDB),
+		"silence_padding_ms", cfg.SilencePaddingMs,
+		"loudness_normalization", cfg.LoudnessNormalization,
+		"loudness_target_lufs", logFloatPtrField(cfg.LoudnessTargetLUFS),
+		"loudness_max_gain_db", logFloatPtrField(cfg.LoudnessMaxGainDB),
+		"fade_ms", cfg.FadeMs,
+		"frame_ms", cfg.FrameMs,
+		"paced_delivery", cfg.PacedDelivery,
+		"pacing_lead_ms", cfg.PacingLeadMs,
+		"max_concurrent_requests", cfg.MaxConcurrentRequests,
+		"queue_timeout_ms", cfg.QueueTimeoutMs,
+		"retry_max_attempts", cfg.RetryMaxAttempts,
+		"circuit_breaker_threshold", cfg.CircuitBreakerThreshold,
 		"stability", logFloatPtrField(cfg.Stability),
 		"similarity_boost", logFloatPtrField(cfg.SimilarityBoost),
 		"optimize_streaming_latency", logIntPtrField(cfg.OptimizeStreamingLatency),
+		"style", logFloatPtrField(cfg.Style),
+		"use_speaker_boost", logBoolPtrField(cfg.UseSpeakerBoost),
+		"speed", logFloatPtrField(cfg.Speed),
 	)
 
 	recorder := telemetry.NewRecorder(logger)
 
+	// STEP 0: Verify the configured voice before binding, so a typo fails
+	// startup rather than the first utterance, and never after the port has
+	// been reported ready.
+	if !cfg.UseStubSynthesizer {
+		if err := checkVoice(ctx, cfg, logger); err != nil {
+			logger.Error("configured voice cannot be used", "voice_id", cfg.VoiceID, "model", cfg.Model, "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// STEP 1: Bind port IMMEDIATELY (before initializing client)
 	// This allows the manager's readiness check to succeed while client initializes.
 	lis, err := net.Listen("tcp", cfg.ListenAddr)
@@ -87,12 +137,21 @@ func main() {
 	healthServer := health.NewServer()
 	healthgrpc.RegisterHealthServer(grpcServer, healthServer)
 
-	serviceName := napv1.TextToSpeechService_ServiceDesc.ServiceName
-	healthServer.SetServingStatus("", healthgrpc.HealthCheckResponse_NOT_SERVING)
-	healthServer.SetServingStatus(serviceName, healthgrpc.HealthCheckResponse_NOT_SERVING)
+	serviceNames := []string{
+		"",
+		napv1.TextToSpeechService_ServiceDesc.ServiceName,
+		server.DuplexSynthesisService_ServiceDesc.ServiceName,
+	}
+	setServingStatus := func(st healthgrpc.HealthCheckResponse_ServingStatus) {
+		for _, name := range serviceNames {
+			healthServer.SetServingStatus(name, st)
+		}
+	}
+	setServingStatus(healthgrpc.HealthCheckResponse_NOT_SERVING)
 
 	lazyService := &lazyTTSServer{}
 	napv1.RegisterTextToSpeechServiceServer(grpcServer, lazyService)
+	server.RegisterDuplexSynthesisServiceServer(grpcServer, lazyService)
 
 	// STEP 3: Start gRPC server in background (port is already bound)
 	serverErr := make(chan error, 1)
@@ -105,13 +164,31 @@ func main() {
 
 	// STEP 4: Initialize synthesizer
 	var synthesizer elevenlabs.Synthesizer
-	if cfg.UseStubSynthesizer {
+	switch {
+	case cfg.UseStubSynthesizer:
 		synthesizer = elevenlabs.NewStubSynthesizer(logger)
 		logger.Info("using STUB synthesizer — responses are deterministic, NOT from ElevenLabs API")
-	} else {
+	case cfg.Backend == config.BackendWebSocket:
+		synthesizer = elevenlabs.NewWebSocketSynthesizer(cfg.APIKey)
+		logger.Info("ElevenLabs WebSocket synthesizer initialized")
+	default:
 		synthesizer = elevenlabs.NewClient(cfg.APIKey)
 		logger.Info("ElevenLabs client initialized")
 	}
+	if !cfg.UseStubSynthesizer {
+		synthesizer = elevenlabs.NewRetryingSynthesizer(synthesizer,
+			elevenlabs.RetryPolicy{
+				MaxAttempts: cfg.RetryMaxAttempts,
+				BaseDelay:   time.Duration(cfg.RetryBaseDelayMs) * time.Millisecond,
+				MaxDelay:    time.Duration(cfg.RetryMaxDelayMs) * time.Millisecond,
+			},
+			elevenlabs.CircuitBreakerPolicy{
+				FailureThreshold: cfg.CircuitBreakerThreshold,
+				Cooldown:         time.Duration(cfg.CircuitBreakerCooldownMs) * time.Millisecond,
+			},
+			logger,
+		)
+	}
 
 	// STEP 5: Initialize cache (if configured)
 	var audioCache *cache.Cache
@@ -125,20 +202,40 @@ func main() {
 		}
 	}
 
+	// STEP 5b: Load the pronunciation lexicon (if configured)
+	var serverOpts []server.Option
+	if cfg.LexiconPath != "" {
+		lex, err := lexicon.Load(cfg.LexiconPath)
+		if err != nil {
+			logger.Error("failed to load lexicon", "path", cfg.LexiconPath, "error", err)
+			os.Exit(1)
+		}
+		dictionary := uploadLexicon(ctx, cfg, lex, logger)
+		serverOpts = append(serverOpts, server.WithLexicon(lex, dictionary))
+		appliedAs := config.LexiconModeRewrite
+		if dictionary != nil {
+			appliedAs = config.LexiconModeUpload
+		}
+		logger.Info("pronunciation lexicon loaded",
+			"path", cfg.LexiconPath,
+			"entries", len(lex.Rules),
+			"version", lex.Version,
+			"applied_as", appliedAs,
+		)
+	}
+
 	// STEP 6: Activate the real TTS service now that client is ready
-	realService := server.New(cfg, logger, synthesizer, recorder, audioCache)
+	realService := server.New(cfg, logger, synthesizer, recorder, audioCache, serverOpts...)
 	lazyService.setServer(realService)
 
-	healthServer.SetServingStatus("", healthgrpc.HealthCheckResponse_SERVING)
-	healthServer.SetServingStatus(serviceName, healthgrpc.HealthCheckResponse_SERVING)
+	setServingStatus(healthgrpc.HealthCheckResponse_SERVING)
 	logger.Info("adapter ready to serve requests")
 
 	// STEP 7: Setup graceful shutdown
 	go func() {
 		<-ctx.Done()
 		logger.Info("shutdown requested, stopping gRPC server")
-		healthServer.SetServingStatus(serviceName, healthgrpc.HealthCheckResponse_NOT_SERVING)
-		healthServer.SetServingStatus("", healthgrpc.HealthCheckResponse_NOT_SERVING)
+		setServingStatus(healthgrpc.HealthCheckResponse_NOT_SERVING)
 
 		stopped := make(chan struct{})
 		go func() {
@@ -166,6 +263,66 @@ func main() {
 	logger.Info("adapter stopped")
 }
 
+// voiceCheckTimeout bounds the startup voice check so an unreachable catalog
+// does not hold up readiness.
+const voiceCheckTimeout = 5 * time.Second
+
+// checkVoice looks up the configured voice and model in the ElevenLabs
+// catalog. It returns an error only when the catalog answers that the voice
+// does not exist. A model missing from the catalog is only logged, as the
+// model list may not show every model the account can use; so is any other
+// failure, such as a network error or an API key without catalog access.
+func checkVoice(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, voiceCheckTimeout)
+	defer cancel()
+
+	voice, err := elevenlabs.NewClient(cfg.APIKey).CheckVoice(ctx, cfg.VoiceID, cfg.Model)
+	if elevenlabs.KindOf(err) == elevenlabs.KindVoiceNotFound {
+		return err
+	}
+	if voice == nil {
+		logger.Warn("could not verify voice against ElevenLabs catalog, continuing", "voice_id", cfg.VoiceID, "error", err)
+		return nil
+	}
+	if err != nil {
+		logger.Warn("could not verify model against ElevenLabs catalog, continuing", "model", cfg.Model, "error", err)
+	}
+
+	if !voice.SupportsModel(cfg.Model) {
+		logger.Warn("voice is not tuned for the configured model, quality may suffer",
+			"voice_id", cfg.VoiceID,
+			"model", cfg.Model,
+			"voice_models", strings.Join(voice.HighQualityBaseModelIDs, ","),
+		)
+	}
+	logger.Info("voice verified", "voice_id", voice.VoiceID, "voice_name", voice.Name, "category", voice.Category)
+	return nil
+}
+
+// uploadLexicon makes lex available as an ElevenLabs pronunciation dictionary
+// when lexicon_mode is "upload", reusing a dictionary uploaded by an earlier
+// run with the same contents. It returns nil, falling back to local
+// rewriting, when upload mode is off or the upload fails.
+func uploadLexicon(ctx context.Context, cfg config.Config, lex *lexicon.Lexicon, logger *slog.Logger) *elevenlabs.PronunciationDictionaryLocator {
+	if cfg.LexiconMode != config.LexiconModeUpload || cfg.UseStubSynthesizer {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, voiceCheckTimeout)
+	defer cancel()
+
+	client := elevenlabs.NewClient(cfg.APIKey)
+	name := "nupi-lexicon-" + lex.Version
+	loc, found, err := client.FindPronunciationDictionary(ctx, name)
+	if err == nil && !found {
+		loc, err = client.UploadPronunciationDictionary(ctx, name, lex.PLS())
+	}
+	if err != nil {
+		logger.Warn("failed to upload pronunciation dictionary, rewriting text locally instead", "error", err)
+		return nil
+	}
+	return &loc
+}
+
 func newLogger(level string) *slog.Logger {
 	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: parseLevel(level),
@@ -201,3 +358,10 @@ func logIntPtrField(v *int) any {
 	}
 	return *v
 }
+
+func logBoolPtrField(v *bool) any {
+	if v == nil {
+		return "default"
+	}
+	return *v
+}
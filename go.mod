@@ -6,12 +6,12 @@ toolchain go1.24.9
 
 require (
 	github.com/nupi-ai/nupi v0.0.0-20251123222241-478598e2ce7a
+	golang.org/x/net v0.43.0
 	google.golang.org/grpc v1.64.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	golang.org/x/net v0.43.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
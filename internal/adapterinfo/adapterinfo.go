@@ -26,13 +26,20 @@ type Metadata struct {
 var Info = mustLoadMetadata()
 
 // SynthesisMetadata produces the standard metadata payload attached
-// to emitted TTS audio chunks.
-func SynthesisMetadata(model, voiceID string) map[string]string {
-	return map[string]string{
+// to emitted TTS audio chunks. Voice settings applied to the request are
+// echoed alongside the model and voice; empty values are omitted.
+func SynthesisMetadata(model, voiceID string, settings map[string]string) map[string]string {
+	metadata := map[string]string{
 		"generator": Info.GeneratorID,
 		"model":     model,
 		"voice_id":  voiceID,
 	}
+	for k, v := range settings {
+		if v != "" {
+			metadata[k] = v
+		}
+	}
+	return metadata
 }
 
 // Version returns the adapter semantic version.
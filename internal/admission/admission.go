@@ -0,0 +1,152 @@
+// Package admission limits the number of upstream synthesis requests in
+// flight and queues the rest by priority.
+package admission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueTimeout is returned when a request waited in the queue for longer
+// than the controller's maximum wait.
+var ErrQueueTimeout = errors.New("admission: queue wait exceeded, upstream at capacity")
+
+// Controller admits up to a fixed number of concurrent requests. Further
+// requests wait in a queue ordered by priority, higher first, and by arrival
+// within a priority.
+type Controller struct {
+	limit   int
+	maxWait time.Duration
+
+	mu     sync.Mutex
+	active int
+	queue  []*waiter
+}
+
+type waiter struct {
+	priority int
+	granted  bool
+	ready    chan struct{} // closed when granted a slot
+	moved    chan struct{} // signalled when the queue ahead may have changed
+}
+
+// New returns a controller admitting limit concurrent requests, each waiting
+// at most maxWait in the queue. A maxWait of zero waits indefinitely.
+func New(limit int, maxWait time.Duration) *Controller {
+	return &Controller{limit: max(limit, 1), maxWait: maxWait}
+}
+
+// Acquire waits for a slot and returns the function releasing it, which
+// must be called once the request is done. While queued, position is called
+// with the caller's 1-based queue position whenever it changes; it may be
+// nil. Acquire returns ErrQueueTimeout after waiting too long, or the
+// context's error when ctx is done first.
+func (c *Controller) Acquire(ctx context.Context, priority int, position func(int)) (func(), error) {
+	c.mu.Lock()
+	if c.active < c.limit && len(c.queue) == 0 {
+		c.active++
+		c.mu.Unlock()
+		return c.releaser(), nil
+	}
+	w := &waiter{priority: priority, ready: make(chan struct{}), moved: make(chan struct{}, 1)}
+	i := len(c.queue)
+	for i > 0 && c.queue[i-1].priority < priority {
+		i--
+	}
+	c.queue = append(c.queue, nil)
+	copy(c.queue[i+1:], c.queue[i:])
+	c.queue[i] = w
+	c.notifyLocked(i + 1)
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if c.maxWait > 0 {
+		timer := time.NewTimer(c.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	reported := 0
+	for {
+		if pos := c.position(w); pos > 0 && pos != reported && position != nil {
+			reported = pos
+			position(pos)
+		}
+		select {
+		case <-w.ready:
+			return c.releaser(), nil
+		case <-w.moved:
+		case <-timeout:
+			return c.leave(w, ErrQueueTimeout)
+		case <-ctx.Done():
+			return c.leave(w, ctx.Err())
+		}
+	}
+}
+
+// position returns w's 1-based position in the queue, or 0 once granted.
+func (c *Controller) position(w *waiter) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, q := range c.queue {
+		if q == w {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// leave removes w from the queue and returns err. When w was granted a slot
+// meanwhile, the slot is passed on.
+func (c *Controller) leave(w *waiter, err error) (func(), error) {
+	c.mu.Lock()
+	if w.granted {
+		c.mu.Unlock()
+		c.releaser()()
+		return nil, err
+	}
+	for i, q := range c.queue {
+		if q == w {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			c.notifyLocked(i)
+			break
+		}
+	}
+	c.mu.Unlock()
+	return nil, err
+}
+
+// releaser returns a function freeing one slot, idempotently.
+func (c *Controller) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(c.release)
+	}
+}
+
+// release hands the slot to the first waiter, or frees it.
+func (c *Controller) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queue) == 0 {
+		c.active--
+		return
+	}
+	next := c.queue[0]
+	c.queue = c.queue[1:]
+	next.granted = true
+	close(next.ready)
+	c.notifyLocked(0)
+}
+
+// notifyLocked tells the waiters from index from on that their position may
+// have changed.
+func (c *Controller) notifyLocked(from int) {
+	for _, w := range c.queue[from:] {
+		select {
+		case w.moved <- struct{}{}:
+		default:
+		}
+	}
+}
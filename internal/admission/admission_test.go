@@ -0,0 +1,152 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// positions records the queue positions reported to a waiter.
+type positions struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (p *positions) report(pos int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.got = append(p.got, pos)
+}
+
+func (p *positions) last() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.got) == 0 {
+		return 0
+	}
+	return p.got[len(p.got)-1]
+}
+
+// waitFor polls cond until it holds or a second passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestControllerLimitsInFlight(t *testing.T) {
+	c := New(2, 0)
+	ctx := context.Background()
+	release1, err := c.Acquire(ctx, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := c.Acquire(ctx, 0, nil); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	admitted := make(chan struct{})
+	var queued positions
+	go func() {
+		if _, err := c.Acquire(ctx, 0, queued.report); err == nil {
+			close(admitted)
+		}
+	}()
+	waitFor(t, "the third request to queue", func() bool { return queued.last() == 1 })
+	select {
+	case <-admitted:
+		t.Fatal("third request admitted beyond the limit")
+	default:
+	}
+
+	release1()
+	release1() // releasing twice frees one slot only
+	<-admitted
+	// Two slots are taken again; a fourth request must not get one.
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(short, 0, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire = %v, want to wait beyond the limit after a double release", err)
+	}
+}
+
+func TestControllerOrdersByPriority(t *testing.T) {
+	c := New(1, 0)
+	ctx := context.Background()
+	release, err := c.Acquire(ctx, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	order := make(chan string, 3)
+	var low, normal, high positions
+	start := func(name string, priority int, p *positions) {
+		go func() {
+			release, err := c.Acquire(ctx, priority, p.report)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				return
+			}
+			order <- name
+			release()
+		}()
+	}
+	start("low", -1, &low)
+	waitFor(t, "low to queue", func() bool { return low.last() == 1 })
+	start("normal", 0, &normal)
+	waitFor(t, "normal to queue ahead of low", func() bool { return normal.last() == 1 && low.last() == 2 })
+	start("high", 5, &high)
+	waitFor(t, "high to queue first", func() bool { return high.last() == 1 && normal.last() == 2 && low.last() == 3 })
+
+	release()
+	for _, want := range []string{"high", "normal", "low"} {
+		if got := <-order; got != want {
+			t.Errorf("admitted %s, want %s", got, want)
+		}
+	}
+}
+
+func TestControllerQueueTimeout(t *testing.T) {
+	c := New(1, 20*time.Millisecond)
+	ctx := context.Background()
+	release, err := c.Acquire(ctx, 0, nil)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := c.Acquire(ctx, 0, nil); !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("Acquire = %v, want ErrQueueTimeout", err)
+	}
+
+	// The timed-out request left the queue: the slot goes straight to the
+	// next caller.
+	release()
+	if _, err := c.Acquire(ctx, 0, nil); err != nil {
+		t.Errorf("Acquire after release: %v", err)
+	}
+}
+
+func TestControllerCancelledWhileQueued(t *testing.T) {
+	c := New(1, 0)
+	if _, err := c.Acquire(context.Background(), 0, nil); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var queued positions
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Acquire(ctx, 0, queued.report)
+		done <- err
+	}()
+	waitFor(t, "the request to queue", func() bool { return queued.last() == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire = %v, want context.Canceled", err)
+	}
+}
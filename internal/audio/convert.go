@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Converter transforms a stream from one Format to another. Input may be fed
+// in pieces of any size, including pieces that split a sample or frame; the
+// remainder is carried over to the next call.
+type Converter struct {
+	in  Format
+	out Format
+
+	pending    []byte // partial input frame carried between calls
+	resamplers []*resampler
+}
+
+// NewConverter returns a Converter from in to out.
+func NewConverter(in, out Format) (*Converter, error) {
+	if err := in.Validate(); err != nil {
+		return nil, fmt.Errorf("audio: input format: %w", err)
+	}
+	if err := out.Validate(); err != nil {
+		return nil, fmt.Errorf("audio: output format: %w", err)
+	}
+	c := &Converter{in: in, out: out}
+	if in.SampleRate != out.SampleRate {
+		c.resamplers = make([]*resampler, out.Channels)
+		for i := range c.resamplers {
+			c.resamplers[i] = newResampler(in.SampleRate, out.SampleRate)
+		}
+	}
+	return c, nil
+}
+
+// Process converts p and returns the converted audio available so far.
+func (c *Converter) Process(p []byte) []byte {
+	data := p
+	if len(c.pending) > 0 {
+		data = append(c.pending, p...)
+	}
+	frameSize := c.in.FrameSize()
+	whole := len(data) / frameSize * frameSize
+	c.pending = append([]byte(nil), data[whole:]...)
+
+	channels := c.mix(c.decode(data[:whole]))
+	if c.resamplers != nil {
+		for i, r := range c.resamplers {
+			channels[i] = r.process(channels[i])
+		}
+	}
+	return c.encode(channels)
+}
+
+// Flush returns any audio still held by the resampler. A trailing partial
+// input frame is dropped.
+func (c *Converter) Flush() []byte {
+	c.pending = nil
+	if c.resamplers == nil {
+		return nil
+	}
+	channels := make([][]float64, len(c.resamplers))
+	for i, r := range c.resamplers {
+		channels[i] = r.flush()
+	}
+	return c.encode(channels)
+}
+
+// decode returns per-channel samples scaled to [-1, 1).
+func (c *Converter) decode(data []byte) [][]float64 {
+	bps := c.in.Encoding.BytesPerSample()
+	frames := len(data) / c.in.FrameSize()
+	channels := make([][]float64, c.in.Channels)
+	for ch := range channels {
+		channels[ch] = make([]float64, frames)
+	}
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < c.in.Channels; ch++ {
+			off := (i*c.in.Channels + ch) * bps
+			var s int16
+			switch c.in.Encoding {
+			case EncodingPCM16:
+				s = int16(binary.LittleEndian.Uint16(data[off:]))
+			case EncodingMuLaw:
+				s = MuLawDecode(data[off])
+			case EncodingALaw:
+				s = ALawDecode(data[off])
+			}
+			channels[ch][i] = float64(s) / 32768
+		}
+	}
+	return channels
+}
+
+// mix maps input channels onto the output channel count. Downmixing to mono
+// averages all channels; otherwise output channel i takes input channel i,
+// repeating the last input channel when upmixing.
+func (c *Converter) mix(in [][]float64) [][]float64 {
+	if len(in) == c.out.Channels {
+		return in
+	}
+	out := make([][]float64, c.out.Channels)
+	if c.out.Channels == 1 {
+		mono := make([]float64, len(in[0]))
+		for _, ch := range in {
+			for i, s := range ch {
+				mono[i] += s
+			}
+		}
+		for i := range mono {
+			mono[i] /= float64(len(in))
+		}
+		out[0] = mono
+		return out
+	}
+	for i := range out {
+		src := i
+		if src >= len(in) {
+			src = len(in) - 1
+		}
+		out[i] = append([]float64(nil), in[src]...)
+	}
+	return out
+}
+
+// encode interleaves per-channel samples into the output encoding.
+func (c *Converter) encode(channels [][]float64) []byte {
+	frames := len(channels[0])
+	for _, ch := range channels[1:] {
+		if len(ch) < frames {
+			frames = len(ch)
+		}
+	}
+	bps := c.out.Encoding.BytesPerSample()
+	out := make([]byte, frames*c.out.FrameSize())
+	for i := 0; i < frames; i++ {
+		for ch := range channels {
+			s := toInt16(channels[ch][i])
+			off := (i*len(channels) + ch) * bps
+			switch c.out.Encoding {
+			case EncodingPCM16:
+				binary.LittleEndian.PutUint16(out[off:], uint16(s))
+			case EncodingMuLaw:
+				out[off] = MuLawEncode(s)
+			case EncodingALaw:
+				out[off] = ALawEncode(s)
+			}
+		}
+	}
+	return out
+}
+
+func toInt16(v float64) int16 {
+	v = math.Round(v * 32768)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func pcm16(samples ...int16) []byte {
+	out := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(s))
+	}
+	return out
+}
+
+func samples16(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return out
+}
+
+func TestNewConverterRejectsInvalidFormats(t *testing.T) {
+	valid := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	if _, err := NewConverter(Format{Encoding: "flac", SampleRate: 16000, Channels: 1}, valid); err == nil {
+		t.Error("expected error for unsupported input encoding")
+	}
+	if _, err := NewConverter(valid, Format{Encoding: EncodingPCM16, SampleRate: 0, Channels: 1}); err == nil {
+		t.Error("expected error for zero output sample rate")
+	}
+}
+
+func TestConverterCarriesPartialSamples(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	c, err := NewConverter(mono, mono)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	in := pcm16(1000, -2000, 3000)
+	var out []byte
+	out = append(out, c.Process(in[:1])...)
+	out = append(out, c.Process(in[1:4])...)
+	out = append(out, c.Process(in[4:])...)
+	out = append(out, c.Flush()...)
+
+	got := samples16(out)
+	want := []int16{1000, -2000, 3000}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConverterUpsampleDuration(t *testing.T) {
+	in := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	out := Format{Encoding: EncodingPCM16, SampleRate: 48000, Channels: 1}
+	c, err := NewConverter(in, out)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	data := make([]byte, 16000*2) // one second
+	var got []byte
+	for off := 0; off < len(data); off += 4096 {
+		end := off + 4096
+		if end > len(data) {
+			end = len(data)
+		}
+		got = append(got, c.Process(data[off:end])...)
+	}
+	got = append(got, c.Flush()...)
+
+	if d := out.Duration(len(got)); d != time.Second {
+		t.Errorf("output duration = %v, want 1s", d)
+	}
+}
+
+func TestConverterDownmixStereo(t *testing.T) {
+	in := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 2}
+	out := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	c, err := NewConverter(in, out)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	got := samples16(c.Process(pcm16(1000, 3000, -4000, 0)))
+	want := []int16{2000, -2000}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("downmix = %v, want %v", got, want)
+	}
+}
+
+func TestConverterUpmixMono(t *testing.T) {
+	in := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	out := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 2}
+	c, err := NewConverter(in, out)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	got := samples16(c.Process(pcm16(1234, -42)))
+	want := []int16{1234, 1234, -42, -42}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConverterEncodesMuLaw(t *testing.T) {
+	in := Format{Encoding: EncodingPCM16, SampleRate: 8000, Channels: 1}
+	out := Format{Encoding: EncodingMuLaw, SampleRate: 8000, Channels: 1}
+	c, err := NewConverter(in, out)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	got := c.Process(pcm16(0, 1000, -1000))
+	if len(got) != 3 {
+		t.Fatalf("got %d bytes, want 3", len(got))
+	}
+	for i, s := range []int16{0, 1000, -1000} {
+		if got[i] != MuLawEncode(s) {
+			t.Errorf("byte %d = %#x, want %#x", i, got[i], MuLawEncode(s))
+		}
+	}
+}
+
+func TestConverterDecodesALaw(t *testing.T) {
+	in := Format{Encoding: EncodingALaw, SampleRate: 8000, Channels: 1}
+	out := Format{Encoding: EncodingPCM16, SampleRate: 8000, Channels: 1}
+	c, err := NewConverter(in, out)
+	if err != nil {
+		t.Fatalf("NewConverter: %v", err)
+	}
+
+	got := samples16(c.Process([]byte{ALawEncode(5000)}))
+	if len(got) != 1 || got[0] != ALawDecode(ALawEncode(5000)) {
+		t.Errorf("decoded = %v, want [%d]", got, ALawDecode(ALawEncode(5000)))
+	}
+}
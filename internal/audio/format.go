@@ -0,0 +1,73 @@
+// Package audio implements the local audio processing applied between the
+// upstream synthesizer and emitted audio chunks: sample-rate conversion,
+// channel mixing and G.711 companding. Everything is pure Go and streams, so
+// audio can be processed as it arrives.
+package audio
+
+import (
+	"fmt"
+	"time"
+)
+
+// Encoding identifies a sample encoding.
+type Encoding string
+
+// Supported encodings. The values match the "encoding" chunk metadata labels.
+const (
+	EncodingPCM16 Encoding = "pcm_s16le"
+	EncodingMuLaw Encoding = "mulaw"
+	EncodingALaw  Encoding = "alaw"
+)
+
+// ParseEncoding validates an encoding name.
+func ParseEncoding(name string) (Encoding, error) {
+	switch e := Encoding(name); e {
+	case EncodingPCM16, EncodingMuLaw, EncodingALaw:
+		return e, nil
+	default:
+		return "", fmt.Errorf("audio: unsupported encoding %q", name)
+	}
+}
+
+// BytesPerSample returns the encoded size of one sample.
+func (e Encoding) BytesPerSample() int {
+	if e == EncodingPCM16 {
+		return 2
+	}
+	return 1
+}
+
+// Format describes an uncompressed, interleaved audio stream.
+type Format struct {
+	Encoding   Encoding
+	SampleRate int
+	Channels   int
+}
+
+// Validate reports whether the format can be processed.
+func (f Format) Validate() error {
+	if _, err := ParseEncoding(string(f.Encoding)); err != nil {
+		return err
+	}
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("audio: sample rate must be positive, got %d", f.SampleRate)
+	}
+	if f.Channels <= 0 {
+		return fmt.Errorf("audio: channel count must be positive, got %d", f.Channels)
+	}
+	return nil
+}
+
+// FrameSize returns the number of bytes in one frame (one sample per channel).
+func (f Format) FrameSize() int {
+	return f.Encoding.BytesPerSample() * f.Channels
+}
+
+// Duration returns the playback duration of n bytes in this format.
+func (f Format) Duration(n int) time.Duration {
+	bps := int64(f.FrameSize()) * int64(f.SampleRate)
+	if bps <= 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(time.Second) / bps)
+}
@@ -0,0 +1,87 @@
+package audio
+
+// G.711 μ-law and A-law companding, following the ITU-T reference algorithms.
+
+const (
+	muLawBias = 0x84
+	muLawClip = 32635
+)
+
+// MuLawEncode compresses a linear 16-bit sample to 8-bit μ-law.
+func MuLawEncode(sample int16) byte {
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > muLawClip {
+		s = muLawClip
+	}
+	s += muLawBias
+
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// MuLawDecode expands an 8-bit μ-law sample to linear 16-bit.
+func MuLawDecode(b byte) int16 {
+	u := ^b
+	sign := u & 0x80
+	exponent := int(u>>4) & 0x07
+	mantissa := int(u & 0x0F)
+	s := ((mantissa << 3) + muLawBias) << exponent
+	s -= muLawBias
+	if sign != 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
+
+// ALawEncode compresses a linear 16-bit sample to 8-bit A-law.
+func ALawEncode(sample int16) byte {
+	s := int(sample) >> 3 // A-law operates on 13-bit magnitudes
+	sign := 0x80
+	if s < 0 {
+		s = -s - 1
+		sign = 0x00
+	}
+
+	var out int
+	if s < 32 {
+		out = s >> 1
+	} else {
+		exponent := 1
+		for v := s >> 5; v > 1 && exponent < 7; v >>= 1 {
+			exponent++
+		}
+		if s >= 4096 {
+			s = 4095
+			exponent = 7
+		}
+		out = exponent<<4 | (s>>exponent)&0x0F
+	}
+	return byte((out | sign) ^ 0x55)
+}
+
+// ALawDecode expands an 8-bit A-law sample to linear 16-bit.
+func ALawDecode(b byte) int16 {
+	a := int(b ^ 0x55)
+	exponent := (a >> 4) & 0x07
+	mantissa := a & 0x0F
+
+	var s int
+	if exponent == 0 {
+		s = mantissa<<4 + 8
+	} else {
+		s = (mantissa<<4 + 0x108) << (exponent - 1)
+	}
+	if a&0x80 == 0 {
+		s = -s
+	}
+	return int16(s)
+}
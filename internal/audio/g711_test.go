@@ -0,0 +1,63 @@
+package audio
+
+import "testing"
+
+func TestMuLawSilence(t *testing.T) {
+	if got := MuLawEncode(0); got != 0xFF {
+		t.Errorf("MuLawEncode(0) = %#x, want 0xff", got)
+	}
+	if got := MuLawDecode(0xFF); got != 0 {
+		t.Errorf("MuLawDecode(0xff) = %d, want 0", got)
+	}
+}
+
+func TestALawSilence(t *testing.T) {
+	if got := ALawEncode(0); got != 0xD5 {
+		t.Errorf("ALawEncode(0) = %#x, want 0xd5", got)
+	}
+	if got := ALawDecode(0xD5); got != 8 {
+		t.Errorf("ALawDecode(0xd5) = %d, want 8", got)
+	}
+}
+
+func TestG711RoundTrip(t *testing.T) {
+	for s := -32768; s <= 32767; s += 7 {
+		sample := int16(s)
+		mag := s
+		if mag < 0 {
+			mag = -mag
+		}
+		// Companding keeps roughly 4 significant bits of mantissa: error is
+		// bounded by 1/16 of the magnitude plus the smallest step size.
+		tolerance := mag/16 + 16
+
+		if got := int(MuLawDecode(MuLawEncode(sample))); abs(got-s) > tolerance {
+			t.Fatalf("μ-law round trip of %d = %d (tolerance %d)", s, got, tolerance)
+		}
+		if got := int(ALawDecode(ALawEncode(sample))); abs(got-s) > tolerance {
+			t.Fatalf("A-law round trip of %d = %d (tolerance %d)", s, got, tolerance)
+		}
+	}
+}
+
+func TestG711DecodeEncodeStable(t *testing.T) {
+	// Every code word must survive decode→encode unchanged, except μ-law's
+	// negative zero which encodes back to positive zero.
+	for b := 0; b < 256; b++ {
+		if b != 0x7F {
+			if got := MuLawEncode(MuLawDecode(byte(b))); got != byte(b) {
+				t.Errorf("μ-law %#x → %d → %#x", b, MuLawDecode(byte(b)), got)
+			}
+		}
+		if got := ALawEncode(ALawDecode(byte(b))); got != byte(b) {
+			t.Errorf("A-law %#x → %d → %#x", b, ALawDecode(byte(b)), got)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
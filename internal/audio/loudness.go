@@ -0,0 +1,366 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Loudness measurement after ITU-R BS.1770 / EBU R128: K-weighted mean square
+// over 400ms blocks overlapping by 75%, with an absolute gate at -70 LUFS and
+// a relative gate 10 LU below the absolutely gated loudness.
+const (
+	loudnessStep          = 100 * time.Millisecond // block hop
+	loudnessStepsPerBlock = 4                      // 400ms blocks
+	absoluteGateLUFS      = -70.0
+	relativeGateLU        = -10.0
+)
+
+// LoudnessMeter measures the integrated loudness of a stream as it arrives.
+// Until the first full 400ms block the measurement covers the shorter
+// window available.
+type LoudnessMeter struct {
+	filters    []kWeighting // one per channel
+	stepFrames int
+
+	sum    float64   // K-weighted energy of the current step
+	frames int       // frames in the current step
+	steps  []float64 // mean square of the latest steps, up to a block's worth
+	blocks []float64 // mean square of each gating block so far
+	full   bool      // whether blocks holds full 400ms blocks
+}
+
+// NewLoudnessMeter returns a meter for audio of the given sample rate and
+// channel count, fed through Add as samples scaled to [-1, 1).
+func NewLoudnessMeter(sampleRate, channels int) *LoudnessMeter {
+	m := &LoudnessMeter{
+		filters:    make([]kWeighting, channels),
+		stepFrames: max(int(time.Duration(sampleRate)*loudnessStep/time.Second), 1),
+	}
+	for i := range m.filters {
+		m.filters[i] = newKWeighting(float64(sampleRate))
+	}
+	return m
+}
+
+// Add measures interleaved samples. It reports whether a new gating block
+// was completed, that is whether Integrated may have changed.
+func (m *LoudnessMeter) Add(samples []float64) bool {
+	channels := len(m.filters)
+	updated := false
+	for i := 0; i+channels <= len(samples); i += channels {
+		for ch := range m.filters {
+			v := m.filters[ch].process(samples[i+ch])
+			m.sum += v * v
+		}
+		m.frames++
+		if m.frames == m.stepFrames {
+			m.endStep()
+			updated = true
+		}
+	}
+	return updated
+}
+
+// endStep closes the current step and records the block ending with it.
+func (m *LoudnessMeter) endStep() {
+	m.steps = append(m.steps, m.sum/float64(m.frames))
+	if len(m.steps) > loudnessStepsPerBlock {
+		m.steps = m.steps[1:]
+	}
+	m.sum, m.frames = 0, 0
+
+	var block float64
+	for _, s := range m.steps {
+		block += s
+	}
+	block /= float64(len(m.steps))
+	if len(m.steps) == loudnessStepsPerBlock && !m.full {
+		// The first full block replaces the shorter windows measured so far.
+		m.blocks = m.blocks[:0]
+		m.full = true
+	}
+	m.blocks = append(m.blocks, block)
+}
+
+// Integrated returns the gated loudness of the audio measured so far, in
+// LUFS. It reports false until a block louder than the absolute gate has
+// been measured.
+func (m *LoudnessMeter) Integrated() (float64, bool) {
+	gated := func(threshold float64) (float64, bool) {
+		var sum float64
+		n := 0
+		for _, b := range m.blocks {
+			if lufs(b) > threshold {
+				sum += b
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, false
+		}
+		return sum / float64(n), true
+	}
+	mean, ok := gated(absoluteGateLUFS)
+	if !ok {
+		return 0, false
+	}
+	mean, ok = gated(lufs(mean) + relativeGateLU)
+	if !ok {
+		return 0, false
+	}
+	return lufs(mean), true
+}
+
+// lufs converts a K-weighted mean square into LUFS.
+func lufs(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+// kWeighting is the BS.1770 pre-filter: a high shelf modelling the head
+// followed by a high-pass, each a biquad. The coefficients are derived for
+// the sample rate from the analogue prototypes.
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+func newKWeighting(rate float64) kWeighting {
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+		hpFreq    = 38.13547087602444
+		hpQ       = 0.5003270373238773
+	)
+	k := math.Tan(math.Pi * shelfFreq / rate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * hpFreq / rate)
+	a0 = 1 + k/hpQ + k*k
+	highPass := biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/hpQ + k*k) / a0,
+	}
+	return kWeighting{shelf: shelf, highPass: highPass}
+}
+
+func (k *kWeighting) process(v float64) float64 {
+	return k.highPass.process(k.shelf.process(v))
+}
+
+// biquad is a second-order IIR filter in transposed direct form II.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(v float64) float64 {
+	out := f.b0*v + f.z1
+	f.z1 = f.b1*v - f.a1*out + f.z2
+	f.z2 = f.b2*v - f.a2*out
+	return out
+}
+
+// LevelerOptions configures a Leveler.
+type LevelerOptions struct {
+	// Normalize steers the gain towards TargetLUFS, within MaxGainDB of
+	// unity in either direction, starting at InitialGainDB.
+	Normalize     bool
+	TargetLUFS    float64
+	MaxGainDB     float64
+	InitialGainDB float64
+
+	// Fade ramps the start and the end of the stream in and out.
+	Fade time.Duration
+}
+
+// gainSmoothing is the time constant with which the applied gain follows
+// the gain the measurement calls for, so changes never step.
+const gainSmoothing = 100 * time.Millisecond
+
+// peakCeiling is the largest sample magnitude the gain may produce, just
+// below full scale.
+const peakCeiling = 0.99
+
+// Leveler normalizes the loudness of a stream without seeing it in full: the
+// gain follows the integrated loudness measured so far, smoothed sample by
+// sample, and is lowered at once wherever a sample would clip. It also fades
+// the stream in and out, holding back the last Fade of audio until Flush
+// shows where the stream ends. Like Converter, it accepts input in pieces of
+// any size.
+type Leveler struct {
+	format Format
+	opts   LevelerOptions
+	meter  *LoudnessMeter // nil unless normalizing
+
+	gain      float64 // applied linear gain
+	target    float64 // linear gain the measurement calls for
+	smoothing float64 // per-frame step towards target
+
+	fadeFrames int
+	faded      int // frames of the fade-in already applied
+
+	carry   []byte // partial frame carried between calls
+	pending []byte // processed audio held back for the fade-out
+}
+
+// NewLeveler returns a leveler for audio in format.
+func NewLeveler(format Format, opts LevelerOptions) (*Leveler, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Normalize && opts.MaxGainDB <= 0 {
+		return nil, fmt.Errorf("audio: maximum gain must be above 0 dB, got %g", opts.MaxGainDB)
+	}
+	if opts.Fade < 0 {
+		return nil, fmt.Errorf("audio: fade must not be negative, got %v", opts.Fade)
+	}
+	l := &Leveler{
+		format:     format,
+		opts:       opts,
+		gain:       1,
+		target:     1,
+		fadeFrames: int(opts.Fade * time.Duration(format.SampleRate) / time.Second),
+	}
+	if opts.Normalize {
+		l.meter = NewLoudnessMeter(format.SampleRate, format.Channels)
+		l.gain = l.limit(opts.InitialGainDB)
+		l.target = l.gain
+		l.smoothing = 1 - math.Exp(-1/(gainSmoothing.Seconds()*float64(format.SampleRate)))
+	}
+	return l, nil
+}
+
+// Process returns the levelled audio, less the tail held back for the
+// fade-out.
+func (l *Leveler) Process(p []byte) []byte {
+	data := append(l.carry, p...)
+	frameSize := l.format.FrameSize()
+	whole := len(data) / frameSize * frameSize
+	l.carry = append([]byte(nil), data[whole:]...)
+	if whole == 0 {
+		return nil
+	}
+
+	samples := decodeSamples(l.format, data[:whole])
+	channels := l.format.Channels
+	for i := 0; i < len(samples); i += channels {
+		frame := samples[i : i+channels]
+		if l.meter != nil {
+			if l.meter.Add(frame) {
+				if measured, ok := l.meter.Integrated(); ok {
+					l.target = l.limit(l.opts.TargetLUFS - measured)
+				}
+			}
+			l.gain += (l.target - l.gain) * l.smoothing
+		}
+		gain := l.gain
+		for _, v := range frame {
+			// Lower the gain where the frame would clip; it recovers
+			// through the smoothing.
+			if peak := math.Abs(v) * gain; peak > peakCeiling {
+				gain *= peakCeiling / peak
+				l.gain = gain
+			}
+		}
+		if l.faded < l.fadeFrames {
+			gain *= float64(l.faded) / float64(l.fadeFrames)
+			l.faded++
+		}
+		for ch := range frame {
+			frame[ch] *= gain
+		}
+	}
+
+	out := append(l.pending, encodeSamples(l.format, samples)...)
+	hold := min(l.fadeFrames*frameSize, len(out))
+	l.pending = append([]byte(nil), out[len(out)-hold:]...)
+	return out[:len(out)-hold]
+}
+
+// Flush ends the stream and returns the held-back tail, faded out.
+func (l *Leveler) Flush() []byte {
+	l.carry = nil
+	tail := l.pending
+	l.pending = nil
+	if len(tail) == 0 {
+		return nil
+	}
+	samples := decodeSamples(l.format, tail)
+	channels := l.format.Channels
+	frames := len(samples) / channels
+	for i := 0; i < frames; i++ {
+		// Reach zero on the sample after the last one.
+		ramp := float64(frames-1-i) / float64(frames)
+		for ch := 0; ch < channels; ch++ {
+			samples[i*channels+ch] *= ramp
+		}
+	}
+	return encodeSamples(l.format, samples)
+}
+
+// Loudness returns the integrated loudness of the input measured so far, in
+// LUFS. It reports false when not normalizing or before any sound.
+func (l *Leveler) Loudness() (float64, bool) {
+	if l.meter == nil {
+		return 0, false
+	}
+	return l.meter.Integrated()
+}
+
+// limit converts a gain in dB to linear, clamped to the maximum gain.
+func (l *Leveler) limit(db float64) float64 {
+	db = max(min(db, l.opts.MaxGainDB), -l.opts.MaxGainDB)
+	return math.Pow(10, db/20)
+}
+
+// decodeSamples returns the interleaved samples of data scaled to [-1, 1).
+func decodeSamples(f Format, data []byte) []float64 {
+	bps := f.Encoding.BytesPerSample()
+	samples := make([]float64, len(data)/bps)
+	for i := range samples {
+		var s int16
+		switch f.Encoding {
+		case EncodingPCM16:
+			s = int16(binary.LittleEndian.Uint16(data[i*bps:]))
+		case EncodingMuLaw:
+			s = MuLawDecode(data[i])
+		case EncodingALaw:
+			s = ALawDecode(data[i])
+		}
+		samples[i] = float64(s) / 32768
+	}
+	return samples
+}
+
+// encodeSamples encodes interleaved samples scaled to [-1, 1) in format f.
+func encodeSamples(f Format, samples []float64) []byte {
+	bps := f.Encoding.BytesPerSample()
+	out := make([]byte, len(samples)*bps)
+	for i, v := range samples {
+		s := toInt16(v)
+		switch f.Encoding {
+		case EncodingPCM16:
+			binary.LittleEndian.PutUint16(out[i*bps:], uint16(s))
+		case EncodingMuLaw:
+			out[i] = MuLawEncode(s)
+		case EncodingALaw:
+			out[i] = ALawEncode(s)
+		}
+	}
+	return out
+}
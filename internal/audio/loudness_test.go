@@ -0,0 +1,160 @@
+package audio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tone returns d of a 1kHz tone at amplitude (full scale 1) as PCM16.
+func tone(rate int, amplitude float64, d time.Duration) []byte {
+	frames := int(time.Duration(rate) * d / time.Second)
+	samples := make([]int16, frames)
+	for i := range samples {
+		samples[i] = int16(math.Round(amplitude * 32767 * math.Sin(2*math.Pi*1000*float64(i)/float64(rate))))
+	}
+	return pcm16(samples...)
+}
+
+// measure returns the integrated loudness of PCM16 audio.
+func measure(t *testing.T, rate int, data []byte) float64 {
+	t.Helper()
+	meter := NewLoudnessMeter(rate, 1)
+	meter.Add(decodeSamples(Format{Encoding: EncodingPCM16, SampleRate: rate, Channels: 1}, data))
+	got, ok := meter.Integrated()
+	if !ok {
+		t.Fatal("Integrated() reported no loudness")
+	}
+	return got
+}
+
+func TestLoudnessMeter(t *testing.T) {
+	// A 1kHz sine at -20 dBFS peak reads -23 LUFS: K-weighting adds 0.69 dB
+	// at 1kHz, cancelled by the -0.691 offset, and a sine's mean square is
+	// half its peak squared.
+	for _, rate := range []int{8000, 16000, 48000} {
+		if got := measure(t, rate, tone(rate, 0.1, time.Second)); math.Abs(got+23.01) > 0.1 {
+			t.Errorf("%d Hz: loudness = %.2f LUFS, want -23.01", rate, got)
+		}
+	}
+}
+
+func TestLoudnessMeterGatesSilence(t *testing.T) {
+	const rate = 16000
+	in := tone(rate, 0.1, time.Second)
+	// Silence after the tone falls below the absolute gate; only the
+	// blocks straddling the end of the tone lower the reading. Without
+	// gating it would drop by 3 LU.
+	withSilence := append(append([]byte(nil), in...), make([]byte, len(in))...)
+	if got := measure(t, rate, withSilence); math.Abs(got+23.01) > 1 {
+		t.Errorf("loudness = %.2f LUFS, want about -23 with silence gated", got)
+	}
+
+	meter := NewLoudnessMeter(rate, 1)
+	meter.Add(make([]float64, rate))
+	if _, ok := meter.Integrated(); ok {
+		t.Error("Integrated() reported loudness for silence")
+	}
+}
+
+func TestLevelerNormalizes(t *testing.T) {
+	const rate = 16000
+	mono := Format{Encoding: EncodingPCM16, SampleRate: rate, Channels: 1}
+	for _, amplitude := range []float64{0.02, 0.5} {
+		leveler, err := NewLeveler(mono, LevelerOptions{Normalize: true, TargetLUFS: -20, MaxGainDB: 20})
+		if err != nil {
+			t.Fatalf("NewLeveler: %v", err)
+		}
+		in := tone(rate, amplitude, 3*time.Second)
+		var out []byte
+		for off := 0; off < len(in); off += 999 { // odd pieces split samples
+			out = append(out, leveler.Process(in[off:min(off+999, len(in))])...)
+		}
+		out = append(out, leveler.Flush()...)
+		if len(out) != len(in) {
+			t.Fatalf("amplitude %g: %d bytes out, want %d", amplitude, len(out), len(in))
+		}
+
+		// Once the gain has settled the output sits at the target.
+		if got := measure(t, rate, out[len(out)/2:]); math.Abs(got+20) > 0.5 {
+			t.Errorf("amplitude %g: output loudness = %.2f LUFS, want -20", amplitude, got)
+		}
+		if got, ok := leveler.Loudness(); !ok || math.Abs(got-measure(t, rate, in)) > 0.01 {
+			t.Errorf("amplitude %g: Loudness() = %.2f, %v, want the input's loudness", amplitude, got, ok)
+		}
+	}
+}
+
+func TestLevelerLimitsGain(t *testing.T) {
+	const rate = 16000
+	mono := Format{Encoding: EncodingPCM16, SampleRate: rate, Channels: 1}
+	leveler, err := NewLeveler(mono, LevelerOptions{Normalize: true, TargetLUFS: -10, MaxGainDB: 6})
+	if err != nil {
+		t.Fatalf("NewLeveler: %v", err)
+	}
+	// -43 LUFS would need 33 dB of gain; only 6 dB are allowed.
+	in := tone(rate, 0.01, 2*time.Second)
+	out := append(leveler.Process(in), leveler.Flush()...)
+	if got := measure(t, rate, out[len(out)/2:]); math.Abs(got-(-43.01+6)) > 0.5 {
+		t.Errorf("output loudness = %.2f LUFS, want %.2f", got, -43.01+6)
+	}
+}
+
+func TestLevelerInitialGain(t *testing.T) {
+	const rate = 16000
+	mono := Format{Encoding: EncodingPCM16, SampleRate: rate, Channels: 1}
+	leveler, err := NewLeveler(mono, LevelerOptions{Normalize: true, TargetLUFS: -23, MaxGainDB: 20, InitialGainDB: 6})
+	if err != nil {
+		t.Fatalf("NewLeveler: %v", err)
+	}
+	// The first samples are played at the initial gain, before anything
+	// has been measured.
+	out := samples16(leveler.Process(pcm16(1000)))
+	if want := int16(math.Round(1000 * math.Pow(10, 6.0/20))); len(out) != 1 || math.Abs(float64(out[0]-want)) > 1 {
+		t.Errorf("first sample = %v, want %d", out, want)
+	}
+}
+
+func TestLevelerPreventsClipping(t *testing.T) {
+	const rate = 16000
+	mono := Format{Encoding: EncodingPCM16, SampleRate: rate, Channels: 1}
+	leveler, err := NewLeveler(mono, LevelerOptions{Normalize: true, TargetLUFS: -5, MaxGainDB: 20, InitialGainDB: 20})
+	if err != nil {
+		t.Fatalf("NewLeveler: %v", err)
+	}
+	out := append(leveler.Process(tone(rate, 0.5, time.Second)), leveler.Flush()...)
+	for i, s := range samples16(out) {
+		if s == math.MaxInt16 || s == math.MinInt16 {
+			t.Fatalf("sample %d clipped", i)
+		}
+	}
+}
+
+func TestLevelerFades(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 4000, Channels: 1}
+	leveler, err := NewLeveler(mono, LevelerOptions{Fade: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewLeveler: %v", err)
+	}
+	// 1ms at 4kHz is four samples: the first four ramp up from silence and
+	// the last four, held back until Flush, ramp down.
+	first := samples16(leveler.Process(pcm16(1000, 1000, 1000, 1000, 1000, 1000)))
+	if want := []int16{0, 250}; !reflect.DeepEqual(first, want) {
+		t.Errorf("Process() = %v, want %v", first, want)
+	}
+	rest := samples16(append(leveler.Process(pcm16(1000, 1000)), leveler.Flush()...))
+	if want := []int16{500, 750, 750, 500, 250, 0}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("remainder = %v, want %v", rest, want)
+	}
+}
+
+func TestNewLevelerRejectsInvalidOptions(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	if _, err := NewLeveler(mono, LevelerOptions{Normalize: true, TargetLUFS: -16}); err == nil {
+		t.Error("expected error for a zero maximum gain")
+	}
+	if _, err := NewLeveler(mono, LevelerOptions{Fade: -time.Millisecond}); err == nil {
+		t.Error("expected error for a negative fade")
+	}
+}
@@ -0,0 +1,119 @@
+package audio
+
+import "math"
+
+// resampleTaps is the number of input samples on each side of the
+// interpolation point considered when upsampling. Downsampling widens the
+// kernel proportionally so the anti-aliasing filter keeps the same shape.
+const resampleTaps = 16
+
+// resampler converts one channel between sample rates using a Blackman
+// windowed-sinc kernel. It keeps the input history it still needs, so audio
+// can be fed in arbitrary pieces.
+type resampler struct {
+	inRate  int64
+	outRate int64
+	half    int     // kernel half-width in input samples
+	cutoff  float64 // cutoff frequency in cycles per input sample
+
+	history []float64 // input samples starting at absolute index base
+	base    int64
+	next    int64 // index of the next output sample
+	total   int64 // input samples consumed so far
+}
+
+func newResampler(inRate, outRate int) *resampler {
+	r := &resampler{
+		inRate:  int64(inRate),
+		outRate: int64(outRate),
+		half:    resampleTaps,
+		cutoff:  0.5,
+	}
+	if outRate < inRate {
+		ratio := float64(outRate) / float64(inRate)
+		r.cutoff = 0.5 * ratio
+		r.half = int(math.Ceil(resampleTaps / ratio))
+	}
+	return r
+}
+
+// process appends input samples and returns every output sample whose kernel
+// is now fully covered by the available input.
+func (r *resampler) process(in []float64) []float64 {
+	r.history = append(r.history, in...)
+	r.total += int64(len(in))
+	return r.drain(r.base + int64(len(r.history)))
+}
+
+// flush pads the input with silence and returns the remaining output so the
+// total output length matches the input duration.
+func (r *resampler) flush() []float64 {
+	want := (r.total*r.outRate + r.inRate - 1) / r.inRate
+	r.history = append(r.history, make([]float64, r.half+1)...)
+	out := r.drain(r.base + int64(len(r.history)))
+	if extra := r.next - want; extra > 0 {
+		out = out[:int64(len(out))-extra]
+	}
+	return out
+}
+
+// drain produces output samples while their kernel ends before limit.
+func (r *resampler) drain(limit int64) []float64 {
+	var out []float64
+	for {
+		pos := r.next * r.inRate
+		center := pos / r.outRate
+		frac := float64(pos%r.outRate) / float64(r.outRate)
+		if center+int64(r.half)+1 > limit {
+			break
+		}
+		out = append(out, r.interpolate(center, frac))
+		r.next++
+	}
+
+	// Drop history no future output sample can reach.
+	keepFrom := (r.next*r.inRate)/r.outRate - int64(r.half)
+	if drop := keepFrom - r.base; drop > 0 {
+		if drop > int64(len(r.history)) {
+			drop = int64(len(r.history))
+		}
+		r.history = append(r.history[:0], r.history[drop:]...)
+		r.base += drop
+	}
+	return out
+}
+
+// interpolate evaluates the band-limited signal at input position center+frac.
+func (r *resampler) interpolate(center int64, frac float64) float64 {
+	var sum, weights float64
+	for k := -r.half + 1; k <= r.half; k++ {
+		idx := center + int64(k) - r.base
+		if idx < 0 || idx >= int64(len(r.history)) {
+			continue
+		}
+		x := float64(k) - frac
+		w := r.kernel(x)
+		sum += r.history[idx] * w
+		weights += w
+	}
+	if weights == 0 {
+		return 0
+	}
+	// Normalising keeps DC gain at exactly one despite the truncated kernel.
+	return sum / weights
+}
+
+func (r *resampler) kernel(x float64) float64 {
+	if math.Abs(x) >= float64(r.half) {
+		return 0
+	}
+	sinc := 1.0
+	if x != 0 {
+		arg := 2 * math.Pi * r.cutoff * x
+		sinc = math.Sin(arg) / arg
+	}
+	// Blackman window over [-half, half].
+	n := (x + float64(r.half)) / (2 * float64(r.half))
+	window := 0.42 - 0.5*math.Cos(2*math.Pi*n) + 0.08*math.Cos(4*math.Pi*n)
+	return sinc * window
+}
@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SilenceTrimmer removes the silence before the first and after the last
+// sound of a stream and optionally pads both ends with a fixed amount of
+// silence. Silence after a sound is held back until the next sound shows it
+// is a pause rather than the end. Like Converter, it accepts input in pieces
+// of any size.
+type SilenceTrimmer struct {
+	format    Format
+	threshold int // loudest sample magnitude still counted as silence
+	padding   []byte
+
+	carry   []byte // partial frame carried between calls
+	pending []byte // silence after the latest sound
+	started bool
+	leading int // bytes of leading silence removed
+}
+
+// NewSilenceTrimmer returns a trimmer for audio in format that counts
+// samples quieter than thresholdDB (dBFS, negative) as silence and pads each
+// end with padding of silence.
+func NewSilenceTrimmer(format Format, thresholdDB float64, padding time.Duration) (*SilenceTrimmer, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	if thresholdDB >= 0 {
+		return nil, fmt.Errorf("audio: silence threshold must be below 0 dBFS, got %g", thresholdDB)
+	}
+	frames := int(padding * time.Duration(format.SampleRate) / time.Second)
+	return &SilenceTrimmer{
+		format:    format,
+		threshold: int(math.Round(32768 * math.Pow(10, thresholdDB/20))),
+		padding:   silence(format, frames),
+	}, nil
+}
+
+// Process returns the audio that is known not to be trailing silence.
+func (t *SilenceTrimmer) Process(p []byte) []byte {
+	data := append(t.carry, p...)
+	frameSize := t.format.FrameSize()
+	whole := len(data) / frameSize * frameSize
+	t.carry = append([]byte(nil), data[whole:]...)
+	data = data[:whole]
+
+	var out []byte
+	if !t.started {
+		first := t.firstSound(data)
+		if first < 0 {
+			t.leading += len(data)
+			return nil
+		}
+		t.leading += first
+		t.started = true
+		out = append(out, t.padding...)
+		data = data[first:]
+	}
+
+	last := t.lastSound(data)
+	if last < 0 {
+		t.pending = append(t.pending, data...)
+		return out
+	}
+	out = append(out, t.pending...)
+	out = append(out, data[:last+frameSize]...)
+	t.pending = append([]byte(nil), data[last+frameSize:]...)
+	return out
+}
+
+// Flush ends the stream: held-back silence is dropped and the trailing
+// padding returned. A stream without any sound yields nothing.
+func (t *SilenceTrimmer) Flush() []byte {
+	t.carry = nil
+	t.pending = nil
+	if !t.started {
+		return nil
+	}
+	return append([]byte(nil), t.padding...)
+}
+
+// Shift returns how much later, in the trimmed output, a point of the input
+// is played: the leading padding minus the leading silence removed. It is
+// final once Process returned the first audio.
+func (t *SilenceTrimmer) Shift() time.Duration {
+	return t.format.Duration(len(t.padding)) - t.format.Duration(t.leading)
+}
+
+// firstSound returns the offset of the first frame with a sample above the
+// threshold, or -1.
+func (t *SilenceTrimmer) firstSound(data []byte) int {
+	frameSize := t.format.FrameSize()
+	for off := 0; off < len(data); off += frameSize {
+		if t.loud(data[off : off+frameSize]) {
+			return off
+		}
+	}
+	return -1
+}
+
+// lastSound returns the offset of the last frame with a sample above the
+// threshold, or -1.
+func (t *SilenceTrimmer) lastSound(data []byte) int {
+	frameSize := t.format.FrameSize()
+	for off := len(data) - frameSize; off >= 0; off -= frameSize {
+		if t.loud(data[off : off+frameSize]) {
+			return off
+		}
+	}
+	return -1
+}
+
+// loud reports whether any sample of frame exceeds the threshold.
+func (t *SilenceTrimmer) loud(frame []byte) bool {
+	bps := t.format.Encoding.BytesPerSample()
+	for off := 0; off < len(frame); off += bps {
+		var v int16
+		switch t.format.Encoding {
+		case EncodingPCM16:
+			v = int16(binary.LittleEndian.Uint16(frame[off:]))
+		case EncodingMuLaw:
+			v = MuLawDecode(frame[off])
+		case EncodingALaw:
+			v = ALawDecode(frame[off])
+		}
+		if m := int(v); m > t.threshold || -m > t.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// silence returns frames frames of silence in format.
+func silence(format Format, frames int) []byte {
+	n := frames * format.FrameSize()
+	out := make([]byte, n)
+	var fill byte
+	switch format.Encoding {
+	case EncodingMuLaw:
+		fill = MuLawEncode(0)
+	case EncodingALaw:
+		fill = ALawEncode(0)
+	default:
+		return out
+	}
+	for i := range out {
+		out[i] = fill
+	}
+	return out
+}
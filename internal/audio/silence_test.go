@@ -0,0 +1,88 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSilenceTrimmer(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 1000, Channels: 1}
+	trimmer, err := NewSilenceTrimmer(mono, -40, 2*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSilenceTrimmer: %v", err)
+	}
+
+	// -40 dBFS is a magnitude of 328: quieter samples are silence.
+	in := pcm16(0, 5, -300, 0, 1000, 0, 0, -2000, 10, 0, 0)
+	var out []byte
+	// Feed in pieces that split samples.
+	for _, piece := range [][]byte{in[:3], in[3:9], in[9:15], in[15:]} {
+		out = append(out, trimmer.Process(piece)...)
+	}
+	out = append(out, trimmer.Flush()...)
+
+	want := []int16{0, 0, 1000, 0, 0, -2000, 0, 0}
+	if got := samples16(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("trimmed = %v, want %v", got, want)
+	}
+	// Four samples (4ms) of leading silence were replaced by 2ms of padding.
+	if shift := trimmer.Shift(); shift != -2*time.Millisecond {
+		t.Errorf("Shift() = %v, want -2ms", shift)
+	}
+}
+
+func TestSilenceTrimmerHoldsPauses(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 1000, Channels: 1}
+	trimmer, err := NewSilenceTrimmer(mono, -40, 0)
+	if err != nil {
+		t.Fatalf("NewSilenceTrimmer: %v", err)
+	}
+
+	if got := samples16(trimmer.Process(pcm16(1000, 0, 0))); !reflect.DeepEqual(got, []int16{1000}) {
+		t.Errorf("first piece = %v, want the sound without the trailing silence", got)
+	}
+	// The silence turns out to be a pause and is released with the sound.
+	if got := samples16(trimmer.Process(pcm16(0, 1000))); !reflect.DeepEqual(got, []int16{0, 0, 0, 1000}) {
+		t.Errorf("second piece = %v, want the pause and the sound", got)
+	}
+	if got := trimmer.Flush(); len(got) != 0 {
+		t.Errorf("Flush() = %v, want nothing", got)
+	}
+}
+
+func TestSilenceTrimmerG711(t *testing.T) {
+	ulaw := Format{Encoding: EncodingMuLaw, SampleRate: 8000, Channels: 1}
+	trimmer, err := NewSilenceTrimmer(ulaw, -40, time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSilenceTrimmer: %v", err)
+	}
+	quiet, loud := MuLawEncode(0), MuLawEncode(8000)
+
+	out := trimmer.Process([]byte{quiet, quiet, loud, quiet})
+	out = append(out, trimmer.Flush()...)
+	// 1ms at 8kHz is eight samples of padding on each side.
+	want := append(append(silence(ulaw, 8), loud), silence(ulaw, 8)...)
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("trimmed = %v, want %v", out, want)
+	}
+}
+
+func TestSilenceTrimmerAllSilent(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	trimmer, err := NewSilenceTrimmer(mono, -50, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSilenceTrimmer: %v", err)
+	}
+	out := append(trimmer.Process(pcm16(0, 1, -1, 0)), trimmer.Flush()...)
+	if len(out) != 0 {
+		t.Errorf("silent stream yielded %d bytes, want none", len(out))
+	}
+}
+
+func TestNewSilenceTrimmerRejectsPositiveThreshold(t *testing.T) {
+	mono := Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+	if _, err := NewSilenceTrimmer(mono, 0, 0); err == nil {
+		t.Error("expected error for a 0 dBFS threshold")
+	}
+}
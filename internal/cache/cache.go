@@ -10,7 +10,7 @@ import (
 	"time"
 )
 
-// Cache is a disk-backed LRU cache for synthesized PCM audio.
+// Cache is a disk-backed LRU cache for synthesized audio.
 type Cache struct {
 	mu       sync.Mutex
 	dir      string
@@ -69,47 +69,80 @@ func (c *Cache) Get(key string) ([]byte, bool) {
 // Put stores data under key, evicting least-recently-used entries if necessary.
 // Entries larger than maxBytes are silently ignored.
 func (c *Cache) Put(key string, data []byte) error {
-	newSize := int64(len(data))
-	if newSize > c.maxBytes {
+	if int64(len(data)) > c.maxBytes {
 		return nil // silently skip oversized entries
 	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// If key already exists, remove old entry first.
-	if old, ok := c.entries[key]; ok {
-		os.Remove(old.path)
-		delete(c.entries, key)
+	w, err := c.Writer(key)
+	if err != nil {
+		return err
 	}
-
-	c.evict(newSize)
-
-	p := filepath.Join(c.dir, key+".pcm")
-	if err := os.WriteFile(p, data, 0o644); err != nil {
-		return fmt.Errorf("cache: write: %w", err)
+	if _, err := w.Write(data); err != nil {
+		w.Abort()
+		return err
 	}
+	return w.Commit()
+}
 
-	c.entries[key] = &entry{
-		size:       newSize,
-		accessedAt: time.Now(),
-		path:       p,
-	}
-	return nil
+// KeyParams lists the synthesis parameters that influence the produced audio.
+type KeyParams struct {
+	Text                     string
+	Model                    string
+	VoiceID                  string
+	LanguageCode             string
+	OutputFormat             string
+	Stability                *float64
+	SimilarityBoost          *float64
+	OptimizeStreamingLatency *int
+	Style                    *float64
+	UseSpeakerBoost          *bool
+	Speed                    *float64
+
+	// Lexicon identifies the pronunciation lexicon applied, if any.
+	Lexicon string
+
+	// PreviousText and NextText are the context sent with one part of a
+	// longer text; they shape its prosody.
+	PreviousText string
+	NextText     string
+
+	// Alignment selects the key of the audio's character timings rather
+	// than of the audio itself.
+	Alignment bool
 }
 
 // Key produces a deterministic SHA-256 hex key from synthesis parameters.
-func Key(text, model, voiceID, languageCode string, stability, similarityBoost *float64, optimizeLatency *int) string {
+func Key(p KeyParams) string {
 	h := sha256.New()
-	fmt.Fprintf(h, "text=%s\nmodel=%s\nvoice=%s\nlang=%s\n", text, model, voiceID, languageCode)
-	if stability != nil {
-		fmt.Fprintf(h, "stability=%f\n", *stability)
+	fmt.Fprintf(h, "text=%s\nmodel=%s\nvoice=%s\nlang=%s\nformat=%s\n", p.Text, p.Model, p.VoiceID, p.LanguageCode, p.OutputFormat)
+	if p.Stability != nil {
+		fmt.Fprintf(h, "stability=%f\n", *p.Stability)
 	}
-	if similarityBoost != nil {
-		fmt.Fprintf(h, "similarity_boost=%f\n", *similarityBoost)
+	if p.SimilarityBoost != nil {
+		fmt.Fprintf(h, "similarity_boost=%f\n", *p.SimilarityBoost)
 	}
-	if optimizeLatency != nil {
-		fmt.Fprintf(h, "optimize_streaming_latency=%d\n", *optimizeLatency)
+	if p.OptimizeStreamingLatency != nil {
+		fmt.Fprintf(h, "optimize_streaming_latency=%d\n", *p.OptimizeStreamingLatency)
+	}
+	if p.Style != nil {
+		fmt.Fprintf(h, "style=%f\n", *p.Style)
+	}
+	if p.UseSpeakerBoost != nil {
+		fmt.Fprintf(h, "use_speaker_boost=%t\n", *p.UseSpeakerBoost)
+	}
+	if p.Speed != nil {
+		fmt.Fprintf(h, "speed=%f\n", *p.Speed)
+	}
+	if p.Lexicon != "" {
+		fmt.Fprintf(h, "lexicon=%s\n", p.Lexicon)
+	}
+	if p.PreviousText != "" {
+		fmt.Fprintf(h, "previous_text=%s\n", p.PreviousText)
+	}
+	if p.NextText != "" {
+		fmt.Fprintf(h, "next_text=%s\n", p.NextText)
+	}
+	if p.Alignment {
+		fmt.Fprintf(h, "alignment=true\n")
 	}
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
@@ -156,7 +189,16 @@ func (c *Cache) oldestKey() string {
 }
 
 // loadExisting scans dir for .pcm files and rebuilds the index from mod times.
+// Entries whose write was abandoned, untouched for staleTempAge, are removed;
+// newer ones may belong to another process sharing dir.
 func (c *Cache) loadExisting() {
+	partial, _ := filepath.Glob(filepath.Join(c.dir, tempPattern))
+	for _, p := range partial {
+		if info, err := os.Stat(p); err == nil && time.Since(info.ModTime()) > staleTempAge {
+			os.Remove(p)
+		}
+	}
+
 	matches, err := filepath.Glob(filepath.Join(c.dir, "*.pcm"))
 	if err != nil {
 		c.log.Warn("cache: glob existing files", "error", err)
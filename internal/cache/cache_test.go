@@ -123,7 +123,7 @@ func TestConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			key := Key("text", "model", "voice", "", nil, nil, nil)
+			key := Key(KeyParams{Text: "text", Model: "model", VoiceID: "voice"})
 			c.Put(key, make([]byte, 100))
 			c.Get(key)
 		}()
@@ -133,16 +133,16 @@ func TestConcurrentAccess(t *testing.T) {
 
 func TestKeyDeterministic(t *testing.T) {
 	s := 0.5
-	k1 := Key("hello", "m1", "v1", "", &s, nil, nil)
-	k2 := Key("hello", "m1", "v1", "", &s, nil, nil)
+	k1 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", Stability: &s})
+	k2 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", Stability: &s})
 	if k1 != k2 {
 		t.Errorf("same input produced different keys: %q vs %q", k1, k2)
 	}
 }
 
 func TestKeyDifferent(t *testing.T) {
-	k1 := Key("hello", "m1", "v1", "", nil, nil, nil)
-	k2 := Key("world", "m1", "v1", "", nil, nil, nil)
+	k1 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1"})
+	k2 := Key(KeyParams{Text: "world", Model: "m1", VoiceID: "v1"})
 	if k1 == k2 {
 		t.Error("different input produced same key")
 	}
@@ -208,9 +208,9 @@ func TestKeyWithOptimizeLatency(t *testing.T) {
 	latency0 := 0
 	latency4 := 4
 
-	k1 := Key("hello", "m1", "v1", "", nil, nil, &latency0)
-	k2 := Key("hello", "m1", "v1", "", nil, nil, &latency4)
-	k3 := Key("hello", "m1", "v1", "", nil, nil, nil)
+	k1 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", OptimizeStreamingLatency: &latency0})
+	k2 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", OptimizeStreamingLatency: &latency4})
+	k3 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1"})
 
 	if k1 == k2 {
 		t.Error("different optimize_latency should produce different keys")
@@ -220,6 +220,68 @@ func TestKeyWithOptimizeLatency(t *testing.T) {
 	}
 }
 
+func TestKeyWithOutputFormat(t *testing.T) {
+	k1 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", OutputFormat: "pcm_16000"})
+	k2 := Key(KeyParams{Text: "hello", Model: "m1", VoiceID: "v1", OutputFormat: "ulaw_8000"})
+	if k1 == k2 {
+		t.Error("different output formats should produce different keys")
+	}
+}
+
+func TestKeyWithExtendedVoiceSettings(t *testing.T) {
+	style := 0.3
+	boost := true
+	speed := 0.9
+
+	base := KeyParams{Text: "hello", Model: "m1", VoiceID: "v1"}
+	withStyle := base
+	withStyle.Style = &style
+	withBoost := base
+	withBoost.UseSpeakerBoost = &boost
+	withSpeed := base
+	withSpeed.Speed = &speed
+
+	keys := map[string]string{}
+	for name, p := range map[string]KeyParams{"base": base, "style": withStyle, "boost": withBoost, "speed": withSpeed} {
+		k := Key(p)
+		if other, ok := keys[k]; ok {
+			t.Errorf("%s and %s produce the same key", name, other)
+		}
+		keys[k] = name
+	}
+}
+
+func TestKeyWithLexicon(t *testing.T) {
+	base := KeyParams{Text: "Nupi", Model: "m1", VoiceID: "v1"}
+	withLexicon := base
+	withLexicon.Lexicon = "rewrite:abc"
+	updated := base
+	updated.Lexicon = "rewrite:def"
+	if Key(base) == Key(withLexicon) || Key(withLexicon) == Key(updated) {
+		t.Error("lexicon version should change the key")
+	}
+}
+
+func TestKeyWithContext(t *testing.T) {
+	base := KeyParams{Text: "Second part.", Model: "m1", VoiceID: "v1"}
+	before := base
+	before.PreviousText = "First part."
+	after := base
+	after.NextText = "First part."
+	if Key(base) == Key(before) || Key(before) == Key(after) {
+		t.Error("previous and next text should change the key")
+	}
+}
+
+func TestKeyWithAlignment(t *testing.T) {
+	audio := KeyParams{Text: "Hello", Model: "m1", VoiceID: "v1"}
+	alignment := audio
+	alignment.Alignment = true
+	if Key(audio) == Key(alignment) {
+		t.Error("alignment key should differ from the audio key")
+	}
+}
+
 func TestStaleFileCleanup(t *testing.T) {
 	dir := t.TempDir()
 	c, err := New(dir, 1024*1024, nil)
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tempPattern names the files of entries being written. They do not end in
+// .pcm, so an entry left behind by a crash is never loaded.
+const tempPattern = "*.tmp"
+
+// staleTempAge is how long a temporary file goes unwritten before it is
+// taken for one left behind by a crash rather than another process's write
+// in progress.
+const staleTempAge = time.Hour
+
+var errWriterClosed = errors.New("cache: writer already committed or aborted")
+
+// Writer streams an entry into the cache as its data arrives. The data goes
+// to a temporary file that becomes the entry, atomically, on Commit; until
+// then, and after Abort, the entry is not visible.
+type Writer struct {
+	c    *Cache
+	key  string
+	file *os.File
+	size int64
+	skip bool // the entry outgrew the cache and is discarded
+	done bool
+}
+
+// Writer starts writing the entry for key. The caller must end the write
+// with Commit or Abort; Abort after Commit does nothing, so it can be
+// deferred.
+func (c *Cache) Writer(key string) (*Writer, error) {
+	f, err := os.CreateTemp(c.dir, key+"."+tempPattern)
+	if err != nil {
+		return nil, fmt.Errorf("cache: create temp file: %w", err)
+	}
+	// CreateTemp makes the file private; entries are readable like Put's.
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("cache: create temp file: %w", err)
+	}
+	return &Writer{c: c, key: key, file: f}, nil
+}
+
+// Write appends p to the entry. Once the entry exceeds the cache's size cap
+// further data is discarded and Commit stores nothing, like Put.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, errWriterClosed
+	}
+	if w.skip {
+		return len(p), nil
+	}
+	if w.size+int64(len(p)) > w.c.maxBytes {
+		w.skip = true
+		w.discard()
+		return len(p), nil
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("cache: write: %w", err)
+	}
+	return n, nil
+}
+
+// Commit stores the data written as the entry for key, replacing any
+// previous entry and evicting least-recently-used entries if necessary.
+func (w *Writer) Commit() error {
+	if w.done {
+		return errWriterClosed
+	}
+	w.done = true
+	if w.skip {
+		return nil
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("cache: write: %w", err)
+	}
+
+	c := w.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.entries[w.key]; ok {
+		os.Remove(old.path)
+		delete(c.entries, w.key)
+	}
+
+	c.evict(w.size)
+
+	p := filepath.Join(c.dir, w.key+".pcm")
+	if err := os.Rename(w.file.Name(), p); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("cache: commit: %w", err)
+	}
+
+	c.entries[w.key] = &entry{
+		size:       w.size,
+		accessedAt: time.Now(),
+		path:       p,
+	}
+	return nil
+}
+
+// Abort discards the data written.
+func (w *Writer) Abort() {
+	if w.done {
+		return
+	}
+	w.done = true
+	if !w.skip {
+		w.discard()
+	}
+}
+
+// discard closes and removes the temporary file.
+func (w *Writer) discard() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
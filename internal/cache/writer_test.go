@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// files lists the names of the files in dir.
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriterCommit(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	w, err := c.Writer("key1")
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	w.Write([]byte("hello "))
+	if _, ok := c.Get("key1"); ok {
+		t.Error("entry visible before Commit")
+	}
+	w.Write([]byte("audio"))
+	if err := w.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	w.Abort() // no-op after Commit
+
+	got, ok := c.Get("key1")
+	if !ok || string(got) != "hello audio" {
+		t.Errorf("Get = %q, %v; want %q", got, ok, "hello audio")
+	}
+	if names := files(t, dir); len(names) != 1 || names[0] != "key1.pcm" {
+		t.Errorf("files = %v, want only key1.pcm", names)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "key1.pcm")); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("entry mode = %v, %v; want %v", info.Mode().Perm(), err, os.FileMode(0o644))
+	}
+	if err := w.Commit(); err == nil {
+		t.Error("second Commit succeeded")
+	}
+}
+
+func TestWriterAbort(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	c.Put("key1", []byte("old"))
+
+	w, err := c.Writer("key1")
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	w.Write([]byte("partial"))
+	w.Abort()
+
+	// The previous entry is untouched and nothing partial is left behind.
+	got, ok := c.Get("key1")
+	if !ok || string(got) != "old" {
+		t.Errorf("Get = %q, %v; want %q", got, ok, "old")
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("files = %v, want only the previous entry", names)
+	}
+	if _, err := w.Write([]byte("more")); err == nil {
+		t.Error("Write after Abort succeeded")
+	}
+}
+
+func TestWriterOversized(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 50, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	w, err := c.Writer("big")
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write(make([]byte, 30)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, ok := c.Get("big"); ok {
+		t.Error("oversized entry should not be cached")
+	}
+	if names := files(t, dir); len(names) != 0 {
+		t.Errorf("files = %v, want none", names)
+	}
+}
+
+func TestLoadExistingRemovesStalePartialWrites(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "abc123.pcm"), []byte("audio data"), 0o644)
+	os.WriteFile(filepath.Join(dir, "def456.1234.tmp"), []byte("partial"), 0o644)
+	os.WriteFile(filepath.Join(dir, "ghi789.5678.tmp"), []byte("in progress"), 0o644)
+	stale := time.Now().Add(-2 * staleTempAge)
+	os.Chtimes(filepath.Join(dir, "def456.1234.tmp"), stale, stale)
+
+	c, err := New(dir, 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, ok := c.Get("abc123"); !ok {
+		t.Error("expected abc123 to be loaded")
+	}
+	// The abandoned write is removed; the recent one may still be going on
+	// in another process.
+	if names := files(t, dir); len(names) != 2 || names[0] != "abc123.pcm" || names[1] != "ghi789.5678.tmp" {
+		t.Errorf("files = %v, want only the stale partial write removed", names)
+	}
+}
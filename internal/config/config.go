@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"strings"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/audio"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/textnorm"
 )
 
 const (
@@ -13,6 +18,57 @@ const (
 	DefaultLogLevel       = "info"
 	DefaultCacheMaxSizeMB = 100
 	DefaultLanguage       = "client"
+	DefaultBackend        = BackendHTTP
+	DefaultOutputFormat   = elevenlabs.DefaultOutputFormat
+
+	DefaultRetryMaxAttempts         = 3
+	DefaultRetryBaseDelayMs         = 250
+	DefaultRetryMaxDelayMs          = 5000
+	DefaultCircuitBreakerThreshold  = 5
+	DefaultCircuitBreakerCooldownMs = 30000
+
+	DefaultLexiconMode       = LexiconModeRewrite
+	DefaultTextNormalization = "all"
+
+	DefaultSynthesisConcurrency = 2
+	MaxSynthesisConcurrency     = 8
+
+	DefaultRequestStitchingTTLSec = 300
+
+	DefaultPacingLeadMs = 500
+
+	DefaultQueueTimeoutMs = 10000
+
+	DefaultSilenceThresholdDB = -50.0
+	MinSilenceThresholdDB     = -120.0
+	MaxSilencePaddingMs       = 1000
+
+	DefaultLoudnessTargetLUFS = -16.0
+	MinLoudnessTargetLUFS     = -70.0
+	DefaultLoudnessMaxGainDB  = 12.0
+	MaxLoudnessMaxGainDB      = 40.0
+	MaxFadeMs                 = 100
+
+	DefaultFrameMs = 100
+	MinFrameMs     = 10
+	MaxFrameMs     = 1000
+)
+
+// Ways to apply a pronunciation lexicon, selectable via Config.LexiconMode.
+const (
+	// LexiconModeRewrite replaces listed words in the request text locally.
+	LexiconModeRewrite = "rewrite"
+	// LexiconModeUpload uploads the lexicon as an ElevenLabs pronunciation
+	// dictionary and references it in every request.
+	LexiconModeUpload = "upload"
+)
+
+// Synthesis backends selectable via Config.Backend.
+const (
+	// BackendHTTP sends the whole text in a single POST to the streaming endpoint.
+	BackendHTTP = "http"
+	// BackendWebSocket pushes text over the stream-input WebSocket endpoint.
+	BackendWebSocket = "websocket"
 )
 
 // Config captures bootstrap configuration extracted from environment variables
@@ -28,11 +84,100 @@ type Config struct {
 	Stability                *float64
 	SimilarityBoost          *float64
 	OptimizeStreamingLatency *int
+	Style                    *float64
+	UseSpeakerBoost          *bool
+	Speed                    *float64
 
 	// Language mode: "client" (default), "auto", or ISO 639-1 code
 	// (e.g. "pl", "en"). Passed to ElevenLabs API as language_code.
 	Language string
 
+	// Synthesis backend: "http" (default) or "websocket".
+	Backend string
+
+	// ElevenLabs output_format, e.g. "pcm_16000" (default), "pcm_44100",
+	// "ulaw_8000", "mp3_44100_128". Requests may override it via metadata.
+	OutputFormat string
+
+	// Local audio processing applied before chunks are emitted. Zero values
+	// keep the upstream rate, channel count and encoding.
+	OutputSampleRate int
+	OutputChannels   int
+	OutputEncoding   string // "pcm_s16le", "mulaw" or "alaw"
+
+	// Retry settings for transient upstream failures (429, 5xx, network).
+	// RetryMaxAttempts counts the first attempt; 1 disables retries. Zero
+	// values fall back to the defaults.
+	RetryMaxAttempts int
+	RetryBaseDelayMs int
+	RetryMaxDelayMs  int
+
+	// Circuit breaker: consecutive transient failures before requests fail
+	// fast, and how long to wait before a trial request. 0 disables it.
+	CircuitBreakerThreshold  int
+	CircuitBreakerCooldownMs int
+
+	// Pronunciation lexicon: path to a PLS (.pls, .xml) or YAML (.yaml, .yml)
+	// file, applied as "rewrite" (default) or "upload".
+	LexiconPath string
+	LexiconMode string
+
+	// TextNormalization lists the textnorm rule sets applied before
+	// synthesis: "all", "none", or names such as "markdown,urls", with
+	// "-name" removing one ("all,-numbers").
+	TextNormalization string
+
+	// SentenceSegmentation synthesizes long texts sentence by sentence,
+	// fetching up to SynthesisConcurrency sentences ahead of playback.
+	SentenceSegmentation bool
+	SynthesisConcurrency int
+
+	// Timestamps requests character timings from ElevenLabs and attaches
+	// them to the audio chunks. Requires the HTTP backend.
+	Timestamps bool
+
+	// Visemes attaches a lip-sync viseme track to the audio chunks, derived
+	// from ElevenLabs character timings where the backend provides them.
+	Visemes bool
+
+	// RequestStitching references the previous utterances of a session in
+	// each request so intonation carries over; a session's history is
+	// forgotten after RequestStitchingTTLSec without requests.
+	RequestStitching       bool
+	RequestStitchingTTLSec int
+
+	// TrimSilence removes silence before the first and after the last sound
+	// of an utterance; samples below SilenceThresholdDB (dBFS) count as
+	// silence, DefaultSilenceThresholdDB when nil. SilencePaddingMs of
+	// silence is then added to each end.
+	TrimSilence        bool
+	SilenceThresholdDB *float64
+	SilencePaddingMs   int
+
+	// LoudnessNormalization steers every utterance towards
+	// LoudnessTargetLUFS, applying at most LoudnessMaxGainDB of gain or
+	// attenuation; nil selects DefaultLoudnessTargetLUFS and
+	// DefaultLoudnessMaxGainDB. FadeMs ramps each utterance in and out.
+	LoudnessNormalization bool
+	LoudnessTargetLUFS    *float64
+	LoudnessMaxGainDB     *float64
+	FadeMs                int
+
+	// FrameMs is the duration of every audio chunk but the last; chunks
+	// always end on a sample boundary. Compressed audio is not framed.
+	FrameMs int
+
+	// PacedDelivery sends audio chunks at the rate they play, at most
+	// PacingLeadMs ahead of real time, instead of as fast as they arrive.
+	PacedDelivery bool
+	PacingLeadMs  int
+
+	// MaxConcurrentRequests caps the upstream requests in flight across all
+	// calls; 0 leaves them unlimited. Requests over the cap wait in a queue
+	// ordered by priority, for at most QueueTimeoutMs.
+	MaxConcurrentRequests int
+	QueueTimeoutMs        int
+
 	// Cache settings
 	CacheDir       string
 	CacheMaxSizeMB int
@@ -66,27 +211,250 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("config: language must be 'client', 'auto', or a short ISO 639-1 code, got %q", c.Language)
 	}
 
+	c.Backend = strings.ToLower(strings.TrimSpace(c.Backend))
+	if c.Backend == "" {
+		c.Backend = DefaultBackend
+	}
+	if c.Backend != BackendHTTP && c.Backend != BackendWebSocket {
+		return fmt.Errorf("config: synthesis_backend must be %q or %q, got %q", BackendHTTP, BackendWebSocket, c.Backend)
+	}
+	if c.Timestamps && c.Backend != BackendHTTP {
+		return fmt.Errorf("config: timestamps require the %q synthesis_backend", BackendHTTP)
+	}
+
+	format, err := elevenlabs.ParseOutputFormat(c.OutputFormat)
+	if err != nil {
+		return fmt.Errorf("config: output_format: %w", err)
+	}
+	c.OutputFormat = format.Name
+
+	// Local audio processing validation
+	if c.OutputSampleRate != 0 && (c.OutputSampleRate < 8000 || c.OutputSampleRate > 192000) {
+		return fmt.Errorf("config: output_sample_rate must be 0 or between 8000 and 192000, got %d", c.OutputSampleRate)
+	}
+	if c.OutputChannels < 0 || c.OutputChannels > 8 {
+		return fmt.Errorf("config: output_channels must be between 0 and 8, got %d", c.OutputChannels)
+	}
+	c.OutputEncoding = strings.ToLower(strings.TrimSpace(c.OutputEncoding))
+	if c.OutputEncoding != "" {
+		if _, err := audio.ParseEncoding(c.OutputEncoding); err != nil {
+			return fmt.Errorf("config: output_encoding: %w", err)
+		}
+	}
+	if (c.OutputSampleRate != 0 || c.OutputChannels != 0 || c.OutputEncoding != "") && format.Compressed() {
+		return fmt.Errorf("config: local audio processing requires a PCM or G.711 output_format, got %q", c.OutputFormat)
+	}
+
+	// Retry validation
+	if c.RetryMaxAttempts == 0 {
+		c.RetryMaxAttempts = DefaultRetryMaxAttempts
+	}
+	if c.RetryBaseDelayMs == 0 {
+		c.RetryBaseDelayMs = DefaultRetryBaseDelayMs
+	}
+	if c.RetryMaxDelayMs == 0 {
+		c.RetryMaxDelayMs = DefaultRetryMaxDelayMs
+	}
+	if c.CircuitBreakerCooldownMs == 0 {
+		c.CircuitBreakerCooldownMs = DefaultCircuitBreakerCooldownMs
+	}
+	if c.RetryMaxAttempts < 1 || c.RetryMaxAttempts > 10 {
+		return fmt.Errorf("config: retry_max_attempts must be between 1 and 10, got %d", c.RetryMaxAttempts)
+	}
+	if c.RetryBaseDelayMs < 0 {
+		return fmt.Errorf("config: retry_base_delay_ms must be >= 0, got %d", c.RetryBaseDelayMs)
+	}
+	if c.RetryMaxDelayMs < c.RetryBaseDelayMs {
+		return fmt.Errorf("config: retry_max_delay_ms must be >= retry_base_delay_ms (%d), got %d", c.RetryBaseDelayMs, c.RetryMaxDelayMs)
+	}
+	if c.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("config: circuit_breaker_threshold must be >= 0, got %d", c.CircuitBreakerThreshold)
+	}
+	if c.CircuitBreakerCooldownMs < 0 {
+		return fmt.Errorf("config: circuit_breaker_cooldown_ms must be >= 0, got %d", c.CircuitBreakerCooldownMs)
+	}
+
+	c.LexiconMode = strings.ToLower(strings.TrimSpace(c.LexiconMode))
+	if c.LexiconMode == "" {
+		c.LexiconMode = DefaultLexiconMode
+	}
+	if c.LexiconMode != LexiconModeRewrite && c.LexiconMode != LexiconModeUpload {
+		return fmt.Errorf("config: lexicon_mode must be %q or %q, got %q", LexiconModeRewrite, LexiconModeUpload, c.LexiconMode)
+	}
+
+	c.TextNormalization = strings.ToLower(strings.TrimSpace(c.TextNormalization))
+	if c.TextNormalization == "" {
+		c.TextNormalization = DefaultTextNormalization
+	}
+	if _, err := textnorm.ParseRules(c.TextNormalization); err != nil {
+		return fmt.Errorf("config: text_normalization: %w", err)
+	}
+
+	if c.SynthesisConcurrency == 0 {
+		c.SynthesisConcurrency = DefaultSynthesisConcurrency
+	}
+	if c.SynthesisConcurrency < 1 || c.SynthesisConcurrency > MaxSynthesisConcurrency {
+		return fmt.Errorf("config: synthesis_concurrency must be between 1 and %d, got %d", MaxSynthesisConcurrency, c.SynthesisConcurrency)
+	}
+	if c.RequestStitchingTTLSec == 0 {
+		c.RequestStitchingTTLSec = DefaultRequestStitchingTTLSec
+	}
+	if c.RequestStitchingTTLSec < 0 {
+		return fmt.Errorf("config: request_stitching_ttl_sec must be > 0, got %d", c.RequestStitchingTTLSec)
+	}
+
+	if c.SilenceThresholdDB != nil && !inRange(*c.SilenceThresholdDB, MinSilenceThresholdDB, 0) {
+		return fmt.Errorf("config: silence_threshold_db must be between %g and 0, got %g", MinSilenceThresholdDB, *c.SilenceThresholdDB)
+	}
+	if c.SilencePaddingMs < 0 || c.SilencePaddingMs > MaxSilencePaddingMs {
+		return fmt.Errorf("config: silence_padding_ms must be between 0 and %d, got %d", MaxSilencePaddingMs, c.SilencePaddingMs)
+	}
+	if c.LoudnessTargetLUFS != nil && !inRange(*c.LoudnessTargetLUFS, MinLoudnessTargetLUFS, 0) {
+		return fmt.Errorf("config: loudness_target_lufs must be between %g and 0, got %g", MinLoudnessTargetLUFS, *c.LoudnessTargetLUFS)
+	}
+	if c.LoudnessMaxGainDB != nil && (!inRange(*c.LoudnessMaxGainDB, 0, MaxLoudnessMaxGainDB) || *c.LoudnessMaxGainDB == 0) {
+		return fmt.Errorf("config: loudness_max_gain_db must be above 0 and at most %g, got %g", MaxLoudnessMaxGainDB, *c.LoudnessMaxGainDB)
+	}
+	if c.FadeMs < 0 || c.FadeMs > MaxFadeMs {
+		return fmt.Errorf("config: fade_ms must be between 0 and %d, got %d", MaxFadeMs, c.FadeMs)
+	}
+	if c.FrameMs == 0 {
+		c.FrameMs = DefaultFrameMs
+	}
+	if c.FrameMs < MinFrameMs || c.FrameMs > MaxFrameMs {
+		return fmt.Errorf("config: frame_ms must be between %d and %d, got %d", MinFrameMs, MaxFrameMs, c.FrameMs)
+	}
+	if c.PacingLeadMs < 0 {
+		return fmt.Errorf("config: pacing_lead_ms must be >= 0, got %d", c.PacingLeadMs)
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("config: max_concurrent_requests must be >= 0, got %d", c.MaxConcurrentRequests)
+	}
+	if c.QueueTimeoutMs == 0 {
+		c.QueueTimeoutMs = DefaultQueueTimeoutMs
+	}
+	if c.QueueTimeoutMs < 0 {
+		return fmt.Errorf("config: queue_timeout_ms must be > 0, got %d", c.QueueTimeoutMs)
+	}
+
 	// Cache validation
 	if c.CacheMaxSizeMB < 0 {
 		return fmt.Errorf("config: cache_max_size_mb must be >= 0, got %d", c.CacheMaxSizeMB)
 	}
 
+	if err := ValidateVoiceID(c.VoiceID); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := ValidateModel(c.Model); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
 	// Validate voice settings ranges if provided
 	if c.Stability != nil {
-		if *c.Stability < 0.0 || *c.Stability > 1.0 {
-			return fmt.Errorf("config: stability must be between 0.0 and 1.0, got %f", *c.Stability)
+		if err := ValidateStability(*c.Stability); err != nil {
+			return fmt.Errorf("config: %w", err)
 		}
 	}
 	if c.SimilarityBoost != nil {
-		if *c.SimilarityBoost < 0.0 || *c.SimilarityBoost > 1.0 {
-			return fmt.Errorf("config: similarity_boost must be between 0.0 and 1.0, got %f", *c.SimilarityBoost)
+		if err := ValidateSimilarityBoost(*c.SimilarityBoost); err != nil {
+			return fmt.Errorf("config: %w", err)
 		}
 	}
 	if c.OptimizeStreamingLatency != nil {
-		if *c.OptimizeStreamingLatency < 0 || *c.OptimizeStreamingLatency > 4 {
-			return fmt.Errorf("config: optimize_streaming_latency must be between 0 and 4, got %d", *c.OptimizeStreamingLatency)
+		if err := ValidateOptimizeStreamingLatency(*c.OptimizeStreamingLatency); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	if c.Style != nil {
+		if err := ValidateStyle(*c.Style); err != nil {
+			return fmt.Errorf("config: %w", err)
 		}
 	}
+	if c.Speed != nil {
+		if err := ValidateSpeed(*c.Speed); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// The checks below are shared by Validate and per-request overrides so both
+// accept the same values.
+
+// ValidateVoiceID checks that id looks like an ElevenLabs voice ID. The ID is
+// used as a URL path segment, so only letters, digits, '-' and '_' are allowed.
+func ValidateVoiceID(id string) error {
+	if !isIdentifier(id) {
+		return fmt.Errorf("voice_id must be 1-64 letters, digits, '-' or '_', got %q", id)
+	}
+	return nil
+}
+
+// ValidateModel checks that id looks like an ElevenLabs model ID.
+func ValidateModel(id string) error {
+	if !isIdentifier(id) {
+		return fmt.Errorf("model must be 1-64 letters, digits, '-' or '_', got %q", id)
+	}
+	return nil
+}
+
+// ValidateStability checks the stability voice setting.
+func ValidateStability(v float64) error {
+	if !inRange(v, 0.0, 1.0) {
+		return fmt.Errorf("stability must be between 0.0 and 1.0, got %f", v)
+	}
+	return nil
+}
+
+// ValidateSimilarityBoost checks the similarity_boost voice setting.
+func ValidateSimilarityBoost(v float64) error {
+	if !inRange(v, 0.0, 1.0) {
+		return fmt.Errorf("similarity_boost must be between 0.0 and 1.0, got %f", v)
+	}
+	return nil
+}
+
+// ValidateOptimizeStreamingLatency checks the optimize_streaming_latency level.
+func ValidateOptimizeStreamingLatency(v int) error {
+	if v < 0 || v > 4 {
+		return fmt.Errorf("optimize_streaming_latency must be between 0 and 4, got %d", v)
+	}
+	return nil
+}
+
+// ValidateStyle checks the style exaggeration voice setting.
+func ValidateStyle(v float64) error {
+	if !inRange(v, 0.0, 1.0) {
+		return fmt.Errorf("style must be between 0.0 and 1.0, got %f", v)
+	}
+	return nil
+}
 
+// ValidateSpeed checks the speed voice setting; ElevenLabs accepts 0.7-1.2.
+func ValidateSpeed(v float64) error {
+	if !inRange(v, 0.7, 1.2) {
+		return fmt.Errorf("speed must be between 0.7 and 1.2, got %f", v)
+	}
 	return nil
 }
+
+// inRange reports whether v is a number between lo and hi; NaN and the
+// infinities are not.
+func inRange(v, lo, hi float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && v >= lo && v <= hi
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
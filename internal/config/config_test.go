@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestValidateAppliesDefaults(t *testing.T) {
 	cfg := Config{
@@ -47,6 +50,8 @@ func TestValidateStabilityRange(t *testing.T) {
 		{"one", 1.0, false},
 		{"negative", -0.1, true},
 		{"over_one", 1.1, true},
+		{"nan", math.NaN(), true},
+		{"inf", math.Inf(1), true},
 	}
 
 	for _, tt := range tests {
@@ -91,3 +96,426 @@ func TestValidateCacheMaxSizeMB(t *testing.T) {
 		t.Fatalf("CacheMaxSizeMB=200 should be valid: %v", err)
 	}
 }
+
+func TestValidateBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", BackendHTTP, false},
+		{"http", "http", BackendHTTP, false},
+		{"websocket", "websocket", BackendWebSocket, false},
+		{"case_insensitive", " WebSocket ", BackendWebSocket, false},
+		{"unknown", "grpc", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr: "127.0.0.1:50051",
+				APIKey:     "test-key",
+				Backend:    tt.backend,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Backend=%q: err=%v, wantErr=%v", tt.backend, err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.Backend != tt.want {
+				t.Errorf("Backend = %q, want %q", cfg.Backend, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", DefaultOutputFormat, false},
+		{"pcm_24000", "pcm_24000", "pcm_24000", false},
+		{"normalised", " ULAW_8000 ", "ulaw_8000", false},
+		{"mp3", "mp3_44100_128", "mp3_44100_128", false},
+		{"invalid", "flac_48000", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:   "127.0.0.1:50051",
+				APIKey:       "test-key",
+				OutputFormat: tt.format,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OutputFormat=%q: err=%v, wantErr=%v", tt.format, err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.OutputFormat != tt.want {
+				t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLocalAudioProcessing(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		sampleRate int
+		channels   int
+		encoding   string
+		wantErr    bool
+	}{
+		{"disabled", "", 0, 0, "", false},
+		{"resample_48k", "pcm_16000", 48000, 0, "", false},
+		{"stereo_mulaw", "pcm_24000", 8000, 2, "MULAW", false},
+		{"rate_too_low", "", 4000, 0, "", true},
+		{"negative_channels", "", 0, -1, "", true},
+		{"bad_encoding", "", 0, 0, "flac", true},
+		{"compressed_upstream", "mp3_44100_128", 48000, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:       "127.0.0.1:50051",
+				APIKey:           "test-key",
+				OutputFormat:     tt.format,
+				OutputSampleRate: tt.sampleRate,
+				OutputChannels:   tt.channels,
+				OutputEncoding:   tt.encoding,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRetrySettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempts    int
+		baseDelay   int
+		maxDelay    int
+		threshold   int
+		wantErr     bool
+		wantAttempt int
+	}{
+		{"defaults", 0, 0, 0, 0, false, DefaultRetryMaxAttempts},
+		{"disabled", 1, 0, 0, 0, false, 1},
+		{"custom", 5, 100, 2000, 3, false, 5},
+		{"too_many_attempts", 11, 0, 0, 0, true, 0},
+		{"negative_attempts", -1, 0, 0, 0, true, 0},
+		{"max_below_base", 3, 1000, 500, 0, true, 0},
+		{"negative_threshold", 3, 0, 0, -1, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:              "127.0.0.1:50051",
+				APIKey:                  "test-key",
+				RetryMaxAttempts:        tt.attempts,
+				RetryBaseDelayMs:        tt.baseDelay,
+				RetryMaxDelayMs:         tt.maxDelay,
+				CircuitBreakerThreshold: tt.threshold,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.RetryMaxAttempts != tt.wantAttempt {
+				t.Errorf("RetryMaxAttempts = %d, want %d", cfg.RetryMaxAttempts, tt.wantAttempt)
+			}
+		})
+	}
+}
+
+func TestValidateVoiceAndModelIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		voiceID string
+		model   string
+		wantErr bool
+	}{
+		{"defaults", "", "", false},
+		{"custom", "21m00Tcm4TlvDq8ikWAM", "eleven_multilingual_v2", false},
+		{"voice_path", "../x", "", true},
+		{"voice_query", "abc?x=1", "", true},
+		{"model_space", "", "eleven turbo", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr: "127.0.0.1:50051",
+				APIKey:     "test-key",
+				VoiceID:    tt.voiceID,
+				Model:      tt.model,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExtendedVoiceSettings(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		style   *float64
+		speed   *float64
+		wantErr bool
+	}{
+		{"unset", nil, nil, false},
+		{"valid", f(0.5), f(1.0), false},
+		{"speed_min", nil, f(0.7), false},
+		{"speed_max", nil, f(1.2), false},
+		{"style_over_one", f(1.5), nil, true},
+		{"style_negative", f(-0.1), nil, true},
+		{"speed_too_slow", nil, f(0.5), true},
+		{"speed_too_fast", nil, f(2.0), true},
+		{"style_nan", f(math.NaN()), nil, true},
+		{"speed_nan", nil, f(math.NaN()), true},
+		{"speed_inf", nil, f(math.Inf(-1)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr: "127.0.0.1:50051",
+				APIKey:     "test-key",
+				Style:      tt.style,
+				Speed:      tt.speed,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLexiconMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{"", LexiconModeRewrite, false},
+		{"Upload", LexiconModeUpload, false},
+		{"rewrite", LexiconModeRewrite, false},
+		{"inline", "", true},
+	}
+	for _, tt := range tests {
+		cfg := Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", LexiconMode: tt.mode}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("mode %q: err=%v, wantErr=%v", tt.mode, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && cfg.LexiconMode != tt.want {
+			t.Errorf("mode %q normalized to %q, want %q", tt.mode, cfg.LexiconMode, tt.want)
+		}
+	}
+}
+
+func TestValidateTextNormalization(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"", DefaultTextNormalization, false},
+		{" Markdown,URLs ", "markdown,urls", false},
+		{"all,-numbers", "all,-numbers", false},
+		{"none", "none", false},
+		{"markdown,emoji", "", true},
+	}
+	for _, tt := range tests {
+		cfg := Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", TextNormalization: tt.spec}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("spec %q: err=%v, wantErr=%v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && cfg.TextNormalization != tt.want {
+			t.Errorf("spec %q normalized to %q, want %q", tt.spec, cfg.TextNormalization, tt.want)
+		}
+	}
+}
+
+func TestValidateSynthesisConcurrency(t *testing.T) {
+	tests := []struct {
+		value   int
+		want    int
+		wantErr bool
+	}{
+		{0, DefaultSynthesisConcurrency, false},
+		{1, 1, false},
+		{MaxSynthesisConcurrency, MaxSynthesisConcurrency, false},
+		{MaxSynthesisConcurrency + 1, 0, true},
+		{-1, 0, true},
+	}
+	for _, tt := range tests {
+		cfg := Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", SynthesisConcurrency: tt.value}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("value %d: err=%v, wantErr=%v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && cfg.SynthesisConcurrency != tt.want {
+			t.Errorf("value %d normalized to %d, want %d", tt.value, cfg.SynthesisConcurrency, tt.want)
+		}
+	}
+}
+
+func TestValidateSilenceTrimming(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name      string
+		threshold *float64
+		padding   int
+		wantErr   bool
+	}{
+		{"defaults", nil, 0, false},
+		{"custom", f(-40), 200, false},
+		{"zero_threshold", f(0), 0, false},
+		{"positive_threshold", f(3), 0, true},
+		{"threshold_too_low", f(MinSilenceThresholdDB - 1), 0, true},
+		{"threshold_nan", f(math.NaN()), 0, true},
+		{"negative_padding", f(-40), -1, true},
+		{"padding_too_long", f(-40), MaxSilencePaddingMs + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:         "127.0.0.1:50051",
+				APIKey:             "test-key",
+				TrimSilence:        true,
+				SilenceThresholdDB: tt.threshold,
+				SilencePaddingMs:   tt.padding,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLoudnessNormalization(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		target  *float64
+		maxGain *float64
+		fade    int
+		wantErr bool
+	}{
+		{"defaults", nil, nil, 0, false},
+		{"custom", f(-23), f(6), 10, false},
+		{"zero_target", f(0), nil, 0, false},
+		{"zero_gain", f(-23), f(0), 0, true},
+		{"positive_target", f(1), nil, 0, true},
+		{"target_too_low", f(MinLoudnessTargetLUFS - 1), nil, 0, true},
+		{"target_nan", f(math.NaN()), nil, 0, true},
+		{"negative_gain", f(-23), f(-1), 0, true},
+		{"gain_too_high", f(-23), f(MaxLoudnessMaxGainDB + 1), 0, true},
+		{"gain_inf", f(-23), f(math.Inf(1)), 0, true},
+		{"negative_fade", f(-23), f(6), -1, true},
+		{"fade_too_long", f(-23), f(6), MaxFadeMs + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:            "127.0.0.1:50051",
+				APIKey:                "test-key",
+				LoudnessNormalization: true,
+				LoudnessTargetLUFS:    tt.target,
+				LoudnessMaxGainDB:     tt.maxGain,
+				FadeMs:                tt.fade,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAdmission(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRequests int
+		timeout     int
+		wantTimeout int
+		wantErr     bool
+	}{
+		{"defaults", 0, 0, DefaultQueueTimeoutMs, false},
+		{"custom", 4, 2000, 2000, false},
+		{"negative_limit", -1, 0, 0, true},
+		{"negative_timeout", 4, -1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				ListenAddr:            "127.0.0.1:50051",
+				APIKey:                "test-key",
+				MaxConcurrentRequests: tt.maxRequests,
+				QueueTimeoutMs:        tt.timeout,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.QueueTimeoutMs != tt.wantTimeout {
+				t.Errorf("QueueTimeoutMs = %d, want %d", cfg.QueueTimeoutMs, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestValidateFrameMs(t *testing.T) {
+	tests := []struct {
+		value   int
+		want    int
+		wantErr bool
+	}{
+		{0, DefaultFrameMs, false},
+		{20, 20, false},
+		{MinFrameMs - 1, 0, true},
+		{MaxFrameMs + 1, 0, true},
+		{-20, 0, true},
+	}
+	for _, tt := range tests {
+		cfg := Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", FrameMs: tt.value}
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("value %d: err=%v, wantErr=%v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && cfg.FrameMs != tt.want {
+			t.Errorf("value %d normalized to %d, want %d", tt.value, cfg.FrameMs, tt.want)
+		}
+	}
+}
+
+func TestValidateTimestampsRequireHTTP(t *testing.T) {
+	cfg := Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", Timestamps: true}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("timestamps with the default backend: %v", err)
+	}
+	cfg = Config{ListenAddr: "127.0.0.1:50051", APIKey: "test-key", Timestamps: true, Backend: BackendWebSocket}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an error for timestamps with the websocket backend")
+	}
+}
@@ -24,6 +24,10 @@ func (l Loader) Load() (Config, error) {
 	cfg := Config{
 		ListenAddr:     DefaultListenAddr,
 		CacheMaxSizeMB: DefaultCacheMaxSizeMB,
+
+		CircuitBreakerThreshold: DefaultCircuitBreakerThreshold,
+		RequestStitching:        true,
+		PacingLeadMs:            DefaultPacingLeadMs,
 	}
 
 	if raw, ok := l.Lookup("NUPI_ADAPTER_CONFIG"); ok && strings.TrimSpace(raw) != "" {
@@ -61,9 +65,43 @@ func applyJSON(raw string, cfg *Config) error {
 		Stability                *float64 `json:"stability"`
 		SimilarityBoost          *float64 `json:"similarity_boost"`
 		OptimizeStreamingLatency *int     `json:"optimize_streaming_latency"`
+		Style                    *float64 `json:"style"`
+		UseSpeakerBoost          *bool    `json:"use_speaker_boost"`
+		Speed                    *float64 `json:"speed"`
+		LexiconPath              string   `json:"lexicon_path"`
+		LexiconMode              string   `json:"lexicon_mode"`
+		TextNormalization        string   `json:"text_normalization"`
+		SentenceSegmentation     *bool    `json:"sentence_segmentation"`
+		SynthesisConcurrency     *int     `json:"synthesis_concurrency"`
+		Timestamps               bool     `json:"timestamps"`
+		Visemes                  bool     `json:"visemes"`
+		RequestStitching         *bool    `json:"request_stitching"`
+		RequestStitchingTTLSec   *int     `json:"request_stitching_ttl_sec"`
+		TrimSilence              bool     `json:"trim_silence"`
+		SilenceThresholdDB       *float64 `json:"silence_threshold_db"`
+		SilencePaddingMs         *int     `json:"silence_padding_ms"`
+		LoudnessNormalization    bool     `json:"loudness_normalization"`
+		LoudnessTargetLUFS       *float64 `json:"loudness_target_lufs"`
+		LoudnessMaxGainDB        *float64 `json:"loudness_max_gain_db"`
+		FadeMs                   *int     `json:"fade_ms"`
+		FrameMs                  *int     `json:"frame_ms"`
+		PacedDelivery            bool     `json:"paced_delivery"`
+		PacingLeadMs             *int     `json:"pacing_lead_ms"`
+		MaxConcurrentRequests    *int     `json:"max_concurrent_requests"`
+		QueueTimeoutMs           *int     `json:"queue_timeout_ms"`
 		CacheDir                 string   `json:"cache_dir"`
 		CacheMaxSizeMB           *int     `json:"cache_max_size_mb"`
 		Language                 string   `json:"language"`
+		Backend                  string   `json:"synthesis_backend"`
+		OutputFormat             string   `json:"output_format"`
+		OutputSampleRate         int      `json:"output_sample_rate"`
+		OutputChannels           int      `json:"output_channels"`
+		OutputEncoding           string   `json:"output_encoding"`
+		RetryMaxAttempts         *int     `json:"retry_max_attempts"`
+		RetryBaseDelayMs         *int     `json:"retry_base_delay_ms"`
+		RetryMaxDelayMs          *int     `json:"retry_max_delay_ms"`
+		CircuitBreakerThreshold  *int     `json:"circuit_breaker_threshold"`
+		CircuitBreakerCooldownMs *int     `json:"circuit_breaker_cooldown_ms"`
 		UseStubSynthesizer       bool     `json:"use_stub_synthesizer"`
 	}
 	var payload jsonConfig
@@ -94,6 +132,78 @@ func applyJSON(raw string, cfg *Config) error {
 	if payload.OptimizeStreamingLatency != nil {
 		assignIntPtr(&cfg.OptimizeStreamingLatency, *payload.OptimizeStreamingLatency)
 	}
+	if payload.Style != nil {
+		assignFloat64Ptr(&cfg.Style, *payload.Style)
+	}
+	if payload.UseSpeakerBoost != nil {
+		assignBoolPtr(&cfg.UseSpeakerBoost, *payload.UseSpeakerBoost)
+	}
+	if payload.Speed != nil {
+		assignFloat64Ptr(&cfg.Speed, *payload.Speed)
+	}
+	if payload.LexiconPath != "" {
+		cfg.LexiconPath = payload.LexiconPath
+	}
+	if payload.LexiconMode != "" {
+		cfg.LexiconMode = payload.LexiconMode
+	}
+	if payload.TextNormalization != "" {
+		cfg.TextNormalization = payload.TextNormalization
+	}
+	if payload.SentenceSegmentation != nil {
+		cfg.SentenceSegmentation = *payload.SentenceSegmentation
+	}
+	if payload.SynthesisConcurrency != nil {
+		cfg.SynthesisConcurrency = *payload.SynthesisConcurrency
+	}
+	if payload.Timestamps {
+		cfg.Timestamps = true
+	}
+	if payload.Visemes {
+		cfg.Visemes = true
+	}
+	if payload.RequestStitching != nil {
+		cfg.RequestStitching = *payload.RequestStitching
+	}
+	if payload.RequestStitchingTTLSec != nil {
+		cfg.RequestStitchingTTLSec = *payload.RequestStitchingTTLSec
+	}
+	if payload.TrimSilence {
+		cfg.TrimSilence = true
+	}
+	if payload.SilenceThresholdDB != nil {
+		assignFloat64Ptr(&cfg.SilenceThresholdDB, *payload.SilenceThresholdDB)
+	}
+	if payload.SilencePaddingMs != nil {
+		cfg.SilencePaddingMs = *payload.SilencePaddingMs
+	}
+	if payload.LoudnessNormalization {
+		cfg.LoudnessNormalization = true
+	}
+	if payload.LoudnessTargetLUFS != nil {
+		assignFloat64Ptr(&cfg.LoudnessTargetLUFS, *payload.LoudnessTargetLUFS)
+	}
+	if payload.LoudnessMaxGainDB != nil {
+		assignFloat64Ptr(&cfg.LoudnessMaxGainDB, *payload.LoudnessMaxGainDB)
+	}
+	if payload.FadeMs != nil {
+		cfg.FadeMs = *payload.FadeMs
+	}
+	if payload.FrameMs != nil {
+		cfg.FrameMs = *payload.FrameMs
+	}
+	if payload.PacedDelivery {
+		cfg.PacedDelivery = true
+	}
+	if payload.PacingLeadMs != nil {
+		cfg.PacingLeadMs = *payload.PacingLeadMs
+	}
+	if payload.MaxConcurrentRequests != nil {
+		cfg.MaxConcurrentRequests = *payload.MaxConcurrentRequests
+	}
+	if payload.QueueTimeoutMs != nil {
+		cfg.QueueTimeoutMs = *payload.QueueTimeoutMs
+	}
 	if payload.CacheDir != "" {
 		cfg.CacheDir = payload.CacheDir
 	}
@@ -103,6 +213,36 @@ func applyJSON(raw string, cfg *Config) error {
 	if payload.Language != "" {
 		cfg.Language = payload.Language
 	}
+	if payload.Backend != "" {
+		cfg.Backend = payload.Backend
+	}
+	if payload.OutputFormat != "" {
+		cfg.OutputFormat = payload.OutputFormat
+	}
+	if payload.OutputSampleRate != 0 {
+		cfg.OutputSampleRate = payload.OutputSampleRate
+	}
+	if payload.OutputChannels != 0 {
+		cfg.OutputChannels = payload.OutputChannels
+	}
+	if payload.OutputEncoding != "" {
+		cfg.OutputEncoding = payload.OutputEncoding
+	}
+	if payload.RetryMaxAttempts != nil {
+		cfg.RetryMaxAttempts = *payload.RetryMaxAttempts
+	}
+	if payload.RetryBaseDelayMs != nil {
+		cfg.RetryBaseDelayMs = *payload.RetryBaseDelayMs
+	}
+	if payload.RetryMaxDelayMs != nil {
+		cfg.RetryMaxDelayMs = *payload.RetryMaxDelayMs
+	}
+	if payload.CircuitBreakerThreshold != nil {
+		cfg.CircuitBreakerThreshold = *payload.CircuitBreakerThreshold
+	}
+	if payload.CircuitBreakerCooldownMs != nil {
+		cfg.CircuitBreakerCooldownMs = *payload.CircuitBreakerCooldownMs
+	}
 	if payload.UseStubSynthesizer {
 		cfg.UseStubSynthesizer = true
 	}
@@ -128,6 +268,11 @@ func assignIntPtr(target **int, value int) {
 	*target = &v
 }
 
+func assignBoolPtr(target **bool, value bool) {
+	v := value
+	*target = &v
+}
+
 func overrideBool(lookup func(string) (string, bool), key string, target *bool) error {
 	if lookup == nil || target == nil {
 		return nil
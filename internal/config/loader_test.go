@@ -65,6 +65,112 @@ func TestLoaderDefaults(t *testing.T) {
 	if cfg.Language != DefaultLanguage {
 		t.Errorf("Language = %q, want default %q", cfg.Language, DefaultLanguage)
 	}
+	if cfg.CircuitBreakerThreshold != DefaultCircuitBreakerThreshold {
+		t.Errorf("CircuitBreakerThreshold = %d, want default %d", cfg.CircuitBreakerThreshold, DefaultCircuitBreakerThreshold)
+	}
+	if cfg.SentenceSegmentation {
+		t.Error("SentenceSegmentation = true, want disabled by default")
+	}
+	if cfg.SynthesisConcurrency != DefaultSynthesisConcurrency {
+		t.Errorf("SynthesisConcurrency = %d, want default %d", cfg.SynthesisConcurrency, DefaultSynthesisConcurrency)
+	}
+	if !cfg.RequestStitching || cfg.RequestStitchingTTLSec != DefaultRequestStitchingTTLSec {
+		t.Errorf("RequestStitching = %v/%d, want enabled with TTL %d", cfg.RequestStitching, cfg.RequestStitchingTTLSec, DefaultRequestStitchingTTLSec)
+	}
+}
+
+func TestLoaderRequestStitchingFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "request_stitching": false, "request_stitching_ttl_sec": 60}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.RequestStitching {
+		t.Error("RequestStitching = true, want false")
+	}
+	if cfg.RequestStitchingTTLSec != 60 {
+		t.Errorf("RequestStitchingTTLSec = %d, want 60", cfg.RequestStitchingTTLSec)
+	}
+}
+
+func TestLoaderPacingFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "paced_delivery": true, "pacing_lead_ms": 0}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !cfg.PacedDelivery {
+		t.Error("PacedDelivery = false, want true")
+	}
+	if cfg.PacingLeadMs != 0 {
+		t.Errorf("PacingLeadMs = %d, want 0 (no lead)", cfg.PacingLeadMs)
+	}
+
+	env = fakeEnv(map[string]string{"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "paced_delivery": true}`})
+	if cfg, err = (Loader{Lookup: env}).Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.PacingLeadMs != DefaultPacingLeadMs {
+		t.Errorf("PacingLeadMs = %d, want default %d", cfg.PacingLeadMs, DefaultPacingLeadMs)
+	}
+}
+
+func TestLoaderSilenceThresholdFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "trim_silence": true, "silence_threshold_db": 0}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.SilenceThresholdDB == nil || *cfg.SilenceThresholdDB != 0 {
+		t.Errorf("SilenceThresholdDB = %v, want explicit 0", cfg.SilenceThresholdDB)
+	}
+}
+
+func TestLoaderLoudnessFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "loudness_normalization": true, "loudness_target_lufs": 0}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.LoudnessTargetLUFS == nil || *cfg.LoudnessTargetLUFS != 0 {
+		t.Errorf("LoudnessTargetLUFS = %v, want explicit 0", cfg.LoudnessTargetLUFS)
+	}
+
+	env = fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "loudness_normalization": true, "loudness_max_gain_db": 0}`,
+	})
+	if _, err := (Loader{Lookup: env}).Load(); err == nil {
+		t.Error("Load() accepted loudness_max_gain_db 0, which would fail every request")
+	}
+}
+
+func TestLoaderSegmentationFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "sentence_segmentation": true, "synthesis_concurrency": 4}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !cfg.SentenceSegmentation {
+		t.Error("SentenceSegmentation = false, want true")
+	}
+	if cfg.SynthesisConcurrency != 4 {
+		t.Errorf("SynthesisConcurrency = %d, want 4", cfg.SynthesisConcurrency)
+	}
 }
 
 func TestLoaderLanguageFromJSON(t *testing.T) {
@@ -287,3 +393,77 @@ func TestLoaderCacheDirFromDataDir(t *testing.T) {
 		t.Errorf("CacheDir = %q, want %q", cfg.CacheDir, "/var/nupi/data/cache")
 	}
 }
+
+func TestLoaderBackendFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "synthesis_backend": "websocket"}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Backend != BackendWebSocket {
+		t.Errorf("Backend = %q, want %q", cfg.Backend, BackendWebSocket)
+	}
+}
+
+func TestLoaderOutputFormatFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "output_format": "pcm_24000"}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.OutputFormat != "pcm_24000" {
+		t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, "pcm_24000")
+	}
+}
+
+func TestLoaderRetryFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{
+			"api_key": "sk-test",
+			"retry_max_attempts": 4,
+			"retry_base_delay_ms": 100,
+			"retry_max_delay_ms": 1000,
+			"circuit_breaker_threshold": 0
+		}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.RetryMaxAttempts != 4 || cfg.RetryBaseDelayMs != 100 || cfg.RetryMaxDelayMs != 1000 {
+		t.Errorf("retry = %d/%d/%d, want 4/100/1000", cfg.RetryMaxAttempts, cfg.RetryBaseDelayMs, cfg.RetryMaxDelayMs)
+	}
+	if cfg.CircuitBreakerThreshold != 0 {
+		t.Errorf("CircuitBreakerThreshold = %d, want 0 (disabled)", cfg.CircuitBreakerThreshold)
+	}
+	if cfg.CircuitBreakerCooldownMs != DefaultCircuitBreakerCooldownMs {
+		t.Errorf("CircuitBreakerCooldownMs = %d, want default %d", cfg.CircuitBreakerCooldownMs, DefaultCircuitBreakerCooldownMs)
+	}
+}
+
+func TestLoaderExtendedVoiceSettingsFromJSON(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"NUPI_ADAPTER_CONFIG": `{"api_key": "sk-test", "style": 0.2, "use_speaker_boost": false, "speed": 1.15}`,
+	})
+
+	cfg, err := (Loader{Lookup: env}).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Style == nil || *cfg.Style != 0.2 {
+		t.Errorf("Style = %v, want 0.2", cfg.Style)
+	}
+	if cfg.UseSpeakerBoost == nil || *cfg.UseSpeakerBoost {
+		t.Errorf("UseSpeakerBoost = %v, want false", cfg.UseSpeakerBoost)
+	}
+	if cfg.Speed == nil || *cfg.Speed != 1.15 {
+		t.Errorf("Speed = %v, want 1.15", cfg.Speed)
+	}
+}
@@ -16,6 +16,13 @@ const (
 
 	// DefaultTimeout for HTTP requests (can be overridden per-request).
 	DefaultTimeout = 30 * time.Second
+
+	// MaxStitchingRequestIDs is the most request IDs ElevenLabs accepts in
+	// previous_request_ids or next_request_ids.
+	MaxStitchingRequestIDs = 3
+
+	// requestIDHeader carries the ID of a generation in the response.
+	requestIDHeader = "request-id"
 )
 
 // Client wraps HTTP calls to the ElevenLabs API.
@@ -40,6 +47,9 @@ func NewClient(apiKey string) *Client {
 type VoiceSettings struct {
 	Stability       *float64 `json:"stability,omitempty"`
 	SimilarityBoost *float64 `json:"similarity_boost,omitempty"`
+	Style           *float64 `json:"style,omitempty"`
+	UseSpeakerBoost *bool    `json:"use_speaker_boost,omitempty"`
+	Speed           *float64 `json:"speed,omitempty"`
 }
 
 // SynthesizeRequest describes a TTS synthesis request.
@@ -49,11 +59,42 @@ type SynthesizeRequest struct {
 	LanguageCode             string         `json:"language_code,omitempty"`
 	VoiceSettings            *VoiceSettings `json:"voice_settings,omitempty"`
 	OptimizeStreamingLatency *int           `json:"optimize_streaming_latency,omitempty"`
+
+	PronunciationDictionaryLocators []PronunciationDictionaryLocator `json:"pronunciation_dictionary_locators,omitempty"`
+
+	// Request stitching: PreviousText and NextText surround Text when a
+	// longer text is synthesized in parts, and PreviousRequestIDs and
+	// NextRequestIDs (at most MaxStitchingRequestIDs each) reference earlier
+	// generations, keeping prosody continuous across them. ElevenLabs ignores
+	// PreviousText when PreviousRequestIDs is set. The WebSocket backend
+	// ignores all four.
+	PreviousText       string   `json:"previous_text,omitempty"`
+	NextText           string   `json:"next_text,omitempty"`
+	PreviousRequestIDs []string `json:"previous_request_ids,omitempty"`
+	NextRequestIDs     []string `json:"next_request_ids,omitempty"`
+
+	// OutputFormat is sent as the output_format query parameter rather than
+	// in the body. Empty selects DefaultOutputFormat.
+	OutputFormat string `json:"-"`
+
+	// WithTimestamps selects the with-timestamps endpoint, whose character
+	// timings are reported by Alignment on the returned stream. The
+	// WebSocket backend ignores it.
+	WithTimestamps bool `json:"-"`
+}
+
+// outputFormat returns the output_format query value for the request.
+func (r SynthesizeRequest) outputFormat() string {
+	if r.OutputFormat == "" {
+		return DefaultOutputFormat
+	}
+	return r.OutputFormat
 }
 
 // SynthesizeStream calls the ElevenLabs streaming TTS endpoint and returns an io.ReadCloser
 // streaming the audio data. The caller must close the reader when done.
-// Audio is returned as PCM 16-bit signed little-endian mono at 16000Hz.
+// Audio is returned in req.OutputFormat, PCM 16-bit signed little-endian mono
+// at 16000Hz by default.
 func (c *Client) SynthesizeStream(ctx context.Context, voiceID string, req SynthesizeRequest) (io.ReadCloser, error) {
 	if voiceID == "" {
 		return nil, fmt.Errorf("elevenlabs: voice_id is required")
@@ -62,32 +103,86 @@ func (c *Client) SynthesizeStream(ctx context.Context, voiceID string, req Synth
 		return nil, fmt.Errorf("elevenlabs: text is required")
 	}
 
-	// Request PCM format (16000Hz, 16-bit mono) for direct playback without transcoding
-	url := fmt.Sprintf("%s/text-to-speech/%s/stream?output_format=pcm_16000", c.baseURL, voiceID)
+	endpoint := "stream"
+	if req.WithTimestamps {
+		endpoint = "stream/with-timestamps"
+	}
+	url := fmt.Sprintf("%s/text-to-speech/%s/%s?output_format=%s", c.baseURL, voiceID, endpoint, req.outputFormat())
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("elevenlabs: marshal request: %w", err)
 	}
 
+	// The body may be read no faster than the audio plays, far longer than
+	// the client timeout, which therefore only bounds the wait for the
+	// response headers.
+	ctx, cancel := context.WithCancel(ctx)
+	timeout := c.httpClient.Timeout
+	var headers *time.Timer
+	if timeout > 0 {
+		headers = time.AfterFunc(timeout, cancel)
+	}
+
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("elevenlabs: create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("xi-api-key", c.apiKey)
 
-	resp, err := c.httpClient.Do(httpReq)
+	streaming := *c.httpClient
+	streaming.Timeout = 0
+	resp, err := streaming.Do(httpReq)
+	if headers != nil && !headers.Stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("elevenlabs: http request: %w", timeoutError{after: timeout})
+	}
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("elevenlabs: http request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer cancel()
 		defer resp.Body.Close()
-		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return nil, fmt.Errorf("elevenlabs: API error (status %d): %s", resp.StatusCode, string(errBody))
+		return nil, newAPIError(resp)
+	}
+
+	stream := &responseStream{ReadCloser: resp.Body, requestID: resp.Header.Get(requestIDHeader), cancel: cancel}
+	if req.WithTimestamps {
+		stream.ReadCloser = newTimestampStream(resp.Body)
 	}
+	return stream, nil
+}
+
+// responseStream is the audio body of a synthesis response.
+type responseStream struct {
+	io.ReadCloser
+	requestID string
+	cancel    context.CancelFunc // ends the request
+}
 
-	return resp.Body, nil
+func (r *responseStream) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+func (r *responseStream) RequestID() string { return r.requestID }
+
+func (r *responseStream) Alignment() []CharacterTiming { return Alignment(r.ReadCloser) }
+
+// RequestID returns the ElevenLabs request ID of an audio stream returned by
+// SynthesizeStream, or "" when the backend does not report one. The ID can
+// be passed in PreviousRequestIDs of a later request.
+func RequestID(stream io.Reader) string {
+	if s, ok := stream.(interface{ RequestID() string }); ok {
+		return s.RequestID()
+	}
+	return ""
 }
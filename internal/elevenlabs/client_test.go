@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSynthesizeStreamSuccess(t *testing.T) {
@@ -61,6 +62,32 @@ func TestSynthesizeStreamOutputFormat(t *testing.T) {
 	rc.Close()
 }
 
+func TestSynthesizeStreamCustomOutputFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("output_format"); got != "ulaw_8000" {
+			t.Errorf("output_format = %q, want %q", got, "ulaw_8000")
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "ulaw_8000") {
+			t.Errorf("output format should not be sent in the body: %s", body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		httpClient: srv.Client(),
+		apiKey:     "test-key",
+		baseURL:    srv.URL,
+	}
+
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello", OutputFormat: "ulaw_8000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc.Close()
+}
+
 func TestSynthesizeStreamRequestBody(t *testing.T) {
 	stability := 0.5
 	similarity := 0.8
@@ -119,6 +146,42 @@ func TestSynthesizeStreamRequestBody(t *testing.T) {
 	rc.Close()
 }
 
+func TestSynthesizeStreamExtendedVoiceSettings(t *testing.T) {
+	style := 0.4
+	boost := false
+	speed := 1.1
+
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		got, _ = payload["voice_settings"].(map[string]interface{})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "test-key", baseURL: srv.URL}
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{
+		Text: "hello",
+		VoiceSettings: &VoiceSettings{
+			Style:           &style,
+			UseSpeakerBoost: &boost,
+			Speed:           &speed,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc.Close()
+
+	if got["style"] != 0.4 || got["use_speaker_boost"] != false || got["speed"] != 1.1 {
+		t.Errorf("voice_settings = %v, want style 0.4, use_speaker_boost false, speed 1.1", got)
+	}
+	if _, ok := got["stability"]; ok {
+		t.Error("unset stability should be omitted")
+	}
+}
+
 func TestSynthesizeStreamAPIError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusTooManyRequests)
@@ -217,3 +280,199 @@ func TestSynthesizeStreamOmitsEmptyLanguageCode(t *testing.T) {
 	}
 	rc.Close()
 }
+
+func TestSynthesizeStreamContextText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		if payload["previous_text"] != "Before." || payload["next_text"] != "After." {
+			t.Errorf("previous_text/next_text = %v/%v, want Before./After.", payload["previous_text"], payload["next_text"])
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		httpClient: srv.Client(),
+		apiKey:     "test-key",
+		baseURL:    srv.URL,
+	}
+
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{
+		Text:         "hello",
+		ModelID:      "m1",
+		PreviousText: "Before.",
+		NextText:     "After.",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc.Close()
+}
+
+func TestMaxTextLength(t *testing.T) {
+	if got := MaxTextLength("eleven_multilingual_v2"); got != 10000 {
+		t.Errorf("MaxTextLength(eleven_multilingual_v2) = %d, want 10000", got)
+	}
+	if got := MaxTextLength("eleven_future_model"); got != DefaultMaxTextLength {
+		t.Errorf("MaxTextLength(unknown) = %d, want %d", got, DefaultMaxTextLength)
+	}
+}
+
+func TestSynthesizeStreamRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal body: %v", err)
+		}
+		ids, _ := payload["previous_request_ids"].([]interface{})
+		if len(ids) != 2 || ids[0] != "r1" || ids[1] != "r2" {
+			t.Errorf("previous_request_ids = %v, want [r1 r2]", payload["previous_request_ids"])
+		}
+		if _, exists := payload["next_request_ids"]; exists {
+			t.Error("next_request_ids should be omitted when empty")
+		}
+		w.Header().Set("request-id", "r3")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		httpClient: srv.Client(),
+		apiKey:     "test-key",
+		baseURL:    srv.URL,
+	}
+
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{
+		Text:               "hello",
+		PreviousRequestIDs: []string{"r1", "r2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	if got := RequestID(rc); got != "r3" {
+		t.Errorf("RequestID = %q, want r3", got)
+	}
+	if got := RequestID(io.NopCloser(strings.NewReader(""))); got != "" {
+		t.Errorf("RequestID of a plain reader = %q, want empty", got)
+	}
+}
+
+func TestSynthesizeStreamWithTimestamps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/text-to-speech/v1/stream/with-timestamps" {
+			t.Errorf("path = %q, want the with-timestamps endpoint", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		// "AQI=" and "AwQ=" are base64 for {1,2} and {3,4}.
+		w.Write([]byte(`{"audio_base64":"AQI=","alignment":{"characters":["H","i"],` +
+			`"character_start_times_seconds":[0,0.1],"character_end_times_seconds":[0.1,0.25]}}` + "\n"))
+		w.Write([]byte(`{"audio_base64":"AwQ=","alignment":null}` + "\n"))
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		httpClient: srv.Client(),
+		apiKey:     "test-key",
+		baseURL:    srv.URL,
+	}
+
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "Hi", WithTimestamps: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	buf := make([]byte, 1)
+	if _, err := rc.Read(buf); err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if got := Alignment(rc); len(got) != 2 {
+		t.Fatalf("alignment after the first read = %v, want the first message's 2 characters", got)
+	}
+	rest, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if got := append(buf, rest...); string(got) != "\x01\x02\x03\x04" {
+		t.Errorf("audio = %v, want [1 2 3 4]", got)
+	}
+
+	want := []CharacterTiming{
+		{Char: "H", Start: 0, End: 100 * time.Millisecond},
+		{Char: "i", Start: 100 * time.Millisecond, End: 250 * time.Millisecond},
+	}
+	got := Alignment(rc)
+	if len(got) != len(want) {
+		t.Fatalf("alignment = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("alignment[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSynthesizeStreamWithTimestampsMalformed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"audio_base64":"not base64!"}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{
+		httpClient: srv.Client(),
+		apiKey:     "test-key",
+		baseURL:    srv.URL,
+	}
+
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "Hi", WithTimestamps: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	if _, err := io.ReadAll(rc); err == nil {
+		t.Error("expected an error for undecodable audio")
+	}
+}
+
+func TestSynthesizeStreamTimeoutCoversHeadersOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text-to-speech/slow/stream" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		w.Write([]byte{0x01, 0x02})
+	}))
+	defer srv.Close()
+
+	httpClient := srv.Client()
+	httpClient.Timeout = 50 * time.Millisecond
+	c := &Client{httpClient: httpClient, apiKey: "test-key", baseURL: srv.URL}
+
+	// A body read long after the timeout still succeeds.
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || len(got) != 2 {
+		t.Errorf("read %d bytes, err %v; want 2 bytes", len(got), err)
+	}
+
+	// Headers arriving late are a retryable timeout.
+	_, err = c.SynthesizeStream(context.Background(), "slow", SynthesizeRequest{Text: "hello"})
+	if err == nil || !IsRetryable(err) {
+		t.Errorf("err = %v, want a retryable timeout", err)
+	}
+}
@@ -0,0 +1,224 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies API failures the caller may want to handle
+// differently.
+type ErrorKind string
+
+const (
+	KindUnknown          ErrorKind = "unknown"
+	KindQuotaExceeded    ErrorKind = "quota_exceeded"
+	KindInvalidAPIKey    ErrorKind = "invalid_api_key"
+	KindVoiceNotFound    ErrorKind = "voice_not_found"
+	KindUnsupportedModel ErrorKind = "unsupported_model"
+	KindTextTooLong      ErrorKind = "text_too_long"
+	KindRateLimited      ErrorKind = "rate_limited"
+)
+
+// upstreamKinds maps the detail.status codes ElevenLabs puts in error bodies
+// onto ErrorKinds.
+var upstreamKinds = map[string]ErrorKind{
+	"quota_exceeded":                  KindQuotaExceeded,
+	"insufficient_credits":            KindQuotaExceeded,
+	"invalid_api_key":                 KindInvalidAPIKey,
+	"missing_api_key":                 KindInvalidAPIKey,
+	"needs_authorization":             KindInvalidAPIKey,
+	"voice_not_found":                 KindVoiceNotFound,
+	"model_not_found":                 KindUnsupportedModel,
+	"invalid_model_id":                KindUnsupportedModel,
+	"model_can_not_do_text_to_speech": KindUnsupportedModel,
+	"unsupported_model":               KindUnsupportedModel,
+	"max_character_limit_exceeded":    KindTextTooLong,
+	"text_too_long":                   KindTextTooLong,
+	"too_many_concurrent_requests":    KindRateLimited,
+	"rate_limit_exceeded":             KindRateLimited,
+	"system_busy":                     KindRateLimited,
+}
+
+// APIError is returned when the ElevenLabs API answers with a non-200 status.
+type APIError struct {
+	StatusCode int
+	Body       string
+
+	// Kind classifies the failure from the error body and status code.
+	Kind ErrorKind
+	// Code is the detail.status value from the error body, if any.
+	Code string
+	// Message is the human-readable detail.message, or Body when the body is
+	// not structured.
+	Message string
+
+	// RetryAfter is the delay requested by a Retry-After header, or zero.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		// Reported in-band on the WebSocket connection.
+		return fmt.Sprintf("elevenlabs: websocket error: %s", e.Body)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("elevenlabs: API error (status %d, %s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("elevenlabs: API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// newAPIError builds an APIError from a failed response, consuming at most
+// 4 KiB of its body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	e.Code, e.Message = parseErrorBody(body)
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(e.Body)
+	}
+	e.Kind = classify(e.StatusCode, e.Code)
+	return e
+}
+
+// parseErrorBody extracts the status code and message from the error bodies
+// ElevenLabs returns:
+//
+//	{"detail": {"status": "voice_not_found", "message": "..."}}
+//	{"detail": "..."}
+//	{"detail": [{"msg": "...", ...}]}   (request validation, HTTP 422)
+func parseErrorBody(body []byte) (code, message string) {
+	var envelope struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Detail) == 0 {
+		return "", ""
+	}
+
+	var detail struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(envelope.Detail, &detail); err == nil {
+		return detail.Status, detail.Message
+	}
+
+	var text string
+	if err := json.Unmarshal(envelope.Detail, &text); err == nil {
+		return "", text
+	}
+
+	var items []struct {
+		Msg string `json:"msg"`
+	}
+	if err := json.Unmarshal(envelope.Detail, &items); err == nil {
+		msgs := make([]string, 0, len(items))
+		for _, item := range items {
+			if item.Msg != "" {
+				msgs = append(msgs, item.Msg)
+			}
+		}
+		return "", strings.Join(msgs, "; ")
+	}
+	return "", ""
+}
+
+// classify derives the ErrorKind from the upstream status code, falling back
+// to the HTTP status when the code is missing or unknown.
+func classify(statusCode int, code string) ErrorKind {
+	if kind, ok := upstreamKinds[code]; ok {
+		return kind
+	}
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return KindInvalidAPIKey
+	case http.StatusTooManyRequests:
+		return KindRateLimited
+	}
+	return KindUnknown
+}
+
+// KindOf returns the ErrorKind of an APIError in err's chain, or KindUnknown.
+func KindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	if errors.Is(err, ErrUnsupportedModel) {
+		return KindUnsupportedModel
+	}
+	return KindUnknown
+}
+
+// parseRetryAfter understands both delta-seconds and HTTP-date values.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// timeoutError reports that ElevenLabs sent no response in time. It is a
+// net.Error, so the request is retried like after other network timeouts.
+type timeoutError struct {
+	after time.Duration
+}
+
+func (e timeoutError) Error() string   { return fmt.Sprintf("no response within %s", e.after) }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limiting, server-side errors and network failures. Cancellation and
+// client errors are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Kind == KindQuotaExceeded {
+			return false
+		}
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
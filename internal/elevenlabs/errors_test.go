@@ -0,0 +1,167 @@
+package elevenlabs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIErrorClassifies(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantKind    ErrorKind
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "quota_exceeded",
+			status:      401,
+			body:        `{"detail":{"status":"quota_exceeded","message":"This request exceeds your quota of 10000."}}`,
+			wantKind:    KindQuotaExceeded,
+			wantCode:    "quota_exceeded",
+			wantMessage: "This request exceeds your quota of 10000.",
+		},
+		{
+			name:     "invalid_api_key",
+			status:   401,
+			body:     `{"detail":{"status":"invalid_api_key","message":"Invalid API key"}}`,
+			wantKind: KindInvalidAPIKey,
+			wantCode: "invalid_api_key",
+		},
+		{
+			name:     "voice_not_found",
+			status:   404,
+			body:     `{"detail":{"status":"voice_not_found","message":"A voice with voice_id x was not found."}}`,
+			wantKind: KindVoiceNotFound,
+			wantCode: "voice_not_found",
+		},
+		{
+			name:     "unsupported_model",
+			status:   400,
+			body:     `{"detail":{"status":"model_not_found","message":"Model not found"}}`,
+			wantKind: KindUnsupportedModel,
+			wantCode: "model_not_found",
+		},
+		{
+			name:     "text_too_long",
+			status:   400,
+			body:     `{"detail":{"status":"max_character_limit_exceeded","message":"Text is too long"}}`,
+			wantKind: KindTextTooLong,
+			wantCode: "max_character_limit_exceeded",
+		},
+		{
+			name:     "rate_limited",
+			status:   429,
+			body:     `{"detail":{"status":"too_many_concurrent_requests","message":"Too many requests"}}`,
+			wantKind: KindRateLimited,
+			wantCode: "too_many_concurrent_requests",
+		},
+		{
+			name:        "plain_401",
+			status:      401,
+			body:        `Unauthorized`,
+			wantKind:    KindInvalidAPIKey,
+			wantMessage: "Unauthorized",
+		},
+		{
+			name:        "string_detail",
+			status:      400,
+			body:        `{"detail":"bad request"}`,
+			wantKind:    KindUnknown,
+			wantMessage: "bad request",
+		},
+		{
+			name:        "validation_detail",
+			status:      422,
+			body:        `{"detail":[{"loc":["body","text"],"msg":"field required","type":"value_error.missing"}]}`,
+			wantKind:    KindUnknown,
+			wantMessage: "field required",
+		},
+		{
+			name:     "server_error",
+			status:   500,
+			body:     `internal`,
+			wantKind: KindUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(tt.status)
+			rec.WriteString(tt.body)
+
+			err := newAPIError(rec.Result())
+			if err.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", err.StatusCode, tt.status)
+			}
+			if err.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", err.Kind, tt.wantKind)
+			}
+			if err.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", err.Code, tt.wantCode)
+			}
+			if tt.wantMessage != "" && err.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", err.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	wrapped := fmt.Errorf("outer: %w", &APIError{StatusCode: 404, Kind: KindVoiceNotFound})
+	if got := KindOf(wrapped); got != KindVoiceNotFound {
+		t.Errorf("KindOf(wrapped) = %q, want %q", got, KindVoiceNotFound)
+	}
+	if got := KindOf(errors.New("plain")); got != KindUnknown {
+		t.Errorf("KindOf(plain) = %q, want %q", got, KindUnknown)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"429", &APIError{StatusCode: http.StatusTooManyRequests, Kind: KindRateLimited}, true},
+		{"503", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"400", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"quota_429", &APIError{StatusCode: http.StatusTooManyRequests, Kind: KindQuotaExceeded}, false},
+		{"circuit_open", ErrCircuitOpen, false},
+		{"plain", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientReturnsTypedError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"detail":{"status":"voice_not_found","message":"voice missing"}}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	_, err := c.SynthesizeStream(t.Context(), "missing", SynthesizeRequest{Text: "hi"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.Kind != KindVoiceNotFound {
+		t.Errorf("Kind = %q, want %q", apiErr.Kind, KindVoiceNotFound)
+	}
+	if !strings.Contains(err.Error(), "voice missing") {
+		t.Errorf("Error() = %q, want to contain the upstream message", err.Error())
+	}
+}
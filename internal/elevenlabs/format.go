@@ -0,0 +1,111 @@
+package elevenlabs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOutputFormat is requested when SynthesizeRequest.OutputFormat is empty.
+const DefaultOutputFormat = "pcm_16000"
+
+// Output codecs understood by ParseOutputFormat.
+const (
+	CodecPCM  = "pcm"
+	CodecULaw = "ulaw"
+	CodecALaw = "alaw"
+	CodecMP3  = "mp3"
+	CodecOpus = "opus"
+)
+
+// OutputFormat describes an ElevenLabs output_format value such as
+// "pcm_16000", "ulaw_8000" or "mp3_44100_128".
+type OutputFormat struct {
+	Name        string
+	Codec       string
+	SampleRate  int
+	BitrateKbps int // compressed codecs only
+}
+
+// ParseOutputFormat validates an ElevenLabs output_format name. An empty name
+// yields DefaultOutputFormat.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultOutputFormat
+	}
+
+	parts := strings.Split(name, "_")
+	f := OutputFormat{Name: name, Codec: parts[0]}
+
+	var wantParts int
+	switch f.Codec {
+	case CodecPCM, CodecULaw, CodecALaw:
+		wantParts = 2
+	case CodecMP3, CodecOpus:
+		wantParts = 3
+	default:
+		return OutputFormat{}, fmt.Errorf("elevenlabs: unsupported output format %q", name)
+	}
+	if len(parts) != wantParts {
+		return OutputFormat{}, fmt.Errorf("elevenlabs: malformed output format %q", name)
+	}
+
+	rate, err := strconv.Atoi(parts[1])
+	if err != nil || rate <= 0 {
+		return OutputFormat{}, fmt.Errorf("elevenlabs: invalid sample rate in output format %q", name)
+	}
+	f.SampleRate = rate
+
+	if wantParts == 3 {
+		kbps, err := strconv.Atoi(parts[2])
+		if err != nil || kbps <= 0 {
+			return OutputFormat{}, fmt.Errorf("elevenlabs: invalid bitrate in output format %q", name)
+		}
+		f.BitrateKbps = kbps
+	}
+	return f, nil
+}
+
+// Encoding returns the audio encoding label attached to chunk metadata.
+func (f OutputFormat) Encoding() string {
+	switch f.Codec {
+	case CodecPCM:
+		return "pcm_s16le"
+	case CodecULaw:
+		return "mulaw"
+	case CodecALaw:
+		return "alaw"
+	default:
+		return f.Codec
+	}
+}
+
+// Compressed reports whether the format is a variable-length codec whose
+// byte count only approximates duration.
+func (f OutputFormat) Compressed() bool {
+	return f.Codec == CodecMP3 || f.Codec == CodecOpus
+}
+
+// BytesPerSecond returns the mono byte rate. For compressed codecs this is the
+// nominal bitrate.
+func (f OutputFormat) BytesPerSecond() int {
+	switch f.Codec {
+	case CodecPCM:
+		return f.SampleRate * 2
+	case CodecULaw, CodecALaw:
+		return f.SampleRate
+	default:
+		return f.BitrateKbps * 1000 / 8
+	}
+}
+
+// Duration returns the playback duration of n bytes of audio in this format.
+func (f OutputFormat) Duration(n int) time.Duration {
+	bps := f.BytesPerSecond()
+	if bps <= 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(time.Second) / int64(bps))
+}
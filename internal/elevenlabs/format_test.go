@@ -0,0 +1,68 @@
+package elevenlabs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantName    string
+		wantCodec   string
+		wantRate    int
+		wantBitrate int
+		wantErr     bool
+	}{
+		{"default", "", "pcm_16000", CodecPCM, 16000, 0, false},
+		{"pcm_24000", "pcm_24000", "pcm_24000", CodecPCM, 24000, 0, false},
+		{"pcm_44100", " PCM_44100 ", "pcm_44100", CodecPCM, 44100, 0, false},
+		{"ulaw", "ulaw_8000", "ulaw_8000", CodecULaw, 8000, 0, false},
+		{"mp3", "mp3_44100_128", "mp3_44100_128", CodecMP3, 44100, 128, false},
+		{"opus", "opus_48000_64", "opus_48000_64", CodecOpus, 48000, 64, false},
+		{"unknown_codec", "wav_16000", "", "", 0, 0, true},
+		{"mp3_missing_bitrate", "mp3_44100", "", "", 0, 0, true},
+		{"pcm_extra_part", "pcm_16000_1", "", "", 0, 0, true},
+		{"bad_rate", "pcm_fast", "", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseOutputFormat(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutputFormat(%q): err=%v, wantErr=%v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if f.Name != tt.wantName || f.Codec != tt.wantCodec || f.SampleRate != tt.wantRate || f.BitrateKbps != tt.wantBitrate {
+				t.Errorf("ParseOutputFormat(%q) = %+v", tt.in, f)
+			}
+		})
+	}
+}
+
+func TestOutputFormatDuration(t *testing.T) {
+	tests := []struct {
+		format string
+		bytes  int
+		want   time.Duration
+	}{
+		{"pcm_16000", 4096, 128 * time.Millisecond},
+		{"pcm_24000", 4800, 100 * time.Millisecond},
+		{"pcm_44100", 88200, time.Second},
+		{"ulaw_8000", 160, 20 * time.Millisecond},
+		{"mp3_44100_128", 16000, time.Second},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseOutputFormat(tt.format)
+		if err != nil {
+			t.Fatalf("ParseOutputFormat(%q): %v", tt.format, err)
+		}
+		if got := f.Duration(tt.bytes); got != tt.want {
+			t.Errorf("%s: Duration(%d) = %v, want %v", tt.format, tt.bytes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package elevenlabs
+
+// DefaultMaxTextLength is the character limit assumed for models missing
+// from modelTextLimits; it matches the most restrictive current model.
+const DefaultMaxTextLength = 5000
+
+// modelTextLimits lists the maximum number of characters per request the
+// API accepts for each model.
+var modelTextLimits = map[string]int{
+	"eleven_v3":              5000,
+	"eleven_multilingual_v2": 10000,
+	"eleven_multilingual_v1": 10000,
+	"eleven_monolingual_v1":  10000,
+	"eleven_flash_v2_5":      40000,
+	"eleven_turbo_v2_5":      40000,
+	"eleven_flash_v2":        30000,
+	"eleven_turbo_v2":        30000,
+}
+
+// MaxTextLength returns the maximum number of characters per request for
+// modelID.
+func MaxTextLength(modelID string) int {
+	if limit, ok := modelTextLimits[modelID]; ok {
+		return limit
+	}
+	return DefaultMaxTextLength
+}
@@ -0,0 +1,106 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// PronunciationDictionaryLocator references an uploaded pronunciation
+// dictionary version in synthesis requests.
+type PronunciationDictionaryLocator struct {
+	PronunciationDictionaryID string `json:"pronunciation_dictionary_id"`
+	VersionID                 string `json:"version_id"`
+}
+
+// FindPronunciationDictionary returns the latest version of the dictionary
+// called name, and false when no such dictionary exists. It pages through
+// all of the account's dictionaries.
+func (c *Client) FindPronunciationDictionary(ctx context.Context, name string) (PronunciationDictionaryLocator, bool, error) {
+	cursor := ""
+	for {
+		var payload struct {
+			Dictionaries []struct {
+				ID              string `json:"id"`
+				LatestVersionID string `json:"latest_version_id"`
+				Name            string `json:"name"`
+			} `json:"pronunciation_dictionaries"`
+			NextCursor string `json:"next_cursor"`
+			HasMore    bool   `json:"has_more"`
+		}
+		path := "/pronunciation-dictionaries?page_size=100"
+		if cursor != "" {
+			path += "&cursor=" + url.QueryEscape(cursor)
+		}
+		if err := c.getJSON(ctx, path, &payload); err != nil {
+			return PronunciationDictionaryLocator{}, false, err
+		}
+		for _, d := range payload.Dictionaries {
+			if d.Name == name && d.ID != "" && d.LatestVersionID != "" {
+				return PronunciationDictionaryLocator{PronunciationDictionaryID: d.ID, VersionID: d.LatestVersionID}, true, nil
+			}
+		}
+		// A repeated cursor would otherwise loop forever.
+		if !payload.HasMore || payload.NextCursor == "" || payload.NextCursor == cursor {
+			return PronunciationDictionaryLocator{}, false, nil
+		}
+		cursor = payload.NextCursor
+	}
+}
+
+// UploadPronunciationDictionary creates a pronunciation dictionary from a PLS
+// document and returns its locator.
+func (c *Client) UploadPronunciationDictionary(ctx context.Context, name string, pls []byte) (PronunciationDictionaryLocator, error) {
+	var loc PronunciationDictionaryLocator
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("name", name); err != nil {
+		return loc, fmt.Errorf("elevenlabs: build upload: %w", err)
+	}
+	part, err := form.CreateFormFile("file", name+".pls")
+	if err != nil {
+		return loc, fmt.Errorf("elevenlabs: build upload: %w", err)
+	}
+	if _, err := part.Write(pls); err != nil {
+		return loc, fmt.Errorf("elevenlabs: build upload: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return loc, fmt.Errorf("elevenlabs: build upload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/pronunciation-dictionaries/add-from-file", &body)
+	if err != nil {
+		return loc, fmt.Errorf("elevenlabs: create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+	httpReq.Header.Set("xi-api-key", c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return loc, fmt.Errorf("elevenlabs: http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return loc, newAPIError(resp)
+	}
+
+	var payload struct {
+		ID        string `json:"id"`
+		VersionID string `json:"version_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return loc, fmt.Errorf("elevenlabs: decode upload response: %w", err)
+	}
+	if payload.ID == "" || payload.VersionID == "" {
+		return loc, fmt.Errorf("elevenlabs: upload response missing dictionary id or version")
+	}
+	loc.PronunciationDictionaryID = payload.ID
+	loc.VersionID = payload.VersionID
+	return loc, nil
+}
@@ -0,0 +1,116 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadPronunciationDictionary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/pronunciation-dictionaries/add-from-file" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.FormValue("name") != "lex" {
+			t.Errorf("name = %q, want %q", r.FormValue("name"), "lex")
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("FormFile: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != "<lexicon/>" {
+			t.Errorf("file = %q", data)
+		}
+		w.Write([]byte(`{"id":"dict-1","version_id":"ver-1","name":"lex"}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	loc, err := c.UploadPronunciationDictionary(context.Background(), "lex", []byte("<lexicon/>"))
+	if err != nil {
+		t.Fatalf("UploadPronunciationDictionary: %v", err)
+	}
+	if loc.PronunciationDictionaryID != "dict-1" || loc.VersionID != "ver-1" {
+		t.Errorf("locator = %+v", loc)
+	}
+}
+
+func TestFindPronunciationDictionary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"pronunciation_dictionaries":[
+			{"id":"d1","latest_version_id":"v1","name":"other"},
+			{"id":"d2","latest_version_id":"v7","name":"wanted"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	loc, found, err := c.FindPronunciationDictionary(context.Background(), "wanted")
+	if err != nil || !found {
+		t.Fatalf("Find = %+v, %v, %v", loc, found, err)
+	}
+	if loc.PronunciationDictionaryID != "d2" || loc.VersionID != "v7" {
+		t.Errorf("locator = %+v", loc)
+	}
+	if _, found, _ := c.FindPronunciationDictionary(context.Background(), "absent"); found {
+		t.Error("absent dictionary reported as found")
+	}
+}
+
+func TestFindPronunciationDictionaryPages(t *testing.T) {
+	var cursors []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		switch cursor {
+		case "":
+			w.Write([]byte(`{"pronunciation_dictionaries":[{"id":"d1","latest_version_id":"v1","name":"other"}],"next_cursor":"page2","has_more":true}`))
+		case "page2":
+			w.Write([]byte(`{"pronunciation_dictionaries":[{"id":"d2","latest_version_id":"v3","name":"wanted"}],"next_cursor":"page3","has_more":true}`))
+		default:
+			w.Write([]byte(`{"pronunciation_dictionaries":[],"has_more":false}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	loc, found, err := c.FindPronunciationDictionary(context.Background(), "wanted")
+	if err != nil || !found || loc.PronunciationDictionaryID != "d2" || loc.VersionID != "v3" {
+		t.Fatalf("Find = %+v, %v, %v; want d2/v3 from the second page", loc, found, err)
+	}
+
+	cursors = nil
+	if _, found, err := c.FindPronunciationDictionary(context.Background(), "absent"); found || err != nil {
+		t.Fatalf("Find absent = %v, %v", found, err)
+	}
+	if len(cursors) != 3 || cursors[2] != "page3" {
+		t.Errorf("cursors = %q, want every page read", cursors)
+	}
+}
+
+func TestSynthesizeStreamSendsDictionaryLocators(t *testing.T) {
+	var payload struct {
+		Locators []PronunciationDictionaryLocator `json:"pronunciation_dictionary_locators"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	c := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	rc, err := c.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{
+		Text:                            "hi",
+		PronunciationDictionaryLocators: []PronunciationDictionaryLocator{{PronunciationDictionaryID: "d", VersionID: "v"}},
+	})
+	if err != nil {
+		t.Fatalf("SynthesizeStream: %v", err)
+	}
+	rc.Close()
+	if len(payload.Locators) != 1 || payload.Locators[0].PronunciationDictionaryID != "d" {
+		t.Errorf("locators = %+v", payload.Locators)
+	}
+}
@@ -0,0 +1,322 @@
+package elevenlabs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the API while the circuit
+// breaker is open after repeated upstream failures.
+var ErrCircuitOpen = errors.New("elevenlabs: circuit breaker open, upstream temporarily unavailable")
+
+// RetryPolicy controls how RetryingSynthesizer retries transient failures.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not
+	// waited out; the error is returned instead.
+	MaxDelay time.Duration
+}
+
+// CircuitBreakerPolicy controls when RetryingSynthesizer stops calling the API.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive transient failures that
+	// opens the circuit. Zero disables the breaker.
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before a trial request.
+	Cooldown time.Duration
+}
+
+// RetryingSynthesizer wraps a Synthesizer with jittered exponential backoff,
+// Retry-After handling and a circuit breaker. Retries only happen before the
+// first audio byte is returned to the caller: failures while opening the
+// stream, or a read error before any audio arrived. Once audio has been
+// delivered, errors are passed through unchanged.
+type RetryingSynthesizer struct {
+	next    Synthesizer
+	policy  RetryPolicy
+	breaker *circuitBreaker
+	log     *slog.Logger
+
+	// Overridable in tests.
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+// NewRetryingSynthesizer wraps next with the given retry and breaker policies.
+func NewRetryingSynthesizer(next Synthesizer, policy RetryPolicy, breaker CircuitBreakerPolicy, logger *slog.Logger) *RetryingSynthesizer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RetryingSynthesizer{
+		next:    next,
+		policy:  policy,
+		breaker: newCircuitBreaker(breaker, time.Now),
+		log:     logger.With("component", "elevenlabs_retry"),
+		sleep:   sleepContext,
+		jitter:  rand.Float64,
+	}
+}
+
+// SynthesizeStream opens the upstream stream, retrying transient failures.
+func (r *RetryingSynthesizer) SynthesizeStream(ctx context.Context, voiceID string, req SynthesizeRequest) (io.ReadCloser, error) {
+	rc, attempt, err := r.open(ctx, voiceID, req, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &retryReader{
+		r:       r,
+		ctx:     ctx,
+		voiceID: voiceID,
+		req:     req,
+		current: rc,
+		attempt: attempt,
+	}, nil
+}
+
+// OpenStream opens an input stream on the wrapped synthesizer, retrying
+// transient failures to connect. Errors on the open stream are returned as
+// they are: the text already sent cannot be replayed.
+func (r *RetryingSynthesizer) OpenStream(ctx context.Context, voiceID string, req SynthesizeRequest) (TextStream, error) {
+	next, ok := r.next.(StreamingSynthesizer)
+	if !ok {
+		return nil, ErrInputStreamsUnsupported
+	}
+	var stream TextStream
+	_, err := r.retry(ctx, 1, func() (err error) {
+		stream, err = next.OpenStream(ctx, voiceID, req)
+		return err
+	})
+	return stream, err
+}
+
+// open calls the wrapped synthesizer starting at the given attempt number and
+// returns the stream together with the attempt that produced it.
+func (r *RetryingSynthesizer) open(ctx context.Context, voiceID string, req SynthesizeRequest, attempt int) (io.ReadCloser, int, error) {
+	var rc io.ReadCloser
+	attempt, err := r.retry(ctx, attempt, func() (err error) {
+		rc, err = r.next.SynthesizeStream(ctx, voiceID, req)
+		return err
+	})
+	return rc, attempt, err
+}
+
+// retry calls try, starting at the given attempt number, until it succeeds or
+// fails for good, and returns the number of the last attempt.
+func (r *RetryingSynthesizer) retry(ctx context.Context, attempt int, try func() error) (int, error) {
+	for {
+		if !r.breaker.allow() {
+			return attempt, ErrCircuitOpen
+		}
+
+		err := try()
+		if err == nil {
+			r.breaker.success()
+			return attempt, nil
+		}
+
+		if !IsRetryable(err) {
+			// A definitive API answer proves the upstream is reachable.
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				r.breaker.success()
+			} else {
+				r.breaker.release()
+			}
+			return attempt, err
+		}
+		r.breaker.failure()
+
+		if err := r.backoff(ctx, attempt, err); err != nil {
+			return attempt, err
+		}
+		attempt++
+	}
+}
+
+// backoff waits before the next attempt, or returns the error to give up with.
+func (r *RetryingSynthesizer) backoff(ctx context.Context, attempt int, cause error) error {
+	if attempt >= r.policy.MaxAttempts {
+		return cause
+	}
+
+	delay := r.delay(attempt)
+	var apiErr *APIError
+	if errors.As(cause, &apiErr) && apiErr.RetryAfter > 0 {
+		if r.policy.MaxDelay > 0 && apiErr.RetryAfter > r.policy.MaxDelay {
+			return cause
+		}
+		delay = apiErr.RetryAfter
+	}
+
+	r.log.Warn("transient upstream failure, retrying",
+		"attempt", attempt,
+		"max_attempts", r.policy.MaxAttempts,
+		"delay_ms", delay.Milliseconds(),
+		"error", cause,
+	)
+	if err := r.sleep(ctx, delay); err != nil {
+		return fmt.Errorf("elevenlabs: retry aborted: %w", err)
+	}
+	return nil
+}
+
+// delay returns the jittered exponential backoff for the given attempt: a
+// random value between half and all of BaseDelay * 2^(attempt-1), capped at
+// MaxDelay.
+func (r *RetryingSynthesizer) delay(attempt int) time.Duration {
+	d := r.policy.BaseDelay
+	for i := 1; i < attempt && (r.policy.MaxDelay <= 0 || d < r.policy.MaxDelay); i++ {
+		d *= 2
+	}
+	if r.policy.MaxDelay > 0 && d > r.policy.MaxDelay {
+		d = r.policy.MaxDelay
+	}
+	return d/2 + time.Duration(r.jitter()*float64(d/2))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryReader reopens the upstream stream when it fails before delivering
+// its first byte.
+type retryReader struct {
+	r       *RetryingSynthesizer
+	ctx     context.Context
+	voiceID string
+	req     SynthesizeRequest
+
+	current   io.ReadCloser
+	attempt   int
+	delivered bool
+}
+
+func (rr *retryReader) Read(p []byte) (int, error) {
+	for {
+		n, err := rr.current.Read(p)
+		if n > 0 {
+			rr.delivered = true
+		}
+		if err == nil || err == io.EOF || rr.delivered || !IsRetryable(err) {
+			return n, err
+		}
+
+		// Nothing reached the caller yet, so the stream can be replaced.
+		rr.current.Close()
+		rr.r.breaker.failure()
+		if berr := rr.r.backoff(rr.ctx, rr.attempt, err); berr != nil {
+			return 0, berr
+		}
+		rc, attempt, oerr := rr.r.open(rr.ctx, rr.voiceID, rr.req, rr.attempt+1)
+		if oerr != nil {
+			rr.current = io.NopCloser(errReader{oerr})
+			return 0, oerr
+		}
+		rr.current = rc
+		rr.attempt = attempt
+	}
+}
+
+func (rr *retryReader) Close() error {
+	return rr.current.Close()
+}
+
+// RequestID reports the request ID of the attempt currently being read.
+func (rr *retryReader) RequestID() string {
+	return RequestID(rr.current)
+}
+
+// Alignment reports the character timings of the attempt currently being
+// read.
+func (rr *retryReader) Alignment() []CharacterTiming {
+	return Alignment(rr.current)
+}
+
+// errReader always fails with err.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+// circuitBreaker opens after FailureThreshold consecutive failures and lets
+// a single trial request through once Cooldown has elapsed.
+type circuitBreaker struct {
+	policy CircuitBreakerPolicy
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	trial    bool // a half-open trial request is in flight
+}
+
+func newCircuitBreaker(policy CircuitBreakerPolicy, now func() time.Time) *circuitBreaker {
+	return &circuitBreaker{policy: policy, now: now}
+}
+
+// allow reports whether a request may be sent.
+func (b *circuitBreaker) allow() bool {
+	if b.policy.FailureThreshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.policy.Cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// success closes the circuit.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.open = false
+	b.trial = false
+}
+
+// failure records a transient failure, opening the circuit when the threshold
+// is reached or a half-open trial fails.
+func (b *circuitBreaker) failure() {
+	if b.policy.FailureThreshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.trial || b.failures >= b.policy.FailureThreshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+	b.trial = false
+}
+
+// release ends a half-open trial that failed for a non-transient reason
+// without changing the circuit state.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
@@ -0,0 +1,350 @@
+package elevenlabs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// scriptedServer answers successive requests with the scripted status codes,
+// then with 200 and a fixed audio body once the script is exhausted.
+type scriptedServer struct {
+	mu       sync.Mutex
+	statuses []int
+	headers  map[int]http.Header // extra headers keyed by request index
+	requests int
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	idx := s.requests
+	s.requests++
+	s.mu.Unlock()
+
+	for k, vs := range s.headers[idx] {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	if idx < len(s.statuses) {
+		w.WriteHeader(s.statuses[idx])
+		w.Write([]byte(`{"detail":{"status":"scripted","message":"scripted failure"}}`))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("audio"))
+}
+
+func (s *scriptedServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+type recordedSleeps struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (r *recordedSleeps) sleep(_ context.Context, d time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delays = append(r.delays, d)
+	return nil
+}
+
+func newRetryTestSynthesizer(t *testing.T, script *scriptedServer, policy RetryPolicy, breaker CircuitBreakerPolicy) (*RetryingSynthesizer, *recordedSleeps) {
+	t.Helper()
+	srv := httptest.NewServer(script)
+	t.Cleanup(srv.Close)
+
+	client := &Client{httpClient: srv.Client(), apiKey: "k", baseURL: srv.URL}
+	r := NewRetryingSynthesizer(client, policy, breaker, nil)
+	sleeps := &recordedSleeps{}
+	r.sleep = sleeps.sleep
+	r.jitter = func() float64 { return 1 }
+	return r, sleeps
+}
+
+func TestRetrySucceedsAfterTransientFailures(t *testing.T) {
+	script := &scriptedServer{statuses: []int{503, 502}}
+	r, sleeps := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, CircuitBreakerPolicy{})
+
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil || string(data) != "audio" {
+		t.Fatalf("ReadAll = %q, %v; want audio", data, err)
+	}
+	if script.count() != 3 {
+		t.Errorf("requests = %d, want 3", script.count())
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(sleeps.delays) != len(want) || sleeps.delays[0] != want[0] || sleeps.delays[1] != want[1] {
+		t.Errorf("backoff delays = %v, want %v", sleeps.delays, want)
+	}
+}
+
+func TestRetryReportsRequestID(t *testing.T) {
+	script := &scriptedServer{
+		statuses: []int{503},
+		headers:  map[int]http.Header{0: {"Request-Id": {"failed"}}, 1: {"Request-Id": {"r2"}}},
+	}
+	r, _ := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{})
+
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	if got := RequestID(rc); got != "r2" {
+		t.Errorf("RequestID = %q, want the successful attempt's r2", got)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	script := &scriptedServer{statuses: []int{500, 500, 500, 500}}
+	r, _ := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{})
+
+	_, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Fatalf("error = %v, want APIError with status 500", err)
+	}
+	if script.count() != 3 {
+		t.Errorf("requests = %d, want 3", script.count())
+	}
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	script := &scriptedServer{statuses: []int{401}}
+	r, sleeps := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{})
+
+	if _, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"}); err == nil {
+		t.Fatal("expected error for 401")
+	}
+	if script.count() != 1 {
+		t.Errorf("requests = %d, want 1", script.count())
+	}
+	if len(sleeps.delays) != 0 {
+		t.Errorf("unexpected backoff %v", sleeps.delays)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	script := &scriptedServer{
+		statuses: []int{429},
+		headers:  map[int]http.Header{0: {"Retry-After": []string{"2"}}},
+	}
+	r, sleeps := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 2, BaseDelay: 10 * time.Millisecond, MaxDelay: 5 * time.Second}, CircuitBreakerPolicy{})
+
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc.Close()
+
+	if len(sleeps.delays) != 1 || sleeps.delays[0] != 2*time.Second {
+		t.Errorf("delays = %v, want [2s] from Retry-After", sleeps.delays)
+	}
+}
+
+func TestRetryAfterBeyondMaxDelayGivesUp(t *testing.T) {
+	script := &scriptedServer{
+		statuses: []int{429},
+		headers:  map[int]http.Header{0: {"Retry-After": []string{"120"}}},
+	}
+	r, sleeps := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 5 * time.Second}, CircuitBreakerPolicy{})
+
+	_, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 120*time.Second {
+		t.Fatalf("error = %v, want APIError with RetryAfter=120s", err)
+	}
+	if len(sleeps.delays) != 0 || script.count() != 1 {
+		t.Errorf("should not wait out a Retry-After beyond MaxDelay: delays=%v requests=%d", sleeps.delays, script.count())
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	script := &scriptedServer{statuses: []int{503, 503}}
+	r, _ := newRetryTestSynthesizer(t, script, RetryPolicy{MaxAttempts: 1}, CircuitBreakerPolicy{FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Unix(1000, 0)
+	r.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"}); err == nil {
+			t.Fatalf("call %d: expected 503 error", i)
+		}
+	}
+
+	if _, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if script.count() != 2 {
+		t.Errorf("requests = %d, want 2 (open circuit must not call upstream)", script.count())
+	}
+
+	// After the cooldown a trial request goes through and closes the circuit.
+	now = now.Add(time.Minute)
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("trial request: %v", err)
+	}
+	rc.Close()
+	rc, err = r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("after recovery: %v", err)
+	}
+	rc.Close()
+}
+
+// flakySynthesizer returns streams whose first Read fails for the first
+// `failures` calls.
+type flakySynthesizer struct {
+	failures int
+	calls    int
+	midway   bool // fail after delivering some audio instead of before
+}
+
+type failingReader struct {
+	prefix []byte
+	err    error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.prefix) > 0 {
+		n := copy(p, f.prefix)
+		f.prefix = f.prefix[n:]
+		return n, nil
+	}
+	return 0, f.err
+}
+
+func (f *flakySynthesizer) SynthesizeStream(context.Context, string, SynthesizeRequest) (io.ReadCloser, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		r := &failingReader{err: io.ErrUnexpectedEOF}
+		if f.midway {
+			r.prefix = []byte("partial")
+		}
+		return io.NopCloser(r), nil
+	}
+	return io.NopCloser(strings.NewReader("audio")), nil
+}
+
+func TestRetryReopensStreamBeforeFirstByte(t *testing.T) {
+	flaky := &flakySynthesizer{failures: 1}
+	r := NewRetryingSynthesizer(flaky, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{}, nil)
+	r.sleep = (&recordedSleeps{}).sleep
+
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil || string(data) != "audio" {
+		t.Fatalf("ReadAll = %q, %v; want audio", data, err)
+	}
+	if flaky.calls != 2 {
+		t.Errorf("calls = %d, want 2", flaky.calls)
+	}
+}
+
+func TestRetryDoesNotReopenAfterFirstByte(t *testing.T) {
+	flaky := &flakySynthesizer{failures: 1, midway: true}
+	r := NewRetryingSynthesizer(flaky, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{}, nil)
+	r.sleep = (&recordedSleeps{}).sleep
+
+	rc, err := r.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("error = %v, want io.ErrUnexpectedEOF passed through", err)
+	}
+	if string(data) != "partial" {
+		t.Errorf("data = %q, want %q", data, "partial")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("calls = %d, want 1", flaky.calls)
+	}
+}
+
+func TestRetryOpensInputStream(t *testing.T) {
+	// The first handshake is refused with a transient error, the second is
+	// answered by the stand-in.
+	standIn := &wsStandIn{}
+	script := &scriptedServer{statuses: []int{http.StatusServiceUnavailable}}
+	ws := websocket.Handler(standIn.handler)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if script.count() < len(script.statuses) {
+			script.ServeHTTP(w, req)
+			return
+		}
+		ws.ServeHTTP(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	next := &WebSocketSynthesizer{apiKey: "k", baseURL: "ws" + strings.TrimPrefix(srv.URL, "http"), origin: srv.URL}
+	r := NewRetryingSynthesizer(next, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, CircuitBreakerPolicy{}, nil)
+	sleeps := &recordedSleeps{}
+	r.sleep = sleeps.sleep
+
+	stream, err := r.OpenStream(context.Background(), "v1", SynthesizeRequest{})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	defer stream.Close()
+	if len(sleeps.delays) != 1 {
+		t.Errorf("retries = %d, want 1", len(sleeps.delays))
+	}
+	stream.SendText("hi ")
+	stream.CloseInput()
+	if data, err := io.ReadAll(stream); err != nil || string(data) != "hi " {
+		t.Errorf("ReadAll = %q, %v; want the audio of the text sent", data, err)
+	}
+}
+
+func TestRetryOpenStreamUnsupported(t *testing.T) {
+	r := NewRetryingSynthesizer(&flakySynthesizer{}, RetryPolicy{MaxAttempts: 3}, CircuitBreakerPolicy{}, nil)
+	if _, err := r.OpenStream(context.Background(), "v1", SynthesizeRequest{}); !errors.Is(err, ErrInputStreamsUnsupported) {
+		t.Errorf("OpenStream error = %v, want ErrInputStreamsUnsupported", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second},
+		{"garbage", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
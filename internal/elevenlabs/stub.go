@@ -24,8 +24,9 @@ func NewStubSynthesizer(logger *slog.Logger) *StubSynthesizer {
 	return &StubSynthesizer{log: logger}
 }
 
-// SynthesizeStream returns an io.ReadCloser streaming deterministic silent PCM.
-// The output size is len(text) * 320 bytes (320 bytes ≈ 10 ms at 16 kHz mono PCM16).
+// SynthesizeStream returns an io.ReadCloser streaming deterministic silent audio.
+// The output covers 10 ms per input character at the requested output format's
+// byte rate: len(text) * 320 bytes for the default 16 kHz mono PCM16.
 func (s *StubSynthesizer) SynthesizeStream(_ context.Context, voiceID string, req SynthesizeRequest) (io.ReadCloser, error) {
 	if voiceID == "" {
 		return nil, fmt.Errorf("elevenlabs: voice_id is required")
@@ -34,12 +35,28 @@ func (s *StubSynthesizer) SynthesizeStream(_ context.Context, voiceID string, re
 		return nil, fmt.Errorf("elevenlabs: text is required")
 	}
 
-	pcmLen := len(req.Text) * 320
+	format, err := ParseOutputFormat(req.OutputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	pcmLen := len(req.Text) * format.BytesPerSecond() / 100
 	pcm := make([]byte, pcmLen)
+	if format.Codec == CodecULaw || format.Codec == CodecALaw {
+		// Zero is not silence in companded encodings.
+		silence := byte(0xFF)
+		if format.Codec == CodecALaw {
+			silence = 0xD5
+		}
+		for i := range pcm {
+			pcm[i] = silence
+		}
+	}
 
 	s.log.Info("stub synthesis",
 		"text_length", len(req.Text),
 		"voice_id", voiceID,
+		"output_format", format.Name,
 		"bytes", pcmLen,
 	)
 
@@ -120,3 +120,31 @@ func TestStubSynthesizeStreamLongText(t *testing.T) {
 		t.Errorf("longer text should produce more bytes: short=%d, long=%d", len(dataShort), len(dataLong))
 	}
 }
+
+func TestStubSynthesizeStreamOutputFormat(t *testing.T) {
+	stub := NewStubSynthesizer(slog.Default())
+	rc, err := stub.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hi", OutputFormat: "ulaw_8000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll error: %v", err)
+	}
+	// 10 ms per character at 8000 bytes/s.
+	if len(data) != 160 {
+		t.Fatalf("got %d bytes, want 160", len(data))
+	}
+	if data[0] != 0xFF {
+		t.Errorf("μ-law silence byte = %#x, want 0xff", data[0])
+	}
+}
+
+func TestStubSynthesizeStreamInvalidOutputFormat(t *testing.T) {
+	stub := NewStubSynthesizer(slog.Default())
+	if _, err := stub.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hi", OutputFormat: "wav"}); err == nil {
+		t.Fatal("expected error for invalid output format")
+	}
+}
@@ -2,6 +2,7 @@ package elevenlabs
 
 import (
 	"context"
+	"errors"
 	"io"
 )
 
@@ -10,3 +11,24 @@ import (
 type Synthesizer interface {
 	SynthesizeStream(ctx context.Context, voiceID string, req SynthesizeRequest) (io.ReadCloser, error)
 }
+
+// StreamingSynthesizer is implemented by backends that accept text
+// incrementally, so synthesis can start before the full utterance is known.
+type StreamingSynthesizer interface {
+	Synthesizer
+	OpenStream(ctx context.Context, voiceID string, req SynthesizeRequest) (TextStream, error)
+}
+
+// TextStream is an open incremental synthesis: text is pushed while the
+// audio generated from it is read. InputStream is the WebSocket
+// implementation.
+type TextStream interface {
+	io.ReadCloser
+	SendText(text string) error
+	Flush() error
+	CloseInput() error
+}
+
+// ErrInputStreamsUnsupported is returned by OpenStream when the wrapped
+// backend takes the whole text in one request.
+var ErrInputStreamsUnsupported = errors.New("elevenlabs: backend does not accept incremental input")
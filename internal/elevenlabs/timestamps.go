@@ -0,0 +1,98 @@
+package elevenlabs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CharacterTiming is when one character of the request text is spoken,
+// relative to the start of the generated audio.
+type CharacterTiming struct {
+	Char       string
+	Start, End time.Duration
+}
+
+// Alignment returns the character timings received so far on a stream
+// returned by SynthesizeStream for a request with WithTimestamps set. Timings
+// arrive together with the audio they describe, so after a Read they cover
+// at least the audio read. Streams without timings return nil.
+func Alignment(stream io.Reader) []CharacterTiming {
+	if s, ok := stream.(interface{ Alignment() []CharacterTiming }); ok {
+		return s.Alignment()
+	}
+	return nil
+}
+
+// timestampMessage is one object of the with-timestamps response stream.
+type timestampMessage struct {
+	AudioBase64 string              `json:"audio_base64"`
+	Alignment   *timestampAlignment `json:"alignment"`
+}
+
+type timestampAlignment struct {
+	Characters []string  `json:"characters"`
+	StartTimes []float64 `json:"character_start_times_seconds"`
+	EndTimes   []float64 `json:"character_end_times_seconds"`
+}
+
+// timestampStream decodes the with-timestamps endpoint's sequence of JSON
+// objects, each carrying base64 audio and the timings of its characters, into
+// plain audio bytes.
+type timestampStream struct {
+	body    io.ReadCloser
+	dec     *json.Decoder
+	pending []byte
+	timings []CharacterTiming
+}
+
+func newTimestampStream(body io.ReadCloser) *timestampStream {
+	return &timestampStream{body: body, dec: json.NewDecoder(body)}
+}
+
+func (s *timestampStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		var msg timestampMessage
+		if err := s.dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, io.EOF
+			}
+			return 0, fmt.Errorf("elevenlabs: decode timestamps: %w", err)
+		}
+		audio, err := base64.StdEncoding.DecodeString(msg.AudioBase64)
+		if err != nil {
+			return 0, fmt.Errorf("elevenlabs: decode audio: %w", err)
+		}
+		if a := msg.Alignment; a != nil {
+			for i, char := range a.Characters {
+				if i >= len(a.StartTimes) || i >= len(a.EndTimes) {
+					break
+				}
+				s.timings = append(s.timings, CharacterTiming{
+					Char:  char,
+					Start: seconds(a.StartTimes[i]),
+					End:   seconds(a.EndTimes[i]),
+				})
+			}
+		}
+		s.pending = audio
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *timestampStream) Close() error {
+	return s.body.Close()
+}
+
+func (s *timestampStream) Alignment() []CharacterTiming {
+	return s.timings
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
@@ -0,0 +1,125 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// ErrUnsupportedModel is returned by CheckVoice when the model does not exist
+// or cannot do text-to-speech.
+var ErrUnsupportedModel = errors.New("elevenlabs: model does not support text-to-speech")
+
+// Voice describes a voice from the ElevenLabs voice library.
+type Voice struct {
+	VoiceID  string            `json:"voice_id"`
+	Name     string            `json:"name"`
+	Category string            `json:"category"`
+	Labels   map[string]string `json:"labels"`
+
+	// HighQualityBaseModelIDs lists the models a fine-tuned voice was trained
+	// for. Empty for voices that work equally well with every model.
+	HighQualityBaseModelIDs []string `json:"high_quality_base_model_ids"`
+}
+
+// SupportsModel reports whether the voice is tuned for modelID.
+func (v *Voice) SupportsModel(modelID string) bool {
+	return len(v.HighQualityBaseModelIDs) == 0 || slices.Contains(v.HighQualityBaseModelIDs, modelID)
+}
+
+// Model describes an ElevenLabs model.
+type Model struct {
+	ModelID              string `json:"model_id"`
+	Name                 string `json:"name"`
+	CanDoTextToSpeech    bool   `json:"can_do_text_to_speech"`
+	MaxCharactersRequest int    `json:"maximum_text_length_per_request"`
+}
+
+// ListVoices returns the voices available to the API key.
+func (c *Client) ListVoices(ctx context.Context) ([]Voice, error) {
+	var payload struct {
+		Voices []Voice `json:"voices"`
+	}
+	if err := c.getJSON(ctx, "/voices", &payload); err != nil {
+		return nil, err
+	}
+	return payload.Voices, nil
+}
+
+// GetVoice returns a single voice. A missing voice yields an APIError of
+// kind KindVoiceNotFound.
+func (c *Client) GetVoice(ctx context.Context, voiceID string) (*Voice, error) {
+	if voiceID == "" {
+		return nil, fmt.Errorf("elevenlabs: voice_id is required")
+	}
+	var voice Voice
+	if err := c.getJSON(ctx, "/voices/"+url.PathEscape(voiceID), &voice); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && apiErr.Kind == KindUnknown {
+			apiErr.Kind = KindVoiceNotFound
+		}
+		return nil, err
+	}
+	return &voice, nil
+}
+
+// ListModels returns the models available to the API key.
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	if err := c.getJSON(ctx, "/models", &models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// CheckVoice verifies that voiceID exists and that modelID can synthesize
+// speech. The returned voice can be inspected further, e.g. with
+// SupportsModel.
+func (c *Client) CheckVoice(ctx context.Context, voiceID, modelID string) (*Voice, error) {
+	voice, err := c.GetVoice(ctx, voiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return voice, err
+	}
+	i := slices.IndexFunc(models, func(m Model) bool { return m.ModelID == modelID })
+	if i < 0 {
+		return voice, fmt.Errorf("%w: %q is not available", ErrUnsupportedModel, modelID)
+	}
+	if !models[i].CanDoTextToSpeech {
+		return voice, fmt.Errorf("%w: %q", ErrUnsupportedModel, modelID)
+	}
+	return voice, nil
+}
+
+// getJSON issues an authenticated GET for path and decodes the JSON response
+// into out.
+func (c *Client) getJSON(ctx context.Context, path string, out any) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("elevenlabs: create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("xi-api-key", c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("elevenlabs: http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("elevenlabs: decode %s: %w", path, err)
+	}
+	return nil
+}
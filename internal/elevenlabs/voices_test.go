@@ -0,0 +1,116 @@
+package elevenlabs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// catalogServer serves a fixed voice library and model list.
+func catalogServer(t *testing.T) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /voices", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("xi-api-key") != "test-key" {
+			t.Errorf("xi-api-key = %q, want %q", r.Header.Get("xi-api-key"), "test-key")
+		}
+		w.Write([]byte(`{"voices":[
+			{"voice_id":"v1","name":"Mark","category":"premade","labels":{"accent":"american"}},
+			{"voice_id":"v2","name":"Clone","category":"professional","high_quality_base_model_ids":["eleven_multilingual_v2"]}
+		]}`))
+	})
+	mux.HandleFunc("GET /voices/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"voice_id":"v1","name":"Mark","category":"premade"}`))
+	})
+	mux.HandleFunc("GET /voices/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"detail":"Not Found"}`))
+	})
+	mux.HandleFunc("GET /models", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"model_id":"eleven_turbo_v2_5","can_do_text_to_speech":true},
+			{"model_id":"eleven_english_sts_v2","can_do_text_to_speech":false}
+		]`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &Client{httpClient: srv.Client(), apiKey: "test-key", baseURL: srv.URL}
+}
+
+func TestListVoices(t *testing.T) {
+	c := catalogServer(t)
+
+	voices, err := c.ListVoices(context.Background())
+	if err != nil {
+		t.Fatalf("ListVoices: %v", err)
+	}
+	if len(voices) != 2 {
+		t.Fatalf("len(voices) = %d, want 2", len(voices))
+	}
+	if voices[0].Name != "Mark" || voices[0].Labels["accent"] != "american" {
+		t.Errorf("voices[0] = %+v", voices[0])
+	}
+	if !voices[0].SupportsModel("eleven_turbo_v2_5") {
+		t.Error("premade voice should support every model")
+	}
+	if voices[1].SupportsModel("eleven_turbo_v2_5") || !voices[1].SupportsModel("eleven_multilingual_v2") {
+		t.Error("fine-tuned voice should only support its base models")
+	}
+}
+
+func TestGetVoiceNotFound(t *testing.T) {
+	c := catalogServer(t)
+
+	_, err := c.GetVoice(context.Background(), "missing")
+	if KindOf(err) != KindVoiceNotFound {
+		t.Fatalf("KindOf(%v) = %q, want %q", err, KindOf(err), KindVoiceNotFound)
+	}
+}
+
+func TestCheckVoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		voiceID  string
+		model    string
+		wantKind ErrorKind
+		wantErr  bool
+	}{
+		{"ok", "v1", "eleven_turbo_v2_5", KindUnknown, false},
+		{"voice_missing", "missing", "eleven_turbo_v2_5", KindVoiceNotFound, true},
+		{"model_missing", "v1", "eleven_v9", KindUnsupportedModel, true},
+		{"model_not_tts", "v1", "eleven_english_sts_v2", KindUnsupportedModel, true},
+	}
+
+	c := catalogServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.CheckVoice(context.Background(), tt.voiceID, tt.model)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if KindOf(err) != tt.wantKind {
+				t.Errorf("KindOf = %q, want %q", KindOf(err), tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestCheckVoiceUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := &Client{httpClient: http.DefaultClient, apiKey: "k", baseURL: srv.URL}
+
+	_, err := c.CheckVoice(context.Background(), "v1", "m")
+	if err == nil {
+		t.Fatal("expected error for unreachable catalog")
+	}
+	if KindOf(err) != KindUnknown {
+		t.Errorf("KindOf = %q, want unknown so startup only warns", KindOf(err))
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		t.Errorf("unexpected APIError %v for network failure", apiErr)
+	}
+}
@@ -0,0 +1,367 @@
+package elevenlabs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// WebSocketBaseURL is the ElevenLabs WebSocket API base URL.
+	WebSocketBaseURL = "wss://api.elevenlabs.io/v1"
+
+	// websocketOrigin is sent in the handshake; the API does not check it, but
+	// the WebSocket protocol requires one.
+	websocketOrigin = "https://api.elevenlabs.io"
+)
+
+// WebSocketSynthesizer drives the ElevenLabs stream-input WebSocket endpoint.
+// Unlike Client, which sends the whole text in a single POST, it lets callers
+// push text fragments as they become available and starts returning audio
+// before the full utterance is known.
+type WebSocketSynthesizer struct {
+	apiKey  string
+	baseURL string
+	origin  string
+}
+
+// NewWebSocketSynthesizer constructs a WebSocket synthesizer with the provided API key.
+func NewWebSocketSynthesizer(apiKey string) *WebSocketSynthesizer {
+	return &WebSocketSynthesizer{
+		apiKey:  apiKey,
+		baseURL: WebSocketBaseURL,
+		origin:  websocketOrigin,
+	}
+}
+
+// wsTextMessage is a client->server message on the stream-input socket.
+type wsTextMessage struct {
+	Text                 string         `json:"text"`
+	VoiceSettings        *VoiceSettings `json:"voice_settings,omitempty"`
+	TryTriggerGeneration bool           `json:"try_trigger_generation,omitempty"`
+	Flush                bool           `json:"flush,omitempty"`
+
+	PronunciationDictionaryLocators []PronunciationDictionaryLocator `json:"pronunciation_dictionary_locators,omitempty"`
+}
+
+// wsAudioMessage is a server->client message on the stream-input socket.
+type wsAudioMessage struct {
+	Audio   string `json:"audio"`
+	IsFinal *bool  `json:"isFinal"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// SynthesizeStream implements Synthesizer by opening an input stream, sending
+// the whole text and closing the input. Audio is returned in req.OutputFormat.
+func (w *WebSocketSynthesizer) SynthesizeStream(ctx context.Context, voiceID string, req SynthesizeRequest) (io.ReadCloser, error) {
+	if req.Text == "" {
+		return nil, fmt.Errorf("elevenlabs: text is required")
+	}
+
+	stream, err := w.OpenStream(ctx, voiceID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream-input protocol expects text to end with a single space.
+	text := req.Text
+	if !strings.HasSuffix(text, " ") {
+		text += " "
+	}
+	if err := stream.SendText(text); err != nil {
+		stream.Close()
+		return nil, err
+	}
+	if err := stream.CloseInput(); err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// OpenStream dials the stream-input endpoint and sends the initial message
+// carrying voice settings. req.Text is ignored; text is pushed afterwards with
+// InputStream.SendText. The caller must close the returned stream when done.
+func (w *WebSocketSynthesizer) OpenStream(ctx context.Context, voiceID string, req SynthesizeRequest) (TextStream, error) {
+	if voiceID == "" {
+		return nil, fmt.Errorf("elevenlabs: voice_id is required")
+	}
+
+	query := url.Values{}
+	query.Set("output_format", req.outputFormat())
+	if req.ModelID != "" {
+		query.Set("model_id", req.ModelID)
+	}
+	if req.LanguageCode != "" {
+		query.Set("language_code", req.LanguageCode)
+	}
+	if req.OptimizeStreamingLatency != nil {
+		query.Set("optimize_streaming_latency", strconv.Itoa(*req.OptimizeStreamingLatency))
+	}
+	endpoint := fmt.Sprintf("%s/text-to-speech/%s/stream-input?%s", w.baseURL, url.PathEscape(voiceID), query.Encode())
+
+	wsConfig, err := websocket.NewConfig(endpoint, w.origin)
+	if err != nil {
+		return nil, fmt.Errorf("elevenlabs: websocket config: %w", err)
+	}
+	wsConfig.Header.Set("xi-api-key", w.apiKey)
+
+	conn, err := dial(ctx, wsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	s := &InputStream{
+		conn:  conn,
+		audio: pr,
+		done:  make(chan struct{}),
+	}
+
+	// The first message must contain a single space; it carries voice settings
+	// and pronunciation dictionaries.
+	initMsg := wsTextMessage{
+		Text:                            " ",
+		VoiceSettings:                   req.VoiceSettings,
+		PronunciationDictionaryLocators: req.PronunciationDictionaryLocators,
+	}
+	if err := s.send(initMsg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go s.receive(pw)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+
+	return s, nil
+}
+
+// dial connects to the endpoint in config and performs the handshake. When
+// the server answers the handshake with an HTTP error instead of switching
+// protocols, that response is returned as an APIError, classified like the
+// same answer to a POST.
+func dial(ctx context.Context, config *websocket.Config) (*websocket.Conn, error) {
+	var dialer net.Dialer
+	address := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		address = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+	var raw net.Conn
+	var err error
+	switch config.Location.Scheme {
+	case "ws":
+		raw, err = dialer.DialContext(ctx, "tcp", address)
+	case "wss":
+		tlsDialer := &tls.Dialer{NetDialer: &dialer, Config: config.TlsConfig}
+		raw, err = tlsDialer.DialContext(ctx, "tcp", address)
+	default:
+		err = websocket.ErrBadScheme
+	}
+	if err != nil {
+		return nil, fmt.Errorf("elevenlabs: websocket dial: %w", err)
+	}
+
+	// The handshake does not watch ctx; cancelling it expires the
+	// connection's deadline instead.
+	stop := context.AfterFunc(ctx, func() { raw.SetDeadline(time.Now()) })
+	defer stop()
+	handshake := &handshakeConn{Conn: raw, recording: true}
+	conn, err := websocket.NewClient(config, handshake)
+	handshake.recording = false
+	if err != nil {
+		defer raw.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("elevenlabs: websocket dial: %w", ctx.Err())
+		}
+		if errors.Is(err, websocket.ErrBadStatus) {
+			if resp, rerr := handshake.response(); rerr == nil {
+				defer resp.Body.Close()
+				return nil, newAPIError(resp)
+			}
+		}
+		return nil, fmt.Errorf("elevenlabs: websocket dial: %w", err)
+	}
+	if !stop() {
+		conn.Close()
+		return nil, fmt.Errorf("elevenlabs: websocket dial: %w", ctx.Err())
+	}
+	return conn, nil
+}
+
+// handshakeConn records what is read from the connection while recording is
+// set, so a response the WebSocket handshake rejected can be parsed again.
+type handshakeConn struct {
+	net.Conn
+	recording bool
+	read      bytes.Buffer
+}
+
+func (c *handshakeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.recording {
+		c.read.Write(p[:n])
+	}
+	return n, err
+}
+
+// response parses the HTTP response read during the handshake, followed by
+// whatever of its body is still unread on the connection.
+func (c *handshakeConn) response() (*http.Response, error) {
+	r := bufio.NewReader(io.MultiReader(&c.read, c.Conn))
+	return http.ReadResponse(r, nil)
+}
+
+// InputStream is an open stream-input session. Text is pushed with SendText
+// and audio is consumed with Read. It is safe to send text from one goroutine
+// while another reads audio.
+type InputStream struct {
+	conn  *websocket.Conn
+	audio *io.PipeReader
+
+	mu          sync.Mutex // serialises writes to conn
+	inputClosed bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// ErrInputClosed is returned when text is sent after CloseInput.
+var ErrInputClosed = errors.New("elevenlabs: input stream already closed")
+
+// SendText pushes a text fragment. ElevenLabs buffers fragments and starts
+// generating once enough text has accumulated; fragments should end on a word
+// boundary followed by a space.
+func (s *InputStream) SendText(text string) error {
+	if text == "" {
+		return nil
+	}
+	return s.send(wsTextMessage{Text: text, TryTriggerGeneration: true})
+}
+
+// Flush forces generation of any buffered text without closing the input.
+func (s *InputStream) Flush() error {
+	return s.send(wsTextMessage{Text: " ", Flush: true})
+}
+
+// CloseInput signals the end of text. Remaining buffered text is generated
+// and the audio stream ends with io.EOF once the final audio is delivered.
+func (s *InputStream) CloseInput() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputClosed {
+		return nil
+	}
+	s.inputClosed = true
+	if err := websocket.JSON.Send(s.conn, wsTextMessage{Text: ""}); err != nil {
+		return fmt.Errorf("elevenlabs: websocket send: %w", err)
+	}
+	return nil
+}
+
+// Read reads synthesized audio.
+func (s *InputStream) Read(p []byte) (int, error) {
+	return s.audio.Read(p)
+}
+
+// Close tears down the WebSocket connection. Pending reads return an error.
+func (s *InputStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+		s.audio.Close()
+	})
+	return err
+}
+
+func (s *InputStream) send(msg wsTextMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputClosed {
+		return ErrInputClosed
+	}
+	if err := websocket.JSON.Send(s.conn, msg); err != nil {
+		return fmt.Errorf("elevenlabs: websocket send: %w", err)
+	}
+	return nil
+}
+
+// newWebSocketError converts an error message received on the socket into an
+// APIError. The error field carries the same codes as detail.status in HTTP
+// error bodies.
+func newWebSocketError(msg wsAudioMessage) *APIError {
+	code := strings.TrimSpace(msg.Error)
+	message := strings.TrimSpace(msg.Message)
+	if message == "" {
+		message = code
+	}
+	return &APIError{
+		Body:    strings.TrimSpace(msg.Error + " " + msg.Message),
+		Kind:    classify(0, code),
+		Code:    code,
+		Message: message,
+	}
+}
+
+// receive decodes audio messages into pw until the server marks the stream
+// final, reports an error, or the connection drops.
+func (s *InputStream) receive(pw *io.PipeWriter) {
+	for {
+		var msg wsAudioMessage
+		if err := websocket.JSON.Receive(s.conn, &msg); err != nil {
+			if err == io.EOF {
+				pw.CloseWithError(fmt.Errorf("elevenlabs: websocket closed before final audio"))
+			} else {
+				pw.CloseWithError(fmt.Errorf("elevenlabs: websocket receive: %w", err))
+			}
+			return
+		}
+
+		if msg.Error != "" || (msg.Message != "" && msg.Audio == "") {
+			pw.CloseWithError(newWebSocketError(msg))
+			return
+		}
+
+		if msg.Audio != "" {
+			pcm, err := base64.StdEncoding.DecodeString(msg.Audio)
+			if err != nil {
+				pw.CloseWithError(fmt.Errorf("elevenlabs: decode audio: %w", err))
+				return
+			}
+			if _, err := pw.Write(pcm); err != nil {
+				// Reader side closed; nothing left to deliver.
+				return
+			}
+		}
+
+		if msg.IsFinal != nil && *msg.IsFinal {
+			pw.Close()
+			return
+		}
+	}
+}
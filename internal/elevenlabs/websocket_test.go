@@ -0,0 +1,273 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// wsStandIn is a local stand-in for the stream-input endpoint. It records
+// every client message and replies with one audio message per non-empty text
+// fragment, followed by isFinal once the client sends the end-of-input marker.
+type wsStandIn struct {
+	mu       sync.Mutex
+	messages []wsTextMessage
+	query    string
+	apiKey   string
+
+	// failWith, when set, is sent as an error message instead of audio.
+	failWith string
+}
+
+func (s *wsStandIn) handler(ws *websocket.Conn) {
+	s.mu.Lock()
+	s.query = ws.Request().URL.RawQuery
+	s.apiKey = ws.Request().Header.Get("xi-api-key")
+	s.mu.Unlock()
+
+	for {
+		var msg wsTextMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, msg)
+		s.mu.Unlock()
+
+		switch {
+		case s.failWith != "" && msg.Text != " ":
+			websocket.JSON.Send(ws, map[string]any{"message": s.failWith, "error": "invalid_request"})
+			return
+		case msg.Text == "":
+			websocket.JSON.Send(ws, map[string]any{"isFinal": true})
+			return
+		case strings.TrimSpace(msg.Text) != "":
+			audio := base64.StdEncoding.EncodeToString([]byte(msg.Text))
+			websocket.JSON.Send(ws, map[string]any{"audio": audio, "isFinal": nil})
+		}
+	}
+}
+
+func (s *wsStandIn) recorded() []wsTextMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]wsTextMessage(nil), s.messages...)
+}
+
+func (s *wsStandIn) handshake() (query, apiKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.query, s.apiKey
+}
+
+func newWSTestSynthesizer(t *testing.T, standIn *wsStandIn) *WebSocketSynthesizer {
+	t.Helper()
+	srv := httptest.NewServer(websocket.Handler(standIn.handler))
+	t.Cleanup(srv.Close)
+	return &WebSocketSynthesizer{
+		apiKey:  "test-key",
+		baseURL: "ws" + strings.TrimPrefix(srv.URL, "http"),
+		origin:  srv.URL,
+	}
+}
+
+func TestWebSocketSynthesizeStream(t *testing.T) {
+	standIn := &wsStandIn{}
+	w := newWSTestSynthesizer(t, standIn)
+
+	stability := 0.4
+	rc, err := w.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{
+		Text:          "hello world",
+		ModelID:       "eleven_flash_v2_5",
+		LanguageCode:  "en",
+		VoiceSettings: &VoiceSettings{Stability: &stability},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if string(got) != "hello world " {
+		t.Errorf("audio = %q, want %q", got, "hello world ")
+	}
+
+	msgs := standIn.recorded()
+	if len(msgs) != 3 {
+		t.Fatalf("got %d client messages, want 3 (init, text, end)", len(msgs))
+	}
+	if msgs[0].Text != " " || msgs[0].VoiceSettings == nil || *msgs[0].VoiceSettings.Stability != 0.4 {
+		t.Errorf("init message = %+v, want single space with voice settings", msgs[0])
+	}
+	if msgs[2].Text != "" {
+		t.Errorf("last message text = %q, want empty end-of-input marker", msgs[2].Text)
+	}
+
+	query, apiKey := standIn.handshake()
+	for _, want := range []string{"model_id=eleven_flash_v2_5", "language_code=en", "output_format=pcm_16000"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query = %q, want to contain %q", query, want)
+		}
+	}
+	if apiKey != "test-key" {
+		t.Errorf("xi-api-key = %q, want %q", apiKey, "test-key")
+	}
+}
+
+func TestWebSocketIncrementalFragments(t *testing.T) {
+	standIn := &wsStandIn{}
+	w := newWSTestSynthesizer(t, standIn)
+
+	stream, err := w.OpenStream(context.Background(), "v1", SynthesizeRequest{ModelID: "m1"})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	defer stream.Close()
+
+	buf := make([]byte, 64)
+	for _, fragment := range []string{"Hello ", "there ", "friend "} {
+		if err := stream.SendText(fragment); err != nil {
+			t.Fatalf("SendText(%q): %v", fragment, err)
+		}
+		// Audio for each fragment arrives before the input is closed.
+		n, err := io.ReadFull(stream, buf[:len(fragment)])
+		if err != nil {
+			t.Fatalf("read after %q: %v", fragment, err)
+		}
+		if string(buf[:n]) != fragment {
+			t.Errorf("audio = %q, want %q", buf[:n], fragment)
+		}
+	}
+
+	if err := stream.CloseInput(); err != nil {
+		t.Fatalf("CloseInput: %v", err)
+	}
+	rest, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("read to EOF: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("unexpected trailing audio %q", rest)
+	}
+
+	if err := stream.SendText("late "); err != ErrInputClosed {
+		t.Errorf("SendText after CloseInput = %v, want ErrInputClosed", err)
+	}
+}
+
+func TestWebSocketServerError(t *testing.T) {
+	standIn := &wsStandIn{failWith: "voice not found"}
+	w := newWSTestSynthesizer(t, standIn)
+
+	rc, err := w.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+
+	_, err = io.ReadAll(rc)
+	if err == nil {
+		t.Fatal("expected read error after server error message")
+	}
+	if !strings.Contains(err.Error(), "voice not found") {
+		t.Errorf("error = %q, want to contain server message", err.Error())
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    map[string]string
+		body      string
+		wantKind  ErrorKind
+		retryable bool
+	}{
+		{
+			name:     "invalid_api_key",
+			status:   http.StatusUnauthorized,
+			body:     `{"detail": {"status": "invalid_api_key", "message": "Invalid API key"}}`,
+			wantKind: KindInvalidAPIKey,
+		},
+		{
+			name:      "rate_limited",
+			status:    http.StatusTooManyRequests,
+			header:    map[string]string{"Retry-After": "2"},
+			body:      `{"detail": "Too many requests"}`,
+			wantKind:  KindRateLimited,
+			retryable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(srv.Close)
+			w := &WebSocketSynthesizer{apiKey: "k", baseURL: "ws" + strings.TrimPrefix(srv.URL, "http"), origin: srv.URL}
+
+			_, err := w.OpenStream(context.Background(), "v1", SynthesizeRequest{})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("OpenStream error = %v, want an APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Kind != tt.wantKind {
+				t.Errorf("APIError = status %d, kind %q; want %d, %q", apiErr.StatusCode, apiErr.Kind, tt.status, tt.wantKind)
+			}
+			if IsRetryable(err) != tt.retryable {
+				t.Errorf("IsRetryable = %v, want %v", IsRetryable(err), tt.retryable)
+			}
+			if tt.retryable && apiErr.RetryAfter != 2*time.Second {
+				t.Errorf("RetryAfter = %v, want 2s", apiErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestWebSocketContextCancel(t *testing.T) {
+	standIn := &wsStandIn{}
+	w := newWSTestSynthesizer(t, standIn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := w.OpenStream(ctx, "v1", SynthesizeRequest{})
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	defer stream.Close()
+
+	cancel()
+	if _, err := io.ReadAll(stream); err == nil {
+		t.Fatal("expected read error after context cancellation")
+	}
+}
+
+func TestWebSocketEmptyVoiceID(t *testing.T) {
+	w := NewWebSocketSynthesizer("k")
+	if _, err := w.SynthesizeStream(context.Background(), "", SynthesizeRequest{Text: "hello"}); err == nil {
+		t.Fatal("expected error for empty voice_id")
+	}
+}
+
+func TestWebSocketEmptyText(t *testing.T) {
+	w := NewWebSocketSynthesizer("k")
+	if _, err := w.SynthesizeStream(context.Background(), "v1", SynthesizeRequest{}); err == nil {
+		t.Fatal("expected error for empty text")
+	}
+}
@@ -0,0 +1,253 @@
+// Package lexicon loads pronunciation lexicons and applies them to text.
+//
+// Two file formats are supported: W3C Pronunciation Lexicon Specification
+// (PLS) XML, and a YAML map of aliases and phonemes:
+//
+//	alphabet: ipa
+//	aliases:
+//	  Nupi: noo pee
+//	  gRPC: gee R P C
+//	phonemes:
+//	  NAP: næp
+package lexicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultAlphabet is the phonetic alphabet assumed when a lexicon does not
+// name one.
+const DefaultAlphabet = "ipa"
+
+// Rule maps one or more spellings to a pronunciation. Exactly one of Alias
+// and Phoneme is set.
+type Rule struct {
+	Graphemes []string
+	Alias     string
+	Phoneme   string
+}
+
+// Lexicon is a parsed pronunciation lexicon.
+type Lexicon struct {
+	Alphabet string
+	Language string
+	Rules    []Rule
+
+	// Version identifies the lexicon contents; it changes whenever the source
+	// file does.
+	Version string
+
+	pattern *regexp.Regexp
+	lookup  map[string]*Rule
+}
+
+// Load reads a lexicon file. Files ending in .yaml or .yml are parsed as YAML,
+// everything else as PLS XML.
+func Load(path string) (*Lexicon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lexicon: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	default:
+		return ParsePLS(data)
+	}
+}
+
+// plsDocument mirrors the subset of PLS 1.0 the adapter understands.
+type plsDocument struct {
+	XMLName  xml.Name `xml:"lexicon"`
+	Alphabet string   `xml:"alphabet,attr"`
+	Lang     string   `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Lexemes  []struct {
+		Graphemes []string `xml:"grapheme"`
+		Aliases   []string `xml:"alias"`
+		Phonemes  []string `xml:"phoneme"`
+	} `xml:"lexeme"`
+}
+
+// ParsePLS parses a PLS XML document. Lexemes with neither an alias nor a
+// phoneme are rejected; when both are present the alias wins.
+func ParsePLS(data []byte) (*Lexicon, error) {
+	var doc plsDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("lexicon: parse PLS: %w", err)
+	}
+
+	lex := &Lexicon{Alphabet: doc.Alphabet, Language: doc.Lang}
+	for i, lx := range doc.Lexemes {
+		rule := Rule{}
+		for _, g := range lx.Graphemes {
+			if g = strings.TrimSpace(g); g != "" {
+				rule.Graphemes = append(rule.Graphemes, g)
+			}
+		}
+		if len(lx.Aliases) > 0 {
+			rule.Alias = strings.TrimSpace(lx.Aliases[0])
+		} else if len(lx.Phonemes) > 0 {
+			rule.Phoneme = strings.TrimSpace(lx.Phonemes[0])
+		}
+		if len(rule.Graphemes) == 0 || (rule.Alias == "" && rule.Phoneme == "") {
+			return nil, fmt.Errorf("lexicon: lexeme %d needs a grapheme and an alias or phoneme", i+1)
+		}
+		lex.Rules = append(lex.Rules, rule)
+	}
+	return lex.finish(data)
+}
+
+type yamlDocument struct {
+	Alphabet string            `yaml:"alphabet"`
+	Language string            `yaml:"language"`
+	Aliases  map[string]string `yaml:"aliases"`
+	Phonemes map[string]string `yaml:"phonemes"`
+}
+
+// ParseYAML parses the YAML alias/phoneme format.
+func ParseYAML(data []byte) (*Lexicon, error) {
+	var doc yamlDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("lexicon: parse YAML: %w", err)
+	}
+
+	lex := &Lexicon{Alphabet: doc.Alphabet, Language: doc.Language}
+	for _, g := range sortedKeys(doc.Aliases) {
+		alias := strings.TrimSpace(doc.Aliases[g])
+		if strings.TrimSpace(g) == "" || alias == "" {
+			return nil, fmt.Errorf("lexicon: alias %q needs a word and a replacement", g)
+		}
+		lex.Rules = append(lex.Rules, Rule{Graphemes: []string{strings.TrimSpace(g)}, Alias: alias})
+	}
+	for _, g := range sortedKeys(doc.Phonemes) {
+		ph := strings.TrimSpace(doc.Phonemes[g])
+		if strings.TrimSpace(g) == "" || ph == "" {
+			return nil, fmt.Errorf("lexicon: phoneme %q needs a word and a pronunciation", g)
+		}
+		lex.Rules = append(lex.Rules, Rule{Graphemes: []string{strings.TrimSpace(g)}, Phoneme: ph})
+	}
+	return lex.finish(data)
+}
+
+// finish fills in defaults, the version and the matcher.
+func (l *Lexicon) finish(source []byte) (*Lexicon, error) {
+	if len(l.Rules) == 0 {
+		return nil, fmt.Errorf("lexicon: no entries")
+	}
+	if l.Alphabet == "" {
+		l.Alphabet = DefaultAlphabet
+	}
+	sum := sha256.Sum256(source)
+	l.Version = hex.EncodeToString(sum[:8])
+
+	l.lookup = make(map[string]*Rule)
+	var graphemes []string
+	for i := range l.Rules {
+		for _, g := range l.Rules[i].Graphemes {
+			if _, dup := l.lookup[g]; dup {
+				continue // first definition wins, as in PLS
+			}
+			l.lookup[g] = &l.Rules[i]
+			graphemes = append(graphemes, g)
+		}
+	}
+
+	// Longest graphemes first so "NAP API" wins over "NAP".
+	sort.SliceStable(graphemes, func(i, j int) bool { return len(graphemes[i]) > len(graphemes[j]) })
+	alternatives := make([]string, len(graphemes))
+	for i, g := range graphemes {
+		alternatives[i] = boundary(g)
+	}
+	pattern, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("lexicon: compile: %w", err)
+	}
+	l.pattern = pattern
+	return l, nil
+}
+
+// boundary quotes g and anchors it at word boundaries where g starts or ends
+// with a word character, so "NAP" does not match inside "SNAP".
+func boundary(g string) string {
+	expr := regexp.QuoteMeta(g)
+	first, _ := utf8.DecodeRuneInString(g)
+	last, _ := utf8.DecodeLastRuneInString(g)
+	if isWordRune(first) {
+		expr = `\b` + expr
+	}
+	if isWordRune(last) {
+		expr += `\b`
+	}
+	return expr
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Rewrite replaces every listed word in text with its alias, or wraps it in
+// an SSML phoneme tag when the lexicon gives a phoneme. Matching is
+// case-sensitive, as in PLS.
+func (l *Lexicon) Rewrite(text string) string {
+	return l.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		rule := l.lookup[match]
+		if rule.Alias != "" {
+			return rule.Alias
+		}
+		return fmt.Sprintf(`<phoneme alphabet="%s" ph="%s">%s</phoneme>`, xmlEscape(l.Alphabet), xmlEscape(rule.Phoneme), xmlEscape(match))
+	})
+}
+
+// PLS renders the lexicon as a PLS 1.0 document, the format ElevenLabs
+// accepts for pronunciation dictionaries.
+func (l *Lexicon) PLS() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	lang := l.Language
+	if lang == "" {
+		lang = "en-US"
+	}
+	fmt.Fprintf(&b, `<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="%s" xml:lang="%s">`+"\n", xmlEscape(l.Alphabet), xmlEscape(lang))
+	for _, r := range l.Rules {
+		b.WriteString("  <lexeme>\n")
+		for _, g := range r.Graphemes {
+			fmt.Fprintf(&b, "    <grapheme>%s</grapheme>\n", xmlEscape(g))
+		}
+		if r.Alias != "" {
+			fmt.Fprintf(&b, "    <alias>%s</alias>\n", xmlEscape(r.Alias))
+		} else {
+			fmt.Fprintf(&b, "    <phoneme>%s</phoneme>\n", xmlEscape(r.Phoneme))
+		}
+		b.WriteString("  </lexeme>\n")
+	}
+	b.WriteString("</lexicon>\n")
+	return b.Bytes()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package lexicon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPLS = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon"
+         alphabet="ipa" xml:lang="en-US">
+  <lexeme>
+    <grapheme>Nupi</grapheme>
+    <alias>noo pee</alias>
+  </lexeme>
+  <lexeme>
+    <grapheme>NAP</grapheme>
+    <phoneme>næp</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>gRPC</grapheme>
+    <grapheme>GRPC</grapheme>
+    <alias>gee R P C</alias>
+  </lexeme>
+</lexicon>`
+
+const testYAML = `
+alphabet: ipa
+aliases:
+  Nupi: noo pee
+  gRPC: gee R P C
+  NAP API: nap A P I
+phonemes:
+  NAP: næp
+`
+
+func TestParsePLS(t *testing.T) {
+	lex, err := ParsePLS([]byte(testPLS))
+	if err != nil {
+		t.Fatalf("ParsePLS: %v", err)
+	}
+	if lex.Alphabet != "ipa" || lex.Language != "en-US" {
+		t.Errorf("alphabet/lang = %q/%q, want ipa/en-US", lex.Alphabet, lex.Language)
+	}
+	if len(lex.Rules) != 3 {
+		t.Fatalf("len(Rules) = %d, want 3", len(lex.Rules))
+	}
+	if got := lex.Rewrite("Nupi speaks GRPC."); got != "noo pee speaks gee R P C." {
+		t.Errorf("Rewrite = %q", got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	lex, err := ParseYAML([]byte(testYAML))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Welcome to Nupi!", "Welcome to noo pee!"},
+		{"Nupi uses gRPC.", "noo pee uses gee R P C."},
+		{"The NAP protocol", `The <phoneme alphabet="ipa" ph="næp">NAP</phoneme> protocol`},
+		{"The NAP API is stable", "The nap A P I is stable"},
+		{"SNAP and nupi stay", "SNAP and nupi stay"},
+		{"Nupis", "Nupis"},
+	}
+	for _, tt := range tests {
+		if got := lex.Rewrite(tt.in); got != tt.want {
+			t.Errorf("Rewrite(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func([]byte) (*Lexicon, error)
+		data  string
+	}{
+		{"pls_malformed", ParsePLS, "<lexicon>"},
+		{"pls_no_pronunciation", ParsePLS, `<lexicon><lexeme><grapheme>x</grapheme></lexeme></lexicon>`},
+		{"pls_empty", ParsePLS, `<lexicon></lexicon>`},
+		{"yaml_malformed", ParseYAML, "aliases: [a"},
+		{"yaml_empty_alias", ParseYAML, "aliases:\n  Nupi: \"\"\n"},
+		{"yaml_empty", ParseYAML, "alphabet: ipa\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.parse([]byte(tt.data)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestVersionTracksContents(t *testing.T) {
+	a, _ := ParseYAML([]byte("aliases:\n  Nupi: noo pee\n"))
+	b, _ := ParseYAML([]byte("aliases:\n  Nupi: new pea\n"))
+	if a.Version == "" || a.Version == b.Version {
+		t.Errorf("versions %q and %q should differ", a.Version, b.Version)
+	}
+}
+
+func TestPLSRoundTrip(t *testing.T) {
+	lex, err := ParseYAML([]byte(testYAML))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	again, err := ParsePLS(lex.PLS())
+	if err != nil {
+		t.Fatalf("ParsePLS(PLS()): %v\n%s", err, lex.PLS())
+	}
+	in := "Nupi uses gRPC and the NAP API."
+	if lex.Rewrite(in) != again.Rewrite(in) {
+		t.Errorf("round trip changed rewriting: %q vs %q", lex.Rewrite(in), again.Rewrite(in))
+	}
+	if !strings.Contains(string(lex.PLS()), `alphabet="ipa"`) {
+		t.Error("PLS output should declare the alphabet")
+	}
+}
+
+func TestLoadByExtension(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "lexicon.yaml")
+	plsPath := filepath.Join(dir, "lexicon.pls")
+	os.WriteFile(yamlPath, []byte(testYAML), 0o644)
+	os.WriteFile(plsPath, []byte(testPLS), 0o644)
+
+	if _, err := Load(yamlPath); err != nil {
+		t.Errorf("Load(yaml): %v", err)
+	}
+	if _, err := Load(plsPath); err != nil {
+		t.Errorf("Load(pls): %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.pls")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
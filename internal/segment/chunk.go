@@ -0,0 +1,114 @@
+package segment
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// clauseEnds are the punctuation marks after which an over-long sentence may
+// be cut.
+const clauseEnds = ",;:–—"
+
+// Chunk splits text into parts of at most maxChars characters. Parts are
+// filled with whole sentences where possible; a sentence longer than
+// maxChars is cut after clause punctuation, then between words, and only a
+// single word longer than maxChars is cut mid-word. Markup tags are never
+// split. Text within the limit is returned as a single part.
+func Chunk(text, lang string, maxChars int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return []string{text}
+	}
+
+	var pieces []string
+	for _, sentence := range sentences(text, lang) {
+		pieces = append(pieces, fit(sentence, maxChars)...)
+	}
+	return pack(pieces, maxChars)
+}
+
+// fit breaks s into pieces of at most maxChars characters, preferring
+// clause boundaries over word boundaries.
+func fit(s string, maxChars int) []string {
+	if utf8.RuneCountInString(s) <= maxChars {
+		return []string{s}
+	}
+	var out []string
+	for _, clause := range cutAtSpaces(s, func(prev rune) bool { return strings.ContainsRune(clauseEnds, prev) }) {
+		if utf8.RuneCountInString(clause) <= maxChars {
+			out = append(out, clause)
+			continue
+		}
+		for _, word := range cutAtSpaces(clause, func(rune) bool { return true }) {
+			out = append(out, cutRunes(word, maxChars)...)
+		}
+	}
+	return pack(out, maxChars)
+}
+
+// cutAtSpaces splits s at whitespace outside markup tags where the rune
+// before the whitespace satisfies cut.
+func cutAtSpaces(s string, cut func(prev rune) bool) []string {
+	var out []string
+	start := 0
+	var prev rune
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '<':
+			if end := tagEnd(s, i); end > 0 {
+				prev = '>'
+				i = end
+				continue
+			}
+		case unicode.IsSpace(r) && !unicode.IsSpace(prev) && cut(prev):
+			out = appendTrimmed(out, s[start:i])
+			start = i
+		}
+		prev = r
+		i += size
+	}
+	return appendTrimmed(out, s[start:])
+}
+
+// cutRunes splits s into pieces of at most n runes.
+func cutRunes(s string, n int) []string {
+	var out []string
+	for utf8.RuneCountInString(s) > n {
+		i := 0
+		for range n {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		out = append(out, s[:i])
+		s = s[i:]
+	}
+	return append(out, s)
+}
+
+// pack joins consecutive pieces with spaces as long as the result stays
+// within maxChars characters.
+func pack(pieces []string, maxChars int) []string {
+	var out []string
+	current, length := "", 0
+	for _, p := range pieces {
+		n := utf8.RuneCountInString(p)
+		if current != "" && length+1+n <= maxChars {
+			current += " " + p
+			length += 1 + n
+			continue
+		}
+		if current != "" {
+			out = append(out, current)
+		}
+		current, length = p, n
+	}
+	if current != "" {
+		out = append(out, current)
+	}
+	return out
+}
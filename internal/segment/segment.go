@@ -0,0 +1,176 @@
+// Package segment splits text into sentences so long answers can be
+// synthesized sentence by sentence.
+//
+// A sentence ends at ".", "!", "?" or "…" (optionally followed by closing
+// quotes or brackets) when whitespace follows, and at every line break. A
+// period does not end a sentence after a known abbreviation of the language,
+// a single-letter initial, or, in German and Polish, an ordinal number
+// ("am 3. März"); it also needs an upper-case letter, digit or quote after
+// it. Markup tags such as <break time="1.5s" /> are never split.
+package segment
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMinChars is the length below which a sentence is merged into the
+// next one; very short requests synthesize with poor prosody.
+const DefaultMinChars = 20
+
+// abbreviations lists lower-cased words that are followed by a period
+// without ending the sentence.
+var abbreviations = map[string]map[string]bool{
+	"en": set("mr", "mrs", "ms", "dr", "prof", "st", "vs", "e.g", "i.e", "jr", "sr", "inc", "ltd", "approx", "fig"),
+	"de": set("z.b", "d.h", "u.a", "bzw", "ca", "nr", "dr", "prof", "vgl", "ggf", "evtl", "inkl", "hr", "fr", "str", "bzgl", "s"),
+	"pl": set("np", "tzn", "tj", "m.in", "dr", "prof", "mgr", "inż", "ul", "godz", "ok", "nr", "tel", "tys", "ang", "al", "pt", "wg"),
+}
+
+// ordinalLanguages write ordinal numbers with a trailing period.
+var ordinalLanguages = map[string]bool{"de": true, "pl": true}
+
+func set(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+// Split returns the sentences of text for the given language code ("en",
+// "de-AT", "auto"), trimmed, with sentences shorter than minChars merged
+// into the following one. Joined with spaces they give back text up to
+// whitespace.
+func Split(text, lang string, minChars int) []string {
+	return merge(sentences(text, lang), minChars)
+}
+
+// sentences splits text at sentence boundaries without merging.
+func sentences(text, lang string) []string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '<':
+			if end := tagEnd(text, i); end > 0 {
+				i = end
+				continue
+			}
+		case r == '\n':
+			sentences = appendTrimmed(sentences, text[start:i])
+			start = i + size
+		case r == '.' || r == '!' || r == '?' || r == '…':
+			end := endOfTerminator(text, i)
+			if isBoundary(text, start, i, end, r, lang) {
+				sentences = appendTrimmed(sentences, text[start:end])
+				start = end
+			}
+			i = end
+			continue
+		}
+		i += size
+	}
+	return appendTrimmed(sentences, text[start:])
+}
+
+// tagEnd returns the end of the markup tag starting with the '<' at s[i], or
+// -1 when there is none there: a tag name, or "/", "!" or "?", must follow
+// the '<', and a '>' must close the tag before any other '<'. A plain "<",
+// as in "x < 5", is ordinary text.
+func tagEnd(s string, i int) int {
+	next, _ := utf8.DecodeRuneInString(s[i+1:])
+	if !unicode.IsLetter(next) && !strings.ContainsRune("/!?", next) {
+		return -1
+	}
+	j := strings.IndexAny(s[i+1:], "<>")
+	if j < 0 || s[i+1+j] != '>' {
+		return -1
+	}
+	return i + 1 + j + 1
+}
+
+// endOfTerminator skips the rest of a run of terminators and closing
+// punctuation starting at i.
+func endOfTerminator(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !strings.ContainsRune(".!?…\"'”’»)]", r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// isBoundary decides whether the terminator at text[at:end] ends a sentence.
+func isBoundary(text string, start, at, end int, terminator rune, lang string) bool {
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && !unicode.IsSpace(next) {
+		return false // "3.14", "e.g.x", "example.com"
+	}
+	if terminator != '.' || end-at > 1 {
+		return true
+	}
+
+	following := strings.TrimLeft(text[end:], " \t")
+	if following != "" {
+		first, _ := utf8.DecodeRuneInString(following)
+		if !unicode.IsUpper(first) && !unicode.IsDigit(first) && !strings.ContainsRune("\"'„“«(<\n", first) {
+			return false
+		}
+	}
+
+	word := text[start:at]
+	if i := strings.LastIndexAny(word, " \t(\"'"); i >= 0 {
+		word = word[i+1:]
+	}
+	if utf8.RuneCountInString(word) == 1 && unicode.IsLetter([]rune(word)[0]) {
+		return false // an initial: "J. Smith"
+	}
+	if abbreviations[lang][strings.ToLower(word)] {
+		return false
+	}
+	if ordinalLanguages[lang] && word != "" && strings.Trim(word, "0123456789") == "" {
+		return false
+	}
+	return true
+}
+
+func appendTrimmed(sentences []string, s string) []string {
+	if s = strings.TrimSpace(s); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// merge joins sentences shorter than minChars with the following sentence;
+// a short last sentence joins the previous one.
+func merge(sentences []string, minChars int) []string {
+	var out []string
+	pending := ""
+	for _, s := range sentences {
+		if pending != "" {
+			s = pending + " " + s
+		}
+		if utf8.RuneCountInString(s) < minChars {
+			pending = s
+			continue
+		}
+		out = append(out, s)
+		pending = ""
+	}
+	if pending != "" {
+		if len(out) == 0 {
+			return []string{pending}
+		}
+		out[len(out)-1] += " " + pending
+	}
+	return out
+}
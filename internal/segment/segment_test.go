@@ -0,0 +1,99 @@
+package segment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		in   string
+		want []string
+	}{
+		{"basic", "en", "The first sentence is here. The second one follows! Is this the third one?",
+			[]string{"The first sentence is here.", "The second one follows!", "Is this the third one?"}},
+		{"abbreviation", "en", "Dr. Smith met Mr. Jones yesterday. They talked for hours.",
+			[]string{"Dr. Smith met Mr. Jones yesterday.", "They talked for hours."}},
+		{"initial", "en", "The book by J. R. R. Tolkien is long. Everyone should read it.",
+			[]string{"The book by J. R. R. Tolkien is long.", "Everyone should read it."}},
+		{"decimal_and_domain", "en", "Pi is about 3.14 and nupi.ai is a site. That is all for now.",
+			[]string{"Pi is about 3.14 and nupi.ai is a site.", "That is all for now."}},
+		{"lowercase_after_period", "en", "Use the approx. value of five here. Then continue working.",
+			[]string{"Use the approx. value of five here.", "Then continue working."}},
+		{"quotes", "en", `He said "stop right there." Then he left the room quickly.`,
+			[]string{`He said "stop right there."`, "Then he left the room quickly."}},
+		{"ellipsis", "en", "Well... I am not sure about that. Let me think about it.",
+			[]string{"Well... I am not sure about that.", "Let me think about it."}},
+		{"newlines", "en", "First item of the list\nSecond item of the list",
+			[]string{"First item of the list", "Second item of the list"}},
+		{"merge_short", "en", "Yes. Sure. That is a really good question indeed.",
+			[]string{"Yes. Sure. That is a really good question indeed."}},
+		{"short_tail", "en", "This is a long enough first sentence. Ok.",
+			[]string{"This is a long enough first sentence. Ok."}},
+		{"tags", "en", `Wait for it <break time="1.5s" /> and then go. Another sentence here.`,
+			[]string{`Wait for it <break time="1.5s" /> and then go.`, "Another sentence here."}},
+		{"less_than", "en", "Check whether x < 5. Then stop the loop right away. The next one comes after.",
+			[]string{"Check whether x < 5.", "Then stop the loop right away.", "The next one comes after."}},
+		{"unclosed_tag", "en", "Keep every a<b pair sorted. Then stop the loop right away.",
+			[]string{"Keep every a<b pair sorted.", "Then stop the loop right away."}},
+		{"de_ordinal", "de", "Wir treffen uns am 3. März in Berlin. Bis dann, alles Gute.",
+			[]string{"Wir treffen uns am 3. März in Berlin.", "Bis dann, alles Gute."}},
+		{"de_abbreviation", "de", "Das ist z.B. ein gutes Beispiel. Und hier noch eins dazu.",
+			[]string{"Das ist z.B. ein gutes Beispiel.", "Und hier noch eins dazu."}},
+		{"pl_abbreviation", "pl-PL", "Spotkamy się ok. 5 minut po czasie. Do zobaczenia wkrótce.",
+			[]string{"Spotkamy się ok. 5 minut po czasie.", "Do zobaczenia wkrótce."}},
+		{"en_year", "en", "It happened in 2024. Nobody expected it at all.",
+			[]string{"It happened in 2024.", "Nobody expected it at all."}},
+		{"single", "en", "Just one sentence without end", []string{"Just one sentence without end"}},
+		{"empty", "en", "  \n ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.in, tt.lang, DefaultMinChars)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitNoMerge(t *testing.T) {
+	got := Split("Yes. No. Maybe.", "en", 0)
+	want := []string{"Yes.", "No.", "Maybe."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split = %q, want %q", got, want)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want []string
+	}{
+		{"within_limit", "One. Two. Three.", 100, []string{"One. Two. Three."}},
+		{"packs_sentences", "First one. Second one. Third one.", 22,
+			[]string{"First one. Second one.", "Third one."}},
+		{"clauses", "A long sentence, with a clause; and another part here.", 30,
+			[]string{"A long sentence,", "with a clause;", "and another part here."}},
+		{"words", "one two three four five six", 10,
+			[]string{"one two", "three four", "five six"}},
+		{"long_word", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"tags", `Wait <break time="1s" /> now and then go.`, 26,
+			[]string{`Wait <break time="1s" />`, "now and then go."}},
+		{"less_than", "x < 5 and y > 3 now", 10, []string{"x < 5 and", "y > 3 now"}},
+		{"runes", "żółć żółć żółć", 9, []string{"żółć żółć", "żółć"}},
+		{"empty", "  ", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Chunk(tt.in, "en", tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunk(%q, %d)\n got %q\nwant %q", tt.in, tt.max, got, tt.want)
+			}
+		})
+	}
+}
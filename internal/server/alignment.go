@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/viseme"
+)
+
+const (
+	// metaAlignment carries, on an audio chunk, the timings of the characters
+	// whose speech starts in that chunk.
+	metaAlignment = "alignment"
+	// metaWordAlignment carries, on the FINISHED status, the timings of all
+	// spoken words.
+	metaWordAlignment = "word_alignment"
+	// metaVisemes carries, on an audio chunk, the mouth shapes starting in
+	// that chunk.
+	metaVisemes = "visemes"
+)
+
+// alignmentJSON is the JSON form of character timings, used for metaAlignment
+// and for alignments stored in the cache. Times are in milliseconds from the
+// start of the utterance.
+type alignmentJSON struct {
+	Chars   []string `json:"chars"`
+	StartMs []int64  `json:"start_ms"`
+	EndMs   []int64  `json:"end_ms"`
+}
+
+// wordTiming is one element of the metaWordAlignment JSON array. Offset is
+// the word's position, in characters, in the spoken text.
+type wordTiming struct {
+	Word    string `json:"word"`
+	Offset  int    `json:"offset"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+}
+
+// visemeJSON is one element of the metaVisemes JSON array.
+type visemeJSON struct {
+	Viseme  string `json:"viseme"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+}
+
+func encodeVisemes(events []viseme.Event) []byte {
+	out := make([]visemeJSON, len(events))
+	for i, e := range events {
+		out[i] = visemeJSON{Viseme: e.Viseme, StartMs: e.Start.Milliseconds(), EndMs: e.End.Milliseconds()}
+	}
+	data, _ := json.Marshal(out)
+	return data
+}
+
+func encodeAlignment(timings []elevenlabs.CharacterTiming) []byte {
+	a := alignmentJSON{
+		Chars:   make([]string, len(timings)),
+		StartMs: make([]int64, len(timings)),
+		EndMs:   make([]int64, len(timings)),
+	}
+	for i, t := range timings {
+		a.Chars[i] = t.Char
+		a.StartMs[i] = t.Start.Milliseconds()
+		a.EndMs[i] = t.End.Milliseconds()
+	}
+	data, _ := json.Marshal(a)
+	return data
+}
+
+func decodeAlignment(data []byte) ([]elevenlabs.CharacterTiming, error) {
+	var a alignmentJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	if len(a.StartMs) != len(a.Chars) || len(a.EndMs) != len(a.Chars) {
+		return nil, fmt.Errorf("alignment: %d characters but %d/%d times", len(a.Chars), len(a.StartMs), len(a.EndMs))
+	}
+	timings := make([]elevenlabs.CharacterTiming, len(a.Chars))
+	for i, c := range a.Chars {
+		timings[i] = elevenlabs.CharacterTiming{
+			Char:  c,
+			Start: time.Duration(a.StartMs[i]) * time.Millisecond,
+			End:   time.Duration(a.EndMs[i]) * time.Millisecond,
+		}
+	}
+	return timings, nil
+}
+
+// wordTimings groups character timings into whitespace-separated words.
+func wordTimings(timings []elevenlabs.CharacterTiming) []wordTiming {
+	words := []wordTiming{}
+	var word strings.Builder
+	var current wordTiming
+	offset := 0
+	for _, t := range timings {
+		if strings.IndexFunc(t.Char, func(r rune) bool { return !unicode.IsSpace(r) }) < 0 {
+			if word.Len() > 0 {
+				current.Word = word.String()
+				words = append(words, current)
+				word.Reset()
+			}
+		} else {
+			if word.Len() == 0 {
+				current = wordTiming{Offset: offset, StartMs: t.Start.Milliseconds()}
+			}
+			word.WriteString(t.Char)
+			current.EndMs = t.End.Milliseconds()
+		}
+		offset += len([]rune(t.Char))
+	}
+	if word.Len() > 0 {
+		current.Word = word.String()
+		words = append(words, current)
+	}
+	return words
+}
+
+// shiftTimings moves timings of a part onto the utterance's timeline.
+func shiftTimings(timings []elevenlabs.CharacterTiming, by time.Duration) []elevenlabs.CharacterTiming {
+	out := make([]elevenlabs.CharacterTiming, len(timings))
+	for i, t := range timings {
+		t.Start += by
+		t.End += by
+		out[i] = t
+	}
+	return out
+}
+
+// visemeTimings converts character timings for the viseme mapper.
+func visemeTimings(timings []elevenlabs.CharacterTiming) []viseme.Timing {
+	out := make([]viseme.Timing, len(timings))
+	for i, t := range timings {
+		out[i] = viseme.Timing(t)
+	}
+	return out
+}
+
+// estimateTimings stands in for the character timings of a part ElevenLabs
+// reported none for, spreading its text over the audio received so far if
+// that is complete.
+func estimateTimings(job *segmentJob, format elevenlabs.OutputFormat) []elevenlabs.CharacterTiming {
+	var duration time.Duration
+	if size, done := job.audio.size(); done {
+		duration = format.Duration(size)
+	}
+	estimated := viseme.Estimate(job.text, duration)
+	out := make([]elevenlabs.CharacterTiming, len(estimated))
+	for i, t := range estimated {
+		out[i] = elevenlabs.CharacterTiming(t)
+	}
+	return out
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+)
+
+// Request metadata key selecting what a duplex message does, and its values.
+const (
+	metaCommand = "command"
+
+	// commandAppend adds the message's text to the buffer.
+	commandAppend = "append"
+	// commandFlush adds the text and queues the buffer as an utterance.
+	commandFlush = "flush"
+	// commandCancel stops the current utterance, then adds the text.
+	commandCancel = "cancel"
+	// commandClear stops the current utterance, drops the queued ones and
+	// the buffer, then adds the text.
+	commandClear = "clear"
+)
+
+// metaUtterance names the utterance a duplex response belongs to.
+const metaUtterance = "utterance_id"
+
+// Synthesize serves a duplex synthesis session: the client streams text
+// fragments and commands, the adapter streams back the responses of each
+// flushed utterance exactly as StreamSynthesis would, tagged with the
+// utterance's ID. Any other request metadata, such as a voice override,
+// applies to the utterances flushed after it. When the client closes its
+// side, buffered text is flushed and the session ends once every utterance
+// was played.
+//
+// With the WebSocket backend, each utterance's text is sent upstream as it
+// is appended, on an input stream of its own, so ElevenLabs starts
+// generating before the flush, which ends the stream's input. Changing the
+// synthesis settings before the flush moves the text to a stream with the
+// new settings; cancelling or clearing closes the streams concerned. Other
+// backends take the whole text, so each utterance is synthesized by a
+// request of its own once flushed.
+func (s *Server) Synthesize(stream DuplexSynthesisService_SynthesizeServer) error {
+	ctx, end := context.WithCancelCause(stream.Context())
+	defer end(nil)
+	session := newDuplexSession(stream, end)
+	defer s.sessions.unregister(session)
+	if s.streaming != nil {
+		session.openInput = func(metadata map[string]string) *duplexInput {
+			return s.openInput(ctx, metadata)
+		}
+	}
+
+	go s.receiveDuplex(session)
+
+	for {
+		uctx, cancel := context.WithCancel(ctx)
+		u, ok := session.next(ctx, cancel)
+		if !ok {
+			cancel()
+			break
+		}
+		ulog := s.log.With("session_id", u.req.GetSessionId(), "utterance_id", u.id)
+		ulog.Debug("duplex utterance started")
+		utteranceStream := &utteranceStream{DuplexSynthesisService_SynthesizeServer: stream, session: session, utterance: u, ctx: uctx}
+		err := s.StreamSynthesis(u.req, utteranceStream)
+		cancel()
+		if u.input != nil {
+			u.input.close()
+		}
+		session.done()
+		if utteranceStream.err != nil {
+			return utteranceStream.err
+		}
+		if err != nil && ctx.Err() == nil {
+			// The failure was reported to the client; the session goes on.
+			ulog.Warn("duplex utterance failed", "error", err)
+		}
+	}
+
+	cause := context.Cause(ctx)
+	if cause == nil {
+		return nil
+	}
+	st := session.state()
+	s.log.Info("duplex session ended",
+		"session_id", st.id,
+		"reason", cause,
+		"buffered_bytes", st.buffered,
+		"queued_utterances", st.queued,
+	)
+	switch {
+	case errors.Is(cause, errSessionReplaced):
+		return status.Error(codes.Aborted, cause.Error())
+	case stream.Context().Err() != nil:
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	return cause
+}
+
+// receiveDuplex applies the client's messages to session until the client
+// closes its side or the stream fails.
+func (s *Server) receiveDuplex(session *duplexSession) {
+	for first := true; ; first = false {
+		req, err := session.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			session.close()
+			return
+		}
+		if err != nil {
+			session.end(err)
+			return
+		}
+		if first && req.GetSessionId() != "" {
+			session.open(req.GetSessionId())
+			s.sessions.register(session)
+		}
+		if err := s.applyDuplex(session, req); err != nil {
+			s.log.Warn("invalid duplex message", "session_id", session.state().id, "error", err)
+			resp := &napv1.SynthesisResponse{
+				Status:       napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR,
+				ErrorMessage: err.Error(),
+				Metadata: map[string]string{
+					metaErrorKind:      errorKindInvalidRequest,
+					metaErrorCode:      codes.InvalidArgument.String(),
+					metaErrorRetryable: "false",
+				},
+			}
+			if err := session.send(resp); err != nil {
+				session.end(err)
+				return
+			}
+		}
+	}
+}
+
+// applyDuplex carries out one client message. A message that fails
+// validation is ignored as a whole.
+func (s *Server) applyDuplex(session *duplexSession, req *napv1.StreamSynthesisRequest) error {
+	command := cmp.Or(strings.ToLower(strings.TrimSpace(req.GetMetadata()[metaCommand])), commandAppend)
+	switch command {
+	case commandAppend, commandFlush, commandCancel, commandClear:
+	default:
+		return fmt.Errorf("%s: unknown command %q", metaCommand, command)
+	}
+
+	overrides := make(map[string]string, len(req.GetMetadata()))
+	for k, v := range req.GetMetadata() {
+		if k != metaCommand {
+			overrides[k] = v
+		}
+	}
+	if len(overrides) > 0 {
+		merged := session.override(overrides)
+		if _, err := resolveSynthesisParams(s.cfg, merged); err != nil {
+			return err
+		}
+		if _, err := resolveOutputFormat(s.cfg.OutputFormat, merged); err != nil {
+			return err
+		}
+		session.apply(merged)
+	}
+
+	switch command {
+	case commandCancel:
+		s.logDuplex(session, "duplex utterance cancelled")
+		session.cancel()
+	case commandClear:
+		s.logDuplex(session, "duplex session cleared")
+		session.clear()
+	}
+	session.append(req.GetText())
+	if command == commandFlush {
+		session.flush(req.GetStreamId())
+	}
+	return nil
+}
+
+// logDuplex logs msg with the session's state.
+func (s *Server) logDuplex(session *duplexSession, msg string) {
+	st := session.state()
+	s.log.Info(msg,
+		"session_id", st.id,
+		"utterance_id", st.current,
+		"queued_utterances", st.queued,
+		"buffered_bytes", st.buffered,
+	)
+}
+
+// utteranceStream presents one utterance of a duplex session to
+// StreamSynthesis as a server stream of its own: its context ends when the
+// utterance is cancelled, and every response is tagged with the utterance's
+// ID. Once cancelled no more audio is sent, but the final status is.
+type utteranceStream struct {
+	DuplexSynthesisService_SynthesizeServer
+	session   *duplexSession
+	utterance *utterance
+	ctx       context.Context
+
+	err error // the session stream failed
+}
+
+func (u *utteranceStream) Context() context.Context {
+	return u.ctx
+}
+
+func (u *utteranceStream) Send(resp *napv1.SynthesisResponse) error {
+	if resp.Chunk != nil && u.ctx.Err() != nil {
+		return u.ctx.Err()
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string, 1)
+	}
+	resp.Metadata[metaUtterance] = u.utterance.id
+	if err := u.session.send(resp); err != nil {
+		u.err = err
+		return err
+	}
+	return nil
+}
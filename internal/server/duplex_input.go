@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// duplexInput sends the text of one duplex utterance upstream as the client
+// appends it, so ElevenLabs starts generating before the utterance is
+// flushed. A goroutine of its own waits for an upstream slot, opens the
+// input stream and sends the text in whole words; the audio is read once the
+// utterance plays.
+type duplexInput struct {
+	prepare func(string) string // turns a fragment into the text sent
+	cancel  context.CancelFunc  // closes the stream
+
+	mu      sync.Mutex
+	pending strings.Builder // appended but not sent yet
+	flushed bool            // no more text follows
+	err     error           // sending failed
+	wake    chan struct{}
+
+	ready  chan struct{} // closed once the stream is open or failed to open
+	stream elevenlabs.TextStream
+	failed error // why the stream could not be opened
+}
+
+// openInput starts streaming the text of an utterance upstream with the
+// synthesis settings in metadata. It returns nil when the utterance cannot
+// be streamed, leaving it to be synthesized as a whole once flushed: without
+// an incremental backend, for SSML, which is parsed as a whole, and for
+// metadata the utterance will be rejected for anyway.
+func (s *Server) openInput(ctx context.Context, metadata map[string]string) *duplexInput {
+	if s.streaming == nil {
+		return nil
+	}
+	if format := strings.ToLower(strings.TrimSpace(metadata[metaTextFormat])); format != "" && format != textFormatPlain {
+		return nil
+	}
+	params, err := resolveSynthesisParams(s.cfg, metadata)
+	if err != nil {
+		return nil
+	}
+	format, err := resolveOutputFormat(s.cfg.OutputFormat, metadata)
+	if err != nil {
+		return nil
+	}
+	priority, err := requestPriority(metadata)
+	if err != nil {
+		return nil
+	}
+	lang := resolveLanguage(s.cfg.Language, metadata)
+
+	ctx, cancel := context.WithCancel(ctx)
+	in := &duplexInput{
+		prepare: func(text string) string {
+			return s.applyLexicon(s.normalizer.Normalize(text, lang))
+		},
+		cancel: cancel,
+		wake:   make(chan struct{}, 1),
+		ready:  make(chan struct{}),
+	}
+	go s.sendInput(ctx, in, params.VoiceID, s.synthesisRequest(params, format, lang), priority)
+	return in
+}
+
+// sendInput opens the input stream of in and sends its text until the
+// utterance is flushed. The stream, and its upstream slot, are held until
+// in is closed.
+func (s *Server) sendInput(ctx context.Context, in *duplexInput, voiceID string, req elevenlabs.SynthesizeRequest, priority int) {
+	if s.admission != nil {
+		release, err := s.admission.Acquire(ctx, priority, nil)
+		if err != nil {
+			in.opened(nil, &segmentError{stage: "synthesis not admitted", err: err})
+			return
+		}
+		defer release()
+	}
+	stream, err := s.streaming.OpenStream(ctx, voiceID, req)
+	if err != nil {
+		in.opened(nil, &segmentError{stage: "synthesis failed", err: err})
+		return
+	}
+	defer stream.Close()
+	in.opened(stream, nil)
+
+	for {
+		text, last, ok := in.take(ctx)
+		if !ok {
+			return
+		}
+		// The stream-input protocol expects every fragment to end with a
+		// space.
+		if text = in.prepare(text); text != "" {
+			if err := stream.SendText(text + " "); err != nil {
+				in.fail(err)
+				return
+			}
+		}
+		if last {
+			if err := stream.CloseInput(); err != nil {
+				in.fail(err)
+				return
+			}
+			break
+		}
+	}
+	<-ctx.Done()
+}
+
+// send queues appended text.
+func (in *duplexInput) send(text string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.pending.WriteString(text)
+	in.signal()
+}
+
+// closeInput marks the utterance flushed: the rest of its text is sent and
+// the input ended, so its audio ends too.
+func (in *duplexInput) closeInput() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.flushed = true
+	in.signal()
+}
+
+// close closes the stream, discarding the audio not read yet.
+func (in *duplexInput) close() {
+	in.cancel()
+}
+
+// take waits for text to send and returns the whole words of it, or all of
+// it once the utterance was flushed, as last reports. It reports false when
+// ctx is done first.
+func (in *duplexInput) take(ctx context.Context) (text string, last, ok bool) {
+	for {
+		in.mu.Lock()
+		pending := in.pending.String()
+		end := strings.LastIndexAny(pending, " \t\n") + 1
+		if in.flushed {
+			end = len(pending)
+		}
+		if end > 0 || in.flushed {
+			in.pending.Reset()
+			in.pending.WriteString(pending[end:])
+			last := in.flushed
+			in.mu.Unlock()
+			return pending[:end], last, true
+		}
+		in.mu.Unlock()
+
+		select {
+		case <-in.wake:
+		case <-ctx.Done():
+			return "", false, false
+		}
+	}
+}
+
+// opened records the opened stream, or why it could not be opened.
+func (in *duplexInput) opened(stream elevenlabs.TextStream, err error) {
+	in.stream = stream
+	in.failed = err
+	close(in.ready)
+}
+
+// open waits for the stream to be opened and returns it.
+func (in *duplexInput) open(ctx context.Context) (elevenlabs.TextStream, error) {
+	select {
+	case <-in.ready:
+		return in.stream, in.failed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fail records that sending text failed and closes the stream.
+func (in *duplexInput) fail(err error) {
+	in.mu.Lock()
+	in.err = err
+	in.mu.Unlock()
+	in.cancel()
+}
+
+// failure returns the error that made reading the stream fail with err: a
+// failed send, which also ended the stream, or err itself.
+func (in *duplexInput) failure(err error) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.err != nil {
+		return in.err
+	}
+	return err
+}
+
+func (in *duplexInput) signal() {
+	select {
+	case in.wake <- struct{}{}:
+	default:
+	}
+}
+
+// relayInput fills job.audio with the audio of a duplex utterance whose text
+// was sent upstream through in.
+func (s *Server) relayInput(ctx context.Context, in *duplexInput, job *segmentJob) {
+	stream, err := in.open(ctx)
+	if err != nil {
+		var segErr *segmentError
+		if !errors.As(err, &segErr) {
+			segErr = &segmentError{stage: "synthesis failed", err: err}
+		}
+		job.audio.finish(segErr)
+		return
+	}
+	// Stopping the utterance closes the stream, ending a pending read.
+	stop := context.AfterFunc(ctx, in.close)
+	defer stop()
+
+	buffer := make([]byte, chunkSize)
+	for {
+		if err := job.audio.wait(ctx); err != nil {
+			job.audio.finish(&segmentError{stage: "stream read error", err: err})
+			return
+		}
+		n, err := stream.Read(buffer)
+		if n > 0 {
+			job.audio.append(buffer[:n], nil)
+		}
+		if errors.Is(err, io.EOF) {
+			job.audio.finish(nil)
+			return
+		}
+		if err != nil {
+			job.audio.finish(&segmentError{stage: "stream read error", err: in.failure(err)})
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+)
+
+// The duplex synthesis service has no .proto of its own: it reuses the NAP
+// request and response messages, so the service descriptor and stubs below
+// are written by hand in the shape protoc-gen-go-grpc would produce for
+//
+//	service DuplexSynthesisService {
+//	  rpc Synthesize(stream nupi.nap.v1.StreamSynthesisRequest)
+//	      returns (stream nupi.nap.v1.SynthesisResponse);
+//	}
+
+const (
+	DuplexSynthesisService_Synthesize_FullMethodName = "/nupi.tts.elevenlabs.v1.DuplexSynthesisService/Synthesize"
+)
+
+// DuplexSynthesisServiceClient is the client API for DuplexSynthesisService.
+type DuplexSynthesisServiceClient interface {
+	Synthesize(ctx context.Context, opts ...grpc.CallOption) (DuplexSynthesisService_SynthesizeClient, error)
+}
+
+type duplexSynthesisServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDuplexSynthesisServiceClient(cc grpc.ClientConnInterface) DuplexSynthesisServiceClient {
+	return &duplexSynthesisServiceClient{cc}
+}
+
+func (c *duplexSynthesisServiceClient) Synthesize(ctx context.Context, opts ...grpc.CallOption) (DuplexSynthesisService_SynthesizeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DuplexSynthesisService_ServiceDesc.Streams[0], DuplexSynthesisService_Synthesize_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &duplexSynthesisServiceSynthesizeClient{stream}, nil
+}
+
+type DuplexSynthesisService_SynthesizeClient interface {
+	Send(*napv1.StreamSynthesisRequest) error
+	Recv() (*napv1.SynthesisResponse, error)
+	grpc.ClientStream
+}
+
+type duplexSynthesisServiceSynthesizeClient struct {
+	grpc.ClientStream
+}
+
+func (x *duplexSynthesisServiceSynthesizeClient) Send(m *napv1.StreamSynthesisRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *duplexSynthesisServiceSynthesizeClient) Recv() (*napv1.SynthesisResponse, error) {
+	m := new(napv1.SynthesisResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DuplexSynthesisServiceServer is the server API for DuplexSynthesisService.
+type DuplexSynthesisServiceServer interface {
+	Synthesize(DuplexSynthesisService_SynthesizeServer) error
+}
+
+func RegisterDuplexSynthesisServiceServer(s grpc.ServiceRegistrar, srv DuplexSynthesisServiceServer) {
+	s.RegisterService(&DuplexSynthesisService_ServiceDesc, srv)
+}
+
+func _DuplexSynthesisService_Synthesize_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DuplexSynthesisServiceServer).Synthesize(&duplexSynthesisServiceSynthesizeServer{stream})
+}
+
+type DuplexSynthesisService_SynthesizeServer interface {
+	Send(*napv1.SynthesisResponse) error
+	Recv() (*napv1.StreamSynthesisRequest, error)
+	grpc.ServerStream
+}
+
+type duplexSynthesisServiceSynthesizeServer struct {
+	grpc.ServerStream
+}
+
+func (x *duplexSynthesisServiceSynthesizeServer) Send(m *napv1.SynthesisResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *duplexSynthesisServiceSynthesizeServer) Recv() (*napv1.StreamSynthesisRequest, error) {
+	m := new(napv1.StreamSynthesisRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DuplexSynthesisService_ServiceDesc is the grpc.ServiceDesc for
+// DuplexSynthesisService. It's only intended for direct use with
+// grpc.RegisterService.
+var DuplexSynthesisService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nupi.tts.elevenlabs.v1.DuplexSynthesisService",
+	HandlerType: (*DuplexSynthesisServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Synthesize",
+			Handler:       _DuplexSynthesisService_Synthesize_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
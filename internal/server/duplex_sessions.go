@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+)
+
+// errSessionReplaced ends a duplex session whose ID was opened again by a
+// newer stream, typically a client reconnecting.
+var errSessionReplaced = errors.New("session replaced by a newer stream")
+
+// duplexSessions tracks the open duplex sessions by session ID. Opening a
+// session under the ID of one still open ends the older session.
+type duplexSessions struct {
+	mu   sync.Mutex
+	open map[string]*duplexSession
+}
+
+func newDuplexSessions() *duplexSessions {
+	return &duplexSessions{open: make(map[string]*duplexSession)}
+}
+
+// register records session under its ID, ending any session registered
+// under it before.
+func (m *duplexSessions) register(session *duplexSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := session.state().id
+	if old, ok := m.open[id]; ok && old != session {
+		old.end(errSessionReplaced)
+	}
+	m.open[id] = session
+}
+
+// unregister forgets session unless a newer session replaced it.
+func (m *duplexSessions) unregister(session *duplexSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id := session.state().id; m.open[id] == session {
+		delete(m.open, id)
+	}
+}
+
+// utterance is text flushed by a duplex client, synthesized as one
+// StreamSynthesis request.
+type utterance struct {
+	id     string
+	req    *napv1.StreamSynthesisRequest
+	input  *duplexInput       // the stream its text was sent to, if any
+	cancel context.CancelFunc // set once synthesis starts
+}
+
+// duplexState summarizes a session's text and utterance queue.
+type duplexState struct {
+	id       string // session ID, empty until the client names the session
+	buffered int    // bytes of text appended but not yet flushed
+	queued   int    // utterances waiting for the current one
+	current  string // ID of the utterance being synthesized, if any
+}
+
+// duplexSession holds the state of one Synthesize stream. Text appended by
+// the client is buffered until a flush turns it into an utterance, and sent
+// upstream as it arrives when the backend accepts incremental input;
+// utterances are played one at a time, in the order flushed.
+type duplexSession struct {
+	stream DuplexSynthesisService_SynthesizeServer
+	end    context.CancelCauseFunc // ends the whole session
+
+	// openInput starts streaming an utterance's text upstream with the
+	// given metadata; nil, or returning nil, when it cannot be streamed.
+	openInput func(metadata map[string]string) *duplexInput
+
+	sendMu sync.Mutex // serializes sends of the receiver and the player
+
+	mu       sync.Mutex
+	id       string
+	metadata map[string]string // request metadata carried into every utterance
+	text     strings.Builder   // appended since the last flush
+	input    *duplexInput      // where text is sent as appended, if streamed
+	queue    []*utterance
+	current  *utterance // nil while idle
+	flushed  int        // utterances flushed so far
+	closed   bool       // the client sent its last message
+	wake     chan struct{}
+}
+
+func newDuplexSession(stream DuplexSynthesisService_SynthesizeServer, end context.CancelCauseFunc) *duplexSession {
+	return &duplexSession{
+		stream:   stream,
+		end:      end,
+		metadata: make(map[string]string),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// open names the session after the client's session ID.
+func (d *duplexSession) open(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.id = id
+}
+
+// send sends resp on the session's stream.
+func (d *duplexSession) send(resp *napv1.SynthesisResponse) error {
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+	return d.stream.Send(resp)
+}
+
+// override merges metadata into the metadata carried by later utterances;
+// an empty value removes the key. It returns the merged metadata without
+// applying it.
+func (d *duplexSession) override(metadata map[string]string) map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	merged := maps.Clone(d.metadata)
+	for k, v := range metadata {
+		if v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// apply replaces the metadata carried by later utterances. Text already
+// sent upstream is sent again on a stream with the new settings, such as
+// another voice.
+func (d *duplexSession) apply(metadata map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	changed := !maps.Equal(d.metadata, metadata)
+	d.metadata = metadata
+	if changed && d.input != nil {
+		d.reopenLocked()
+	}
+}
+
+// append buffers text for the next flush and sends it upstream when the
+// utterance is streamed.
+func (d *duplexSession) append(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.text.WriteString(text)
+	switch {
+	case d.input != nil:
+		d.input.send(text)
+	case d.openInput != nil && strings.TrimSpace(d.text.String()) != "":
+		d.reopenLocked()
+	}
+}
+
+// reopenLocked closes the stream the buffered text was sent to, if any, and
+// sends the text on a new one with the current metadata.
+func (d *duplexSession) reopenLocked() {
+	if d.input != nil {
+		d.input.close()
+	}
+	d.input = d.openInput(d.metadata)
+	if d.input != nil {
+		d.input.send(d.text.String())
+	}
+}
+
+// flush queues the buffered text as an utterance named streamID, or by its
+// position in the session when streamID is empty. Nothing is queued while
+// the buffer is empty.
+func (d *duplexSession) flush(streamID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flushLocked(streamID)
+}
+
+func (d *duplexSession) flushLocked(streamID string) {
+	if strings.TrimSpace(d.text.String()) == "" {
+		d.text.Reset()
+		if d.input != nil {
+			d.input.close()
+			d.input = nil
+		}
+		return
+	}
+	d.flushed++
+	id := streamID
+	if id == "" {
+		id = strconv.Itoa(d.flushed)
+	}
+	d.queue = append(d.queue, &utterance{
+		id: id,
+		req: &napv1.StreamSynthesisRequest{
+			SessionId: d.id,
+			StreamId:  id,
+			Text:      d.text.String(),
+			Metadata:  maps.Clone(d.metadata),
+		},
+		input: d.input,
+	})
+	if d.input != nil {
+		d.input.closeInput()
+		d.input = nil
+	}
+	d.text.Reset()
+	d.signal()
+}
+
+// cancel stops the utterance being synthesized, closing the stream its audio
+// comes from; queued utterances follow.
+func (d *duplexSession) cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.current != nil && d.current.cancel != nil {
+		d.current.cancel()
+	}
+}
+
+// clear stops the utterance being synthesized and drops the queued ones and
+// the buffered text, closing the streams their text was sent to.
+func (d *duplexSession) clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.current != nil && d.current.cancel != nil {
+		d.current.cancel()
+	}
+	for _, u := range d.queue {
+		if u.input != nil {
+			u.input.close()
+		}
+	}
+	d.queue = nil
+	d.text.Reset()
+	if d.input != nil {
+		d.input.close()
+		d.input = nil
+	}
+}
+
+// close flushes the buffered text after the client's last message; the
+// session ends once the queue is played.
+func (d *duplexSession) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flushLocked("")
+	d.closed = true
+	d.signal()
+}
+
+// next waits for the next utterance and marks it current, with cancel
+// stopping it. It reports false once the client closed the session and
+// every utterance was played, or when ctx is done.
+func (d *duplexSession) next(ctx context.Context, cancel context.CancelFunc) (*utterance, bool) {
+	for {
+		d.mu.Lock()
+		if len(d.queue) > 0 {
+			u := d.queue[0]
+			d.queue = d.queue[1:]
+			u.cancel = cancel
+			d.current = u
+			d.mu.Unlock()
+			return u, true
+		}
+		closed := d.closed
+		d.mu.Unlock()
+		if closed {
+			return nil, false
+		}
+		select {
+		case <-d.wake:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// done marks the current utterance finished.
+func (d *duplexSession) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.current = nil
+}
+
+// state returns the session's text and utterance queue state.
+func (d *duplexSession) state() duplexState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := duplexState{id: d.id, buffered: d.text.Len(), queued: len(d.queue)}
+	if d.current != nil {
+		st.current = d.current.id
+	}
+	return st
+}
+
+// signal wakes the player without blocking.
+func (d *duplexSession) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,486 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// setupDuplex creates a bufconn gRPC server serving the duplex service and
+// returns its client.
+func setupDuplex(t *testing.T, synth elevenlabs.Synthesizer) DuplexSynthesisServiceClient {
+	t.Helper()
+	return setupDuplexWithConfig(t, testConfig(), synth)
+}
+
+func setupDuplexWithConfig(t *testing.T, cfg config.Config, synth elevenlabs.Synthesizer) DuplexSynthesisServiceClient {
+	t.Helper()
+	buf := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	RegisterDuplexSynthesisServiceServer(srv, New(cfg, slog.Default(), synth, nil, nil))
+	go srv.Serve(buf)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return buf.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return NewDuplexSynthesisServiceClient(conn)
+}
+
+// duplexMessage builds a client message with a command.
+func duplexMessage(text, command string) *napv1.StreamSynthesisRequest {
+	return &napv1.StreamSynthesisRequest{SessionId: "s1", Text: text, Metadata: map[string]string{metaCommand: command}}
+}
+
+// recvUntil receives responses until one satisfies done and returns them.
+func recvUntil(t *testing.T, stream DuplexSynthesisService_SynthesizeClient, done func(*napv1.SynthesisResponse) bool) []*napv1.SynthesisResponse {
+	t.Helper()
+	var responses []*napv1.SynthesisResponse
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		responses = append(responses, resp)
+		if done(resp) {
+			return responses
+		}
+	}
+}
+
+// statusOf reports whether resp is the given status for utterance id.
+func statusOf(resp *napv1.SynthesisResponse, st napv1.SynthesisStatus, id string) bool {
+	return resp.Status == st && resp.Metadata[metaUtterance] == id
+}
+
+// recordingSynthesizer serves every text with 100ms of audio, except that
+// texts listed in hold stream 100ms and then stall until cancelled.
+type recordingSynthesizer struct {
+	hold map[string]bool
+
+	mu     sync.Mutex
+	texts  []string
+	voices []string
+}
+
+func (r *recordingSynthesizer) SynthesizeStream(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+	r.mu.Lock()
+	r.texts = append(r.texts, req.Text)
+	r.voices = append(r.voices, voiceID)
+	r.mu.Unlock()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(make([]byte, 3200))
+		if r.hold[req.Text] {
+			<-ctx.Done()
+			pw.CloseWithError(ctx.Err())
+			return
+		}
+		pw.Close()
+	}()
+	return pr, nil
+}
+
+func (r *recordingSynthesizer) requested() ([]string, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.texts...), append([]string(nil), r.voices...)
+}
+
+func TestDuplexAppendFlushAndVoiceChange(t *testing.T) {
+	synth := &recordingSynthesizer{}
+	client := setupDuplex(t, synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	messages := []*napv1.StreamSynthesisRequest{
+		duplexMessage("Hello ", commandAppend),
+		{SessionId: "s1", StreamId: "greeting", Text: "world.", Metadata: map[string]string{metaCommand: commandFlush}},
+		// The voice changes for the utterances flushed from here on.
+		{Text: "Bye", Metadata: map[string]string{metaVoiceID: "other_voice"}},
+		duplexMessage(" now.", commandFlush),
+		duplexMessage("Unflushed.", commandAppend),
+	}
+	for _, m := range messages {
+		if err := stream.Send(m); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	// Closing the client side flushes the rest.
+	stream.CloseSend()
+
+	var finished []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if resp.Metadata[metaUtterance] == "" {
+			t.Errorf("response %v not tagged with its utterance", resp)
+		}
+		if resp.Status == napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED {
+			finished = append(finished, resp.Metadata[metaUtterance])
+		}
+	}
+
+	if want := []string{"greeting", "2", "3"}; !reflect.DeepEqual(finished, want) {
+		t.Errorf("finished utterances = %v, want %v", finished, want)
+	}
+	texts, voices := synth.requested()
+	if want := []string{"Hello world.", "Bye now.", "Unflushed."}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("synthesized texts = %q, want %q", texts, want)
+	}
+	if want := []string{"test-voice", "other_voice", "other_voice"}; !reflect.DeepEqual(voices, want) {
+		t.Errorf("voices = %v, want %v", voices, want)
+	}
+}
+
+func TestDuplexCancel(t *testing.T) {
+	synth := &recordingSynthesizer{hold: map[string]bool{"Long story.": true}}
+	client := setupDuplex(t, synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	stream.Send(duplexMessage("Long story.", commandFlush))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool { return r.Chunk != nil })
+
+	// Cancelling stops the stalled utterance; the next one plays.
+	stream.Send(duplexMessage("", commandCancel))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, "1")
+	})
+	stream.Send(duplexMessage("Short.", commandFlush))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, "2")
+	})
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("after the last utterance: %v, want EOF", err)
+	}
+}
+
+func TestDuplexClear(t *testing.T) {
+	synth := &recordingSynthesizer{hold: map[string]bool{"Long story.": true}}
+	client := setupDuplex(t, synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	stream.Send(duplexMessage("Long story.", commandFlush))
+	stream.Send(duplexMessage("Queued.", commandFlush))
+	stream.Send(duplexMessage("Buffered", commandAppend))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool { return r.Chunk != nil })
+
+	// Clearing drops the queued utterance and the buffer; the message's
+	// text starts a new buffer.
+	stream.Send(duplexMessage("Instead.", commandClear))
+	stream.Send(duplexMessage("", commandFlush))
+	stream.CloseSend()
+
+	var statuses []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		switch resp.Status {
+		case napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED:
+			statuses = append(statuses, resp.Status.String()+" "+resp.Metadata[metaUtterance])
+		}
+	}
+	want := []string{"SYNTHESIS_STATUS_INTERRUPTED 1", "SYNTHESIS_STATUS_FINISHED 3"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if texts, _ := synth.requested(); !reflect.DeepEqual(texts, []string{"Long story.", "Instead."}) {
+		t.Errorf("synthesized texts = %q, want the queued utterance dropped", texts)
+	}
+}
+
+func TestDuplexInvalidMessage(t *testing.T) {
+	synth := &recordingSynthesizer{}
+	client := setupDuplex(t, synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	stream.Send(duplexMessage("Hi.", "shout"))
+	stream.Send(&napv1.StreamSynthesisRequest{Text: "Hi.", Metadata: map[string]string{metaVoiceID: "bad voice!"}})
+	for i := 0; i < 2; i++ {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if resp.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR || resp.Metadata[metaErrorKind] != errorKindInvalidRequest {
+			t.Errorf("response %d = %v, want an invalid request error", i, resp)
+		}
+	}
+
+	// The rejected messages left no trace; the session goes on.
+	stream.Send(duplexMessage("Fine.", commandFlush))
+	stream.CloseSend()
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, "1")
+	})
+	if texts, voices := synth.requested(); !reflect.DeepEqual(texts, []string{"Fine."}) || voices[0] != "test-voice" {
+		t.Errorf("synthesized %q with %v, want only the valid message", texts, voices)
+	}
+}
+
+func TestDuplexSessionReplaced(t *testing.T) {
+	client := setupDuplex(t, &recordingSynthesizer{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	old, err := client.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	old.Send(duplexMessage("Hi.", commandFlush))
+	recvUntil(t, old, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, "1")
+	})
+
+	// A new stream for the same session ends the old one.
+	replacement, err := client.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	replacement.Send(duplexMessage("", commandAppend))
+	_, err = old.Recv()
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("old stream ended with %v, want Aborted", err)
+	}
+}
+
+// inputSynthesizer is a recordingSynthesizer that also accepts text
+// incrementally. Each input stream answers with 100ms of audio once its
+// input is closed, unless its text contains a held text, in which case the
+// audio stalls until the stream is closed.
+type inputSynthesizer struct {
+	recordingSynthesizer
+
+	mu      sync.Mutex
+	streams []*fakeInputStream
+}
+
+func (s *inputSynthesizer) OpenStream(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest) (elevenlabs.TextStream, error) {
+	pr, pw := io.Pipe()
+	stream := &fakeInputStream{voice: voiceID, hold: s.hold, audio: pr, w: pw}
+	s.mu.Lock()
+	s.streams = append(s.streams, stream)
+	s.mu.Unlock()
+	context.AfterFunc(ctx, func() { stream.Close() })
+	return stream, nil
+}
+
+// opened returns the streams opened so far.
+func (s *inputSynthesizer) opened() []*fakeInputStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*fakeInputStream(nil), s.streams...)
+}
+
+type fakeInputStream struct {
+	voice string
+	hold  map[string]bool
+	audio *io.PipeReader
+	w     *io.PipeWriter
+
+	mu     sync.Mutex
+	text   strings.Builder
+	ended  bool // CloseInput was called
+	closed bool
+}
+
+func (f *fakeInputStream) SendText(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.text.WriteString(text)
+	return nil
+}
+
+func (f *fakeInputStream) Flush() error { return nil }
+
+func (f *fakeInputStream) CloseInput() error {
+	f.mu.Lock()
+	f.ended = true
+	text := strings.TrimSpace(f.text.String())
+	f.mu.Unlock()
+	go func() {
+		f.w.Write(make([]byte, 3200))
+		if !f.hold[text] {
+			f.w.Close()
+		}
+	}()
+	return nil
+}
+
+func (f *fakeInputStream) Read(p []byte) (int, error) { return f.audio.Read(p) }
+
+func (f *fakeInputStream) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	f.w.CloseWithError(io.ErrClosedPipe)
+	return nil
+}
+
+// state returns the text sent on the stream and whether its input was ended
+// and the stream closed.
+func (f *fakeInputStream) state() (text string, ended, closed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.text.String(), f.ended, f.closed
+}
+
+func inputConfig() config.Config {
+	cfg := testConfig()
+	cfg.Backend = config.BackendWebSocket
+	return cfg
+}
+
+func TestDuplexStreamsTextAsAppended(t *testing.T) {
+	synth := &inputSynthesizer{}
+	client := setupDuplexWithConfig(t, inputConfig(), synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	// Whole words go upstream before the flush; the rest waits for it.
+	stream.Send(duplexMessage("Hello wor", commandAppend))
+	waitFor(t, func() bool {
+		streams := synth.opened()
+		if len(streams) != 1 {
+			return false
+		}
+		text, _, _ := streams[0].state()
+		return text == "Hello "
+	})
+
+	stream.Send(duplexMessage("ld.", commandFlush))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, "1")
+	})
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("after the last utterance: %v, want EOF", err)
+	}
+
+	streams := synth.opened()
+	if len(streams) != 1 {
+		t.Fatalf("opened %d streams, want 1", len(streams))
+	}
+	if text, ended, closed := streams[0].state(); text != "Hello world. " || !ended || !closed {
+		t.Errorf("stream got %q, ended %v, closed %v; want the whole text, ended and closed", text, ended, closed)
+	}
+	if texts, _ := synth.requested(); len(texts) != 0 {
+		t.Errorf("synthesized %q as whole texts, want none", texts)
+	}
+}
+
+func TestDuplexInputReopenedOnVoiceChange(t *testing.T) {
+	synth := &inputSynthesizer{}
+	client := setupDuplexWithConfig(t, inputConfig(), synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	stream.Send(duplexMessage("Hello ", commandAppend))
+	waitFor(t, func() bool { return len(synth.opened()) == 1 })
+	stream.Send(&napv1.StreamSynthesisRequest{Text: "there ", Metadata: map[string]string{metaVoiceID: "other_voice"}})
+	stream.Send(duplexMessage("now.", commandFlush))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, "1")
+	})
+
+	streams := synth.opened()
+	if len(streams) != 2 {
+		t.Fatalf("opened %d streams, want 2", len(streams))
+	}
+	if _, ended, closed := streams[0].state(); ended || !closed {
+		t.Errorf("first stream ended %v, closed %v; want closed without audio", ended, closed)
+	}
+	text, _, _ := streams[1].state()
+	if streams[1].voice != "other_voice" || text != "Hello there now. " {
+		t.Errorf("second stream got %q with %s, want the whole text with other_voice", text, streams[1].voice)
+	}
+}
+
+func TestDuplexInputClosedOnCancelAndClear(t *testing.T) {
+	synth := &inputSynthesizer{recordingSynthesizer: recordingSynthesizer{hold: map[string]bool{"Long story.": true}}}
+	client := setupDuplexWithConfig(t, inputConfig(), synth)
+	stream, err := client.Synthesize(context.Background())
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+
+	stream.Send(duplexMessage("Long story.", commandFlush))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool { return r.Chunk != nil })
+	stream.Send(duplexMessage("Next ", commandAppend))
+	waitFor(t, func() bool { return len(synth.opened()) == 2 })
+
+	// Cancelling closes the stream of the stalled utterance only.
+	stream.Send(duplexMessage("", commandCancel))
+	recvUntil(t, stream, func(r *napv1.SynthesisResponse) bool {
+		return statusOf(r, napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, "1")
+	})
+	streams := synth.opened()
+	waitFor(t, func() bool { _, _, closed := streams[0].state(); return closed })
+	if _, _, closed := streams[1].state(); closed {
+		t.Error("cancel closed the stream of the text still being appended")
+	}
+
+	// Clearing drops the appended text and closes its stream.
+	stream.Send(duplexMessage("", commandClear))
+	waitFor(t, func() bool { _, _, closed := streams[1].state(); return closed })
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("after clearing: %v, want EOF", err)
+	}
+	if _, ended, _ := streams[1].state(); ended {
+		t.Error("the cleared text was synthesized")
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/admission"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// Metadata keys attached to STATUS_ERROR responses so the daemon can react to
+// the failure without parsing ErrorMessage.
+const (
+	metaErrorKind      = "error_kind"
+	metaErrorCode      = "grpc_code"
+	metaErrorRetryable = "retryable"
+	metaHTTPStatus     = "http_status"
+	metaUpstreamCode   = "upstream_code"
+	metaRetryAfterMs   = "retry_after_ms"
+)
+
+// error_kind values for failures that do not come from ElevenLabs; API errors
+// use the elevenlabs.ErrorKind values.
+const (
+	errorKindInvalidRequest      = "invalid_request"
+	errorKindInternal            = "internal"
+	errorKindCircuitOpen         = "circuit_open"
+	errorKindCanceled            = "canceled"
+	errorKindDeadlineExceeded    = "deadline_exceeded"
+	errorKindUpstreamUnavailable = "upstream_unavailable"
+)
+
+// classifyError maps a synthesis failure onto a gRPC code and the structured
+// metadata describing it.
+func classifyError(err error) (codes.Code, map[string]string) {
+	metadata := map[string]string{
+		metaErrorRetryable: strconv.FormatBool(elevenlabs.IsRetryable(err)),
+	}
+
+	var apiErr *elevenlabs.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		metadata[metaErrorKind] = string(apiErr.Kind)
+		if apiErr.StatusCode != 0 {
+			metadata[metaHTTPStatus] = strconv.Itoa(apiErr.StatusCode)
+		}
+		if apiErr.Code != "" {
+			metadata[metaUpstreamCode] = apiErr.Code
+		}
+		if apiErr.RetryAfter > 0 {
+			metadata[metaRetryAfterMs] = strconv.FormatInt(apiErr.RetryAfter.Milliseconds(), 10)
+		}
+		return apiErrorCode(apiErr), metadata
+	case errors.Is(err, admission.ErrQueueTimeout):
+		metadata[metaErrorKind] = errorKindQueueTimeout
+		metadata[metaErrorRetryable] = "true"
+		return codes.ResourceExhausted, metadata
+	case errors.Is(err, elevenlabs.ErrCircuitOpen):
+		metadata[metaErrorKind] = errorKindCircuitOpen
+		return codes.Unavailable, metadata
+	case errors.Is(err, context.Canceled):
+		metadata[metaErrorKind] = errorKindCanceled
+		return codes.Canceled, metadata
+	case errors.Is(err, context.DeadlineExceeded):
+		metadata[metaErrorKind] = errorKindDeadlineExceeded
+		return codes.DeadlineExceeded, metadata
+	case elevenlabs.IsRetryable(err):
+		metadata[metaErrorKind] = errorKindUpstreamUnavailable
+		return codes.Unavailable, metadata
+	}
+	metadata[metaErrorKind] = errorKindInternal
+	return codes.Internal, metadata
+}
+
+// apiErrorCode returns the gRPC code for an ElevenLabs API error.
+func apiErrorCode(err *elevenlabs.APIError) codes.Code {
+	switch err.Kind {
+	case elevenlabs.KindQuotaExceeded, elevenlabs.KindRateLimited:
+		return codes.ResourceExhausted
+	case elevenlabs.KindInvalidAPIKey:
+		return codes.Unauthenticated
+	case elevenlabs.KindVoiceNotFound:
+		return codes.NotFound
+	case elevenlabs.KindUnsupportedModel, elevenlabs.KindTextTooLong:
+		return codes.InvalidArgument
+	}
+	switch {
+	case err.StatusCode == 0:
+		return codes.Unknown
+	case err.StatusCode == 403:
+		return codes.PermissionDenied
+	case err.StatusCode == 404:
+		return codes.NotFound
+	case err.StatusCode == 408:
+		return codes.Unavailable
+	case err.StatusCode >= 500:
+		return codes.Unavailable
+	case err.StatusCode >= 400:
+		return codes.InvalidArgument
+	}
+	return codes.Unknown
+}
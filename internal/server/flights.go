@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// flight is one upstream synthesis shared by the identical parts of
+// concurrent requests. Every subscriber relays the flight's audio into its
+// own segment, at its own pace; audio all subscribers relayed is dropped.
+type flight struct {
+	audio     *segmentAudio
+	requestID string             // set before audio is finished
+	cancel    context.CancelFunc // stops the upstream synthesis
+
+	subscribers map[*segmentJob]int // offset relayed; guarded by flights.mu
+}
+
+// flights coalesces concurrent syntheses of the same part, keyed like the
+// cache, into one upstream request. The request outlives the subscriber
+// that started it and is cancelled only when its last subscriber leaves.
+type flights struct {
+	mu       sync.Mutex
+	inflight map[string]*flight
+}
+
+func newFlights() *flights {
+	return &flights{inflight: make(map[string]*flight)}
+}
+
+// join subscribes job to the flight synthesizing key. When no such flight
+// is in progress, or it already dropped the start of its audio, a new one is
+// started, and the context for its upstream request is returned; it carries
+// the values of ctx but not its cancellation.
+func (f *flights) join(ctx context.Context, key string, job *segmentJob) (*flight, context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fl, ok := f.inflight[key]; ok && !fl.audio.dropped() {
+		fl.subscribers[job] = 0
+		return fl, nil
+	}
+	upstream, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	fl := &flight{
+		audio:       newSharedAudio(min(job.audio.window, chunkSize)),
+		cancel:      cancel,
+		subscribers: map[*segmentJob]int{job: 0},
+	}
+	f.inflight[key] = fl
+	return fl, upstream
+}
+
+// leave unsubscribes job from fl. The last subscriber to leave cancels the
+// upstream request, if it is still running.
+func (f *flights) leave(key string, fl *flight, job *segmentJob) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(fl.subscribers, job)
+	if len(fl.subscribers) == 0 {
+		fl.cancel()
+		f.removeLocked(key, fl)
+	}
+}
+
+// land removes fl once its audio is complete, so later requests go to the
+// cache or upstream again.
+func (f *flights) land(key string, fl *flight) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(key, fl)
+}
+
+func (f *flights) removeLocked(key string, fl *flight) {
+	if f.inflight[key] == fl {
+		delete(f.inflight, key)
+	}
+}
+
+// queued tells the subscribers of fl waiting for playback its position in
+// the admission queue.
+func (f *flights) queued(fl *flight, position int) {
+	f.mu.Lock()
+	var notify []func(int)
+	for job := range fl.subscribers {
+		if job.queued != nil {
+			notify = append(notify, job.queued)
+		}
+	}
+	f.mu.Unlock()
+	for _, queued := range notify {
+		queued(position)
+	}
+}
+
+// advance records that job relayed the audio of fl up to offset, and drops
+// the audio every subscriber has relayed.
+func (f *flights) advance(fl *flight, job *segmentJob, offset int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fl.subscribers[job] = offset
+	for _, relayed := range fl.subscribers {
+		offset = min(offset, relayed)
+	}
+	fl.audio.drop(offset)
+}
+
+// relay copies the audio of fl into job.audio as it arrives, until the
+// flight ends or ctx is done.
+func (f *flights) relay(ctx context.Context, fl *flight, job *segmentJob) {
+	seen := 0 // timings already passed to job.audio
+	for offset := 0; ; {
+		// Reading no more than job.audio takes holds back the flight, and
+		// with it the upstream request.
+		if err := job.audio.wait(ctx); err != nil {
+			job.audio.finish(err)
+			return
+		}
+		data, done, err := fl.audio.read(ctx, offset, chunkSize)
+		if err != nil {
+			job.audio.finish(err)
+			return
+		}
+		offset += len(data)
+		f.advance(fl, job, offset)
+		// Timings are added with their audio, so all those of data are in.
+		timings := fl.audio.alignment()[seen:]
+		seen += len(timings)
+		if len(data) > 0 || len(timings) > 0 {
+			job.audio.append(data, timings)
+		}
+		if done {
+			job.requestID = fl.requestID
+			job.audio.finish(nil)
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+// framer cuts delivered audio into frames of a fixed duration that always end
+// on a sample boundary. Audio short of a frame is carried into the next call.
+type framer struct {
+	frameBytes  int // 0 passes audio through unchanged
+	sampleBytes int // bytes of one sample across all channels
+	pending     []byte
+}
+
+// newFramer returns a framer for frames of samples samples of sampleBytes
+// each. A zero sampleBytes, as for compressed audio, disables framing.
+func newFramer(sampleBytes, samples int) *framer {
+	return &framer{frameBytes: sampleBytes * max(samples, 1), sampleBytes: sampleBytes}
+}
+
+// frames returns the complete frames available after adding data. When last
+// is set the remainder is returned as a final, shorter frame; a trailing
+// partial sample is dropped.
+func (f *framer) frames(data []byte, last bool) [][]byte {
+	if f.frameBytes == 0 {
+		if len(data) == 0 {
+			return nil
+		}
+		return [][]byte{data}
+	}
+
+	f.pending = append(f.pending, data...)
+	var frames [][]byte
+	for len(f.pending) >= f.frameBytes {
+		frames = append(frames, f.pending[:f.frameBytes:f.frameBytes])
+		f.pending = f.pending[f.frameBytes:]
+	}
+	if last {
+		if rest := f.rest(); len(rest) > 0 {
+			frames = append(frames, rest)
+		}
+	}
+	// Copy the remainder so the sent frames' buffer can be released.
+	if len(frames) > 0 && len(f.pending) > 0 {
+		f.pending = append([]byte(nil), f.pending...)
+	}
+	return frames
+}
+
+// rest returns the audio carried over, cut to whole samples, and clears it.
+func (f *framer) rest() []byte {
+	n := len(f.pending)
+	if f.sampleBytes > 0 {
+		n -= n % f.sampleBytes
+	}
+	rest := f.pending[:n:n]
+	f.pending = nil
+	return rest
+}
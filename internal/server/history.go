@@ -0,0 +1,109 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// maxStitchingSessions bounds the number of sessions whose history is kept.
+const maxStitchingSessions = 1024
+
+// sessionHistory remembers the ElevenLabs request IDs of the latest
+// generations per session, so the next utterance can be stitched to them.
+// Sessions idle for longer than ttl are forgotten; beyond maxSessions the
+// least recently used session is dropped.
+type sessionHistory struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxSessions int
+	now         func() time.Time
+	sessions    map[string]*sessionEntry
+}
+
+type sessionEntry struct {
+	requestIDs []string // oldest first, at most MaxStitchingRequestIDs
+	usedAt     time.Time
+}
+
+func newSessionHistory(ttl time.Duration, maxSessions int, now func() time.Time) *sessionHistory {
+	return &sessionHistory{
+		ttl:         ttl,
+		maxSessions: maxSessions,
+		now:         now,
+		sessions:    make(map[string]*sessionEntry),
+	}
+}
+
+// previous returns the request IDs to send as previous_request_ids for the
+// next utterance of session.
+func (h *sessionHistory) previous(session string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e, ok := h.sessions[session]
+	if !ok {
+		return nil
+	}
+	now := h.now()
+	if now.Sub(e.usedAt) > h.ttl {
+		delete(h.sessions, session)
+		return nil
+	}
+	e.usedAt = now
+	return append([]string(nil), e.requestIDs...)
+}
+
+// record appends the request IDs of an utterance to the session's history.
+// An empty list clears it: the utterance was not generated by a request the
+// next one could reference, e.g. it was served from the cache.
+func (h *sessionHistory) record(session string, requestIDs []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	e, ok := h.sessions[session]
+	if len(requestIDs) == 0 {
+		delete(h.sessions, session)
+		return
+	}
+	if !ok || now.Sub(e.usedAt) > h.ttl {
+		h.expire(now)
+		if len(h.sessions) >= h.maxSessions {
+			h.evictOldest()
+		}
+		e = &sessionEntry{}
+		h.sessions[session] = e
+	}
+
+	ids := append(e.requestIDs, requestIDs...)
+	if len(ids) > elevenlabs.MaxStitchingRequestIDs {
+		ids = ids[len(ids)-elevenlabs.MaxStitchingRequestIDs:]
+	}
+	e.requestIDs = append([]string(nil), ids...)
+	e.usedAt = now
+}
+
+// expire removes sessions idle for longer than ttl. Must be called with mu
+// held.
+func (h *sessionHistory) expire(now time.Time) {
+	for id, e := range h.sessions {
+		if now.Sub(e.usedAt) > h.ttl {
+			delete(h.sessions, id)
+		}
+	}
+}
+
+// evictOldest removes the least recently used session. Must be called with
+// mu held.
+func (h *sessionHistory) evictOldest() {
+	var oldestID string
+	var oldest time.Time
+	for id, e := range h.sessions {
+		if oldestID == "" || e.usedAt.Before(oldest) {
+			oldestID, oldest = id, e.usedAt
+		}
+	}
+	delete(h.sessions, oldestID)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestSessionHistoryKeepsLatestIDs(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	h := newSessionHistory(time.Minute, 10, clock.now)
+
+	if got := h.previous("s1"); got != nil {
+		t.Errorf("previous of an unknown session = %v, want nil", got)
+	}
+	h.record("s1", []string{"a", "b"})
+	h.record("s1", []string{"c", "d"})
+	if got, want := h.previous("s1"), []string{"b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("previous = %v, want %v", got, want)
+	}
+	if got := h.previous("s2"); got != nil {
+		t.Errorf("sessions must not share history, got %v", got)
+	}
+
+	h.record("s1", nil)
+	if got := h.previous("s1"); got != nil {
+		t.Errorf("previous after an unreferenceable utterance = %v, want nil", got)
+	}
+}
+
+func TestSessionHistoryExpires(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	h := newSessionHistory(time.Minute, 10, clock.now)
+
+	h.record("s1", []string{"a"})
+	clock.t = clock.t.Add(50 * time.Second)
+	if got := h.previous("s1"); len(got) != 1 {
+		t.Fatalf("previous within TTL = %v, want [a]", got)
+	}
+	// The lookup refreshed the session.
+	clock.t = clock.t.Add(50 * time.Second)
+	if got := h.previous("s1"); len(got) != 1 {
+		t.Fatalf("previous within TTL of the last use = %v, want [a]", got)
+	}
+	clock.t = clock.t.Add(61 * time.Second)
+	if got := h.previous("s1"); got != nil {
+		t.Errorf("previous after TTL = %v, want nil", got)
+	}
+
+	h.record("s2", []string{"b"})
+	clock.t = clock.t.Add(2 * time.Minute)
+	h.record("s3", []string{"c"})
+	if len(h.sessions) != 1 {
+		t.Errorf("sessions = %d, want expired sessions removed", len(h.sessions))
+	}
+}
+
+func TestSessionHistoryBounded(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	h := newSessionHistory(time.Hour, 2, clock.now)
+
+	h.record("s1", []string{"a"})
+	clock.t = clock.t.Add(time.Second)
+	h.record("s2", []string{"b"})
+	clock.t = clock.t.Add(time.Second)
+	h.previous("s1") // s2 is now the least recently used
+	clock.t = clock.t.Add(time.Second)
+	h.record("s3", []string{"c"})
+
+	if len(h.sessions) != 2 {
+		t.Fatalf("sessions = %d, want 2", len(h.sessions))
+	}
+	if h.previous("s2") != nil {
+		t.Error("least recently used session should have been evicted")
+	}
+	if h.previous("s1") == nil || h.previous("s3") == nil {
+		t.Error("recent sessions should be kept")
+	}
+}
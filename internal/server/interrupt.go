@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// Metadata keys of the INTERRUPTED status describing how far playback got.
+const (
+	metaSpokenChars      = "spoken_chars"
+	metaSpokenText       = "spoken_text"
+	metaAudioMsDelivered = "audio_ms_delivered"
+)
+
+// wholePart selects all characters of a part in heardText.
+const wholePart = time.Duration(math.MaxInt64)
+
+// partTimings returns the character timings of a part: those reported by
+// ElevenLabs or, lacking them, an estimate from its text.
+func partTimings(job *segmentJob, format elevenlabs.OutputFormat) []elevenlabs.CharacterTiming {
+	if timings := job.audio.alignment(); len(timings) > 0 {
+		return timings
+	}
+	return estimateTimings(job, format)
+}
+
+// heardText joins the characters whose speech ended within the first heard
+// of a part's audio.
+func heardText(timings []elevenlabs.CharacterTiming, heard time.Duration) string {
+	var b strings.Builder
+	for _, t := range timings {
+		if t.End > heard {
+			break
+		}
+		b.WriteString(t.Char)
+	}
+	return b.String()
+}
+
+// describeInterruption records in metadata how much audio was delivered and
+// the text spoken in it: that of the finished parts, then current, the
+// delivered beginning of the part playing when the stream was interrupted.
+func describeInterruption(metadata map[string]string, spoken []string, current string, delivered time.Duration) {
+	if current != "" {
+		spoken = append(spoken, current)
+	}
+	text := strings.Join(spoken, " ")
+	metadata[metaSpokenText] = text
+	metadata[metaSpokenChars] = fmt.Sprintf("%d", utf8.RuneCountInString(text))
+	metadata[metaAudioMsDelivered] = fmt.Sprintf("%d", delivered.Milliseconds())
+}
@@ -0,0 +1,46 @@
+package server
+
+import "sync"
+
+// maxLevelVoices bounds the number of voices whose loudness is remembered.
+const maxLevelVoices = 256
+
+// voiceLevels remembers the loudness last measured per voice and model, so
+// normalization of the next utterance starts at the right gain instead of
+// converging during its first words. Beyond maxVoices an arbitrary voice is
+// forgotten.
+type voiceLevels struct {
+	mu        sync.Mutex
+	maxVoices int
+	levels    map[string]float64 // LUFS by levelKey
+}
+
+func newVoiceLevels(maxVoices int) *voiceLevels {
+	return &voiceLevels{maxVoices: maxVoices, levels: make(map[string]float64)}
+}
+
+// levelKey identifies a voice as rendered by a model.
+func levelKey(voiceID, model string) string {
+	return voiceID + "/" + model
+}
+
+// get returns the loudness last measured for voice, in LUFS.
+func (v *voiceLevels) get(voice string) (float64, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	level, ok := v.levels[voice]
+	return level, ok
+}
+
+// record stores the loudness measured for an utterance of voice.
+func (v *voiceLevels) record(voice string, lufs float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.levels[voice]; !ok && len(v.levels) >= v.maxVoices {
+		for k := range v.levels {
+			delete(v.levels, k)
+			break
+		}
+	}
+	v.levels[voice] = lufs
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// Request metadata keys overriding the configured synthesis parameters.
+const (
+	metaVoiceID                  = "elevenlabs.voice_id"
+	metaModel                    = "elevenlabs.model"
+	metaStability                = "elevenlabs.stability"
+	metaSimilarityBoost          = "elevenlabs.similarity_boost"
+	metaOptimizeStreamingLatency = "elevenlabs.optimize_streaming_latency"
+	metaStyle                    = "elevenlabs.style"
+	metaUseSpeakerBoost          = "elevenlabs.use_speaker_boost"
+	metaSpeed                    = "elevenlabs.speed"
+)
+
+// synthesisParams are the voice, model and voice settings used for a single
+// request: the configured values with metadata overrides applied.
+type synthesisParams struct {
+	VoiceID                  string
+	Model                    string
+	Stability                *float64
+	SimilarityBoost          *float64
+	OptimizeStreamingLatency *int
+	Style                    *float64
+	UseSpeakerBoost          *bool
+	Speed                    *float64
+}
+
+// resolveSynthesisParams applies the elevenlabs.* metadata overrides to the
+// configured parameters. Overrides are validated with the same rules as
+// config.Config.
+func resolveSynthesisParams(cfg config.Config, metadata map[string]string) (synthesisParams, error) {
+	p := synthesisParams{
+		VoiceID:                  cfg.VoiceID,
+		Model:                    cfg.Model,
+		Stability:                cfg.Stability,
+		SimilarityBoost:          cfg.SimilarityBoost,
+		OptimizeStreamingLatency: cfg.OptimizeStreamingLatency,
+		Style:                    cfg.Style,
+		UseSpeakerBoost:          cfg.UseSpeakerBoost,
+		Speed:                    cfg.Speed,
+	}
+
+	if v, ok := override(metadata, metaVoiceID); ok {
+		if err := config.ValidateVoiceID(v); err != nil {
+			return p, fmt.Errorf("%s: %w", metaVoiceID, err)
+		}
+		p.VoiceID = v
+	}
+	if v, ok := override(metadata, metaModel); ok {
+		if err := config.ValidateModel(v); err != nil {
+			return p, fmt.Errorf("%s: %w", metaModel, err)
+		}
+		p.Model = v
+	}
+	if err := overrideFloat(metadata, metaStability, config.ValidateStability, &p.Stability); err != nil {
+		return p, err
+	}
+	if err := overrideFloat(metadata, metaSimilarityBoost, config.ValidateSimilarityBoost, &p.SimilarityBoost); err != nil {
+		return p, err
+	}
+	if v, ok := override(metadata, metaOptimizeStreamingLatency); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("%s: invalid integer %q", metaOptimizeStreamingLatency, v)
+		}
+		if err := config.ValidateOptimizeStreamingLatency(n); err != nil {
+			return p, fmt.Errorf("%s: %w", metaOptimizeStreamingLatency, err)
+		}
+		p.OptimizeStreamingLatency = &n
+	}
+	if err := overrideFloat(metadata, metaStyle, config.ValidateStyle, &p.Style); err != nil {
+		return p, err
+	}
+	if v, ok := override(metadata, metaUseSpeakerBoost); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return p, fmt.Errorf("%s: invalid boolean %q", metaUseSpeakerBoost, v)
+		}
+		p.UseSpeakerBoost = &b
+	}
+	if err := overrideFloat(metadata, metaSpeed, config.ValidateSpeed, &p.Speed); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// override returns the trimmed metadata value for key, if non-empty.
+func override(metadata map[string]string, key string) (string, bool) {
+	v := strings.TrimSpace(metadata[key])
+	return v, v != ""
+}
+
+func overrideFloat(metadata map[string]string, key string, validate func(float64) error, target **float64) error {
+	v, ok := override(metadata, key)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", key, v)
+	}
+	if err := validate(f); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*target = &f
+	return nil
+}
+
+// voiceSettings returns the voice_settings payload, or nil when no setting is
+// set and the voice defaults apply.
+func (p synthesisParams) voiceSettings() *elevenlabs.VoiceSettings {
+	if p.Stability == nil && p.SimilarityBoost == nil && p.Style == nil && p.UseSpeakerBoost == nil && p.Speed == nil {
+		return nil
+	}
+	return &elevenlabs.VoiceSettings{
+		Stability:       p.Stability,
+		SimilarityBoost: p.SimilarityBoost,
+		Style:           p.Style,
+		UseSpeakerBoost: p.UseSpeakerBoost,
+		Speed:           p.Speed,
+	}
+}
+
+// settingsMetadata describes the voice settings applied to the request, for
+// echoing in chunk metadata.
+func (p synthesisParams) settingsMetadata() map[string]string {
+	return map[string]string{
+		"stability":                  formatFloatPtr(p.Stability),
+		"similarity_boost":           formatFloatPtr(p.SimilarityBoost),
+		"optimize_streaming_latency": formatIntPtr(p.OptimizeStreamingLatency),
+		"style":                      formatFloatPtr(p.Style),
+		"use_speaker_boost":          formatBoolPtr(p.UseSpeakerBoost),
+		"speed":                      formatFloatPtr(p.Speed),
+	}
+}
+
+func formatFloatPtr(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func formatIntPtr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func formatBoolPtr(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
@@ -0,0 +1,395 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/audio"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/viseme"
+)
+
+// audioPipeline turns upstream audio into the audio delivered to the client,
+// applying the configured local conversion. Without conversion it passes the
+// upstream bytes through unchanged.
+type audioPipeline struct {
+	upstream  elevenlabs.OutputFormat
+	trimmer   *audio.SilenceTrimmer // nil unless silence is trimmed
+	leveler   *audio.Leveler        // nil without normalization or fades
+	converter *audio.Converter      // nil when audio is passed through
+	out       audio.Format          // valid when converter != nil
+}
+
+// newPipeline builds the pipeline for audio arriving in the upstream format
+// from voice, as identified by levelKey.
+func (s *Server) newPipeline(upstream elevenlabs.OutputFormat, voice string) (*audioPipeline, error) {
+	p := &audioPipeline{upstream: upstream}
+	in, ok := upstreamAudioFormat(upstream)
+	if s.cfg.TrimSilence && ok {
+		threshold := config.DefaultSilenceThresholdDB
+		if s.cfg.SilenceThresholdDB != nil {
+			threshold = *s.cfg.SilenceThresholdDB
+		}
+		padding := time.Duration(s.cfg.SilencePaddingMs) * time.Millisecond
+		trimmer, err := audio.NewSilenceTrimmer(in, threshold, padding)
+		if err != nil {
+			return nil, err
+		}
+		p.trimmer = trimmer
+	}
+	if (s.cfg.LoudnessNormalization || s.cfg.FadeMs > 0) && ok {
+		opts := audio.LevelerOptions{
+			Normalize:  s.cfg.LoudnessNormalization,
+			TargetLUFS: config.DefaultLoudnessTargetLUFS,
+			MaxGainDB:  config.DefaultLoudnessMaxGainDB,
+			Fade:       time.Duration(s.cfg.FadeMs) * time.Millisecond,
+		}
+		if s.cfg.LoudnessTargetLUFS != nil {
+			opts.TargetLUFS = *s.cfg.LoudnessTargetLUFS
+		}
+		if s.cfg.LoudnessMaxGainDB != nil {
+			opts.MaxGainDB = *s.cfg.LoudnessMaxGainDB
+		}
+		if s.levels != nil {
+			if level, known := s.levels.get(voice); known {
+				opts.InitialGainDB = opts.TargetLUFS - level
+			}
+		}
+		leveler, err := audio.NewLeveler(in, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.leveler = leveler
+	}
+	if s.cfg.OutputSampleRate == 0 && s.cfg.OutputChannels == 0 && s.cfg.OutputEncoding == "" {
+		return p, nil
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("local audio conversion requires a PCM or G.711 output format, got %q", upstream.Name)
+	}
+	out := in
+	if s.cfg.OutputSampleRate > 0 {
+		out.SampleRate = s.cfg.OutputSampleRate
+	}
+	if s.cfg.OutputChannels > 0 {
+		out.Channels = s.cfg.OutputChannels
+	}
+	if s.cfg.OutputEncoding != "" {
+		out.Encoding = audio.Encoding(s.cfg.OutputEncoding)
+	}
+	if out == in {
+		return p, nil
+	}
+
+	converter, err := audio.NewConverter(in, out)
+	if err != nil {
+		return nil, err
+	}
+	p.converter = converter
+	p.out = out
+	return p, nil
+}
+
+// upstreamAudioFormat maps an ElevenLabs output format onto the formats the
+// audio package can process. Compressed codecs are not supported.
+func upstreamAudioFormat(f elevenlabs.OutputFormat) (audio.Format, bool) {
+	var enc audio.Encoding
+	switch f.Codec {
+	case elevenlabs.CodecPCM:
+		enc = audio.EncodingPCM16
+	case elevenlabs.CodecULaw:
+		enc = audio.EncodingMuLaw
+	case elevenlabs.CodecALaw:
+		enc = audio.EncodingALaw
+	default:
+		return audio.Format{}, false
+	}
+	return audio.Format{Encoding: enc, SampleRate: f.SampleRate, Channels: defaultChannels}, true
+}
+
+// process trims, levels and converts upstream audio; the result may be
+// empty while a stage buffers input.
+func (p *audioPipeline) process(data []byte) []byte {
+	if p.trimmer != nil {
+		data = p.trimmer.Process(data)
+	}
+	if p.leveler != nil {
+		data = p.leveler.Process(data)
+	}
+	if p.converter == nil {
+		return append([]byte(nil), data...)
+	}
+	return p.converter.Process(data)
+}
+
+// flush returns audio still held by the pipeline at the end of the stream.
+func (p *audioPipeline) flush() []byte {
+	var data []byte
+	if p.trimmer != nil {
+		data = p.trimmer.Flush()
+	}
+	if p.leveler != nil {
+		data = append(p.leveler.Process(data), p.leveler.Flush()...)
+	}
+	if p.converter == nil {
+		return data
+	}
+	return append(p.converter.Process(data), p.converter.Flush()...)
+}
+
+// shift returns how much later than in the upstream audio a point of it is
+// delivered, once leading silence has been trimmed.
+func (p *audioPipeline) shift() time.Duration {
+	if p.trimmer == nil {
+		return 0
+	}
+	return p.trimmer.Shift()
+}
+
+// loudness returns the loudness of the upstream audio measured so far, in
+// LUFS, when it is normalized.
+func (p *audioPipeline) loudness() (float64, bool) {
+	if p.leveler == nil {
+		return 0, false
+	}
+	return p.leveler.Loudness()
+}
+
+// sampleBytes returns the size of one sample across all channels of the
+// delivered audio, or 0 for compressed audio.
+func (p *audioPipeline) sampleBytes() int {
+	if p.converter != nil {
+		return p.out.FrameSize()
+	}
+	switch p.upstream.Codec {
+	case elevenlabs.CodecPCM:
+		return 2 * defaultChannels
+	case elevenlabs.CodecULaw, elevenlabs.CodecALaw:
+		return defaultChannels
+	default:
+		return 0
+	}
+}
+
+// sampleRate returns the sample rate of the delivered audio.
+func (p *audioPipeline) sampleRate() int {
+	if p.converter != nil {
+		return p.out.SampleRate
+	}
+	return p.upstream.SampleRate
+}
+
+// duration returns the playback duration of n delivered bytes.
+func (p *audioPipeline) duration(n int) time.Duration {
+	if p.converter == nil {
+		return p.upstream.Duration(n)
+	}
+	return p.out.Duration(n)
+}
+
+// describe records how to decode the delivered audio in metadata.
+func (p *audioPipeline) describe(metadata map[string]string) {
+	metadata["output_format"] = p.upstream.Name
+	if p.converter == nil {
+		metadata["encoding"] = p.upstream.Encoding()
+		metadata["sample_rate"] = strconv.Itoa(p.upstream.SampleRate)
+		metadata["channels"] = strconv.Itoa(defaultChannels)
+		return
+	}
+	metadata["encoding"] = string(p.out.Encoding)
+	metadata["sample_rate"] = strconv.Itoa(p.out.SampleRate)
+	metadata["channels"] = strconv.Itoa(p.out.Channels)
+}
+
+// chunkEmitter sends processed audio to the client as numbered AudioChunks.
+// The live and cache paths share it so both produce identical chunking.
+type chunkEmitter struct {
+	stream   napv1.TextToSpeechService_StreamSynthesisServer
+	pipeline *audioPipeline
+	framer   *framer
+	metadata map[string]string
+	log      *slog.Logger
+
+	sequence uint64
+	bytes    int
+
+	// With paced set, chunks are sent no more than lead ahead of their
+	// playback time, counted from the first chunk at pacedSince.
+	paced      bool
+	lead       time.Duration
+	pacedSince time.Time
+
+	// Character timings on the utterance's timeline; those from sentTimings
+	// on are attached to the next chunk sent.
+	timings     []elevenlabs.CharacterTiming
+	sentTimings int
+
+	// Viseme events queued for the next chunk.
+	visemes []viseme.Event
+}
+
+// align queues character timings for the next chunk.
+func (e *chunkEmitter) align(timings ...elevenlabs.CharacterTiming) {
+	e.timings = append(e.timings, timings...)
+}
+
+// viseme queues viseme events for the next chunk.
+func (e *chunkEmitter) viseme(events ...viseme.Event) {
+	e.visemes = append(e.visemes, events...)
+}
+
+// delivered returns the duration of the audio sent so far.
+func (e *chunkEmitter) delivered() time.Duration {
+	return e.pipeline.duration(e.bytes)
+}
+
+// position returns the point of the upstream audio reached by the audio
+// sent so far.
+func (e *chunkEmitter) position() time.Duration {
+	return e.delivered() - e.pipeline.shift()
+}
+
+// place moves timings from the upstream audio onto the delivered audio,
+// where leading silence may have been trimmed.
+func (e *chunkEmitter) place(timings []elevenlabs.CharacterTiming) []elevenlabs.CharacterTiming {
+	placed := shiftTimings(timings, e.pipeline.shift())
+	for i := range placed {
+		placed[i].Start = max(placed[i].Start, 0)
+		placed[i].End = max(placed[i].End, 0)
+	}
+	return placed
+}
+
+// pace waits until the next chunk is due. It returns the context's error when
+// ctx is done first.
+func (e *chunkEmitter) pace(ctx context.Context) error {
+	if !e.paced {
+		return nil
+	}
+	if e.pacedSince.IsZero() {
+		e.pacedSince = time.Now()
+		return nil
+	}
+	wait := time.Until(e.pacedSince.Add(e.delivered() - e.lead))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// emit processes upstream audio and sends the result as frames of the
+// configured duration, carrying a partial frame over to the next call. When
+// last is set the pipeline and the framer are flushed and the final frame is
+// marked Last; if no audio is left, that is an empty frame. Otherwise nothing
+// is sent when processing yields no complete frame.
+func (e *chunkEmitter) emit(upstream []byte, last bool) error {
+	data := e.pipeline.process(upstream)
+	if last {
+		data = append(data, e.pipeline.flush()...)
+	}
+	frames := e.framer.frames(data, last)
+	if last && len(frames) == 0 {
+		// The audio ended on a frame boundary before the end of the stream
+		// was known.
+		frames = [][]byte{{}}
+	}
+	for i, frame := range frames {
+		if err := e.send(frame, last && i == len(frames)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drain flushes the pipeline and sends the audio held back for an incomplete
+// frame, so that all audio synthesized before a failure is delivered, faded
+// out like a complete utterance.
+func (e *chunkEmitter) drain() error {
+	for _, frame := range e.framer.frames(e.pipeline.flush(), true) {
+		if err := e.send(frame, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send paces and sends one frame, attaching the queued timings and visemes
+// that start within it.
+func (e *chunkEmitter) send(data []byte, last bool) error {
+	if err := e.pace(e.stream.Context()); err != nil {
+		return err
+	}
+
+	start := e.delivered()
+	e.sequence++
+	e.bytes += len(data)
+	end := e.delivered()
+
+	metadata := make(map[string]string, len(e.metadata))
+	for k, v := range e.metadata {
+		metadata[k] = v
+	}
+	shift := e.pipeline.shift()
+	n := e.sentTimings
+	for n < len(e.timings) && (last || e.timings[n].Start+shift < end) {
+		n++
+	}
+	if n > e.sentTimings {
+		metadata[metaAlignment] = string(encodeAlignment(e.place(e.timings[e.sentTimings:n])))
+		e.sentTimings = n
+	}
+	n = 0
+	for n < len(e.visemes) && (last || e.visemes[n].Start+shift < end) {
+		n++
+	}
+	if n > 0 {
+		events := make([]viseme.Event, n)
+		for i, ev := range e.visemes[:n] {
+			ev.Start = max(ev.Start+shift, 0)
+			ev.End = max(ev.End+shift, 0)
+			events[i] = ev
+		}
+		metadata[metaVisemes] = string(encodeVisemes(events))
+		e.visemes = e.visemes[n:]
+	}
+	// Durations are differences of cumulative times, so their sum never
+	// drifts from the delivered audio.
+	durationMs := uint32(end.Milliseconds() - start.Milliseconds())
+
+	resp := &napv1.SynthesisResponse{
+		Status: napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING,
+		Chunk: &napv1.AudioChunk{
+			Data:       data,
+			Sequence:   e.sequence,
+			DurationMs: durationMs,
+			First:      e.sequence == 1,
+			Last:       last,
+			Metadata:   metadata,
+		},
+	}
+	if err := e.stream.Send(resp); err != nil {
+		e.log.Error("failed to send audio chunk", "error", err, "sequence", e.sequence)
+		return err
+	}
+
+	e.log.Debug("sent audio chunk",
+		"sequence", e.sequence,
+		"bytes", len(data),
+		"duration_ms", durationMs,
+	)
+	return nil
+}
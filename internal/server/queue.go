@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
+)
+
+// metaPriority orders a request in the upstream queue; higher goes first,
+// the default is 0.
+const metaPriority = "nupi.priority"
+
+// Metadata keys of the STARTED statuses reporting a queued request.
+const (
+	metaQueued        = "queued"
+	metaQueuePosition = "queue_position"
+)
+
+// errorKindQueueTimeout marks a request rejected after waiting in the queue.
+const errorKindQueueTimeout = "queue_timeout"
+
+// requestPriority returns the request's queue priority.
+func requestPriority(metadata map[string]string) (int, error) {
+	v := strings.TrimSpace(metadata[metaPriority])
+	if v == "" {
+		return 0, nil
+	}
+	priority, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid integer %q", metaPriority, v)
+	}
+	return priority, nil
+}
+
+// lockedStream serializes sends on a stream, so producers can report on
+// the call while the streaming loop sends audio.
+type lockedStream struct {
+	napv1.TextToSpeechService_StreamSynthesisServer
+	mu sync.Mutex
+}
+
+func (l *lockedStream) Send(resp *napv1.SynthesisResponse) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.TextToSpeechService_StreamSynthesisServer.Send(resp)
+}
@@ -0,0 +1,386 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/cache"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+)
+
+// maxContextChars bounds the previous_text and next_text sent with a part.
+const maxContextChars = 500
+
+// segmentJob is one part of a request, synthesized ahead of playback.
+type segmentJob struct {
+	text         string
+	previousText string // end of the preceding part
+	nextText     string // start of the following part
+	cacheKey     string
+	alignmentKey string // set when character timings are requested
+	audio        *segmentAudio
+
+	// previousRequestIDs stitches the part to earlier generations.
+	previousRequestIDs []string
+
+	// priority orders the upstream request in the admission queue; queued,
+	// when set, is told the queue position while the request waits.
+	priority int
+	queued   func(position int)
+
+	// Set by the producer before the audio is finished.
+	cached    bool
+	requestID string // of the upstream generation, if reported
+}
+
+// contextBefore returns the end of the part preceding another, at most
+// maxContextChars long and starting at a word.
+func contextBefore(part string) string {
+	if utf8.RuneCountInString(part) <= maxContextChars {
+		return part
+	}
+	runes := []rune(part)
+	tail := string(runes[len(runes)-maxContextChars:])
+	if i := strings.IndexAny(tail, " \t\n"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
+
+// contextAfter returns the start of the part following another, at most
+// maxContextChars long and ending at a word.
+func contextAfter(part string) string {
+	if utf8.RuneCountInString(part) <= maxContextChars {
+		return part
+	}
+	head := string([]rune(part)[:maxContextChars])
+	if i := strings.LastIndexAny(head, " \t\n"); i >= 0 {
+		head = head[:i]
+	}
+	return head
+}
+
+// segmentError records at which stage synthesis of a segment failed.
+type segmentError struct {
+	stage string // "synthesis not admitted", "synthesis failed" or "stream read error"
+	err   error
+}
+
+func (e *segmentError) Error() string { return e.stage + ": " + e.err.Error() }
+func (e *segmentError) Unwrap() error { return e.err }
+
+// segmentAudio buffers the upstream audio of one segment. A producer appends
+// to it while the streaming loop reads it in order, possibly much later.
+// With a window set, the producer waits while that much audio is buffered
+// beyond the furthest reader. Unless the segment is shared, audio is dropped
+// once read, so only the unread part of it stays in memory; the owner of a
+// shared segment drops audio once all its readers are past it.
+type segmentAudio struct {
+	window int
+	shared bool // read by several readers, dropped only by drop
+
+	mu       sync.Mutex
+	data     []byte // audio from offset base on
+	base     int
+	timings  []elevenlabs.CharacterTiming // relative to the segment's start
+	consumed int                          // furthest offset read
+	done     bool
+	err      error
+	changed  chan struct{} // closed and replaced on every update
+}
+
+// newSegmentAudio returns an empty segment buffering at most window bytes
+// ahead of its readers, or any amount when window is 0.
+func newSegmentAudio(window int) *segmentAudio {
+	return &segmentAudio{window: window, changed: make(chan struct{})}
+}
+
+// newSharedAudio is like newSegmentAudio but keeps the audio until drop is
+// called, for readers at different offsets.
+func newSharedAudio(window int) *segmentAudio {
+	a := newSegmentAudio(window)
+	a.shared = true
+	return a
+}
+
+// append adds audio and the timings of the characters it covers. Timings
+// must be added no later than their audio.
+func (a *segmentAudio) append(p []byte, timings []elevenlabs.CharacterTiming) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.data = append(a.data, p...)
+	a.timings = append(a.timings, timings...)
+	a.notify()
+}
+
+// fill sets the complete audio of the segment at once, so the last chunk
+// read from it is known to be the last.
+func (a *segmentAudio) fill(data []byte, timings []elevenlabs.CharacterTiming) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.data = data
+	a.timings = timings
+	a.done = true
+	a.notify()
+}
+
+// finish marks the segment complete, or failed when err is set.
+func (a *segmentAudio) finish(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.done = true
+	a.err = err
+	a.notify()
+}
+
+// wait blocks while the window is full, so the producer advances no faster
+// than the audio is read. It returns the context's error when ctx is done
+// first.
+func (a *segmentAudio) wait(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		if a.window <= 0 || a.base+len(a.data)-a.consumed < a.window {
+			a.mu.Unlock()
+			return nil
+		}
+		changed := a.changed
+		a.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// drop releases the audio before offset, which no reader will read again.
+func (a *segmentAudio) drop(offset int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n := offset - a.base; n > 0 {
+		a.data = a.data[n:]
+		a.base = offset
+	}
+}
+
+// dropped reports whether audio from the start of the segment was dropped.
+func (a *segmentAudio) dropped() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.base > 0
+}
+
+// alignment returns the character timings received so far.
+func (a *segmentAudio) alignment() []elevenlabs.CharacterTiming {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.timings[:len(a.timings):len(a.timings)]
+}
+
+// size returns the number of bytes buffered and whether the segment is
+// complete.
+func (a *segmentAudio) size() (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.base + len(a.data), a.done && a.err == nil
+}
+
+func (a *segmentAudio) notify() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// read returns up to max bytes starting at offset, waiting until audio is
+// available. done reports that the returned bytes end the segment. The
+// segment's error is returned only after all audio before it was read.
+// Unless the segment is shared, offsets must not go back.
+func (a *segmentAudio) read(ctx context.Context, offset, max int) ([]byte, bool, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		a.mu.Lock()
+		if available := a.base + len(a.data) - offset; available > 0 {
+			n := min(available, max)
+			// Appends never modify bytes already in data, and dropping read
+			// bytes only reslices it, so the slice stays valid after the lock
+			// is released.
+			start := offset - a.base
+			data := a.data[start : start+n]
+			done := a.done && a.err == nil && available == n
+			if !a.shared {
+				// The backing array is freed once appends outgrow it.
+				a.data = a.data[start+n:]
+				a.base = offset + n
+			}
+			if offset+n > a.consumed {
+				a.consumed = offset + n
+				a.notify()
+			}
+			a.mu.Unlock()
+			return data, done, nil
+		}
+		if a.done {
+			err := a.err
+			a.mu.Unlock()
+			return nil, err == nil, err
+		}
+		changed := a.changed
+		a.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		}
+	}
+}
+
+// synthesizeSegment fills job.audio from the cache or from ElevenLabs, via
+// the flight synthesizing the same part.
+func (s *Server) synthesizeSegment(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest, job *segmentJob, log *slog.Logger) {
+	if s.cache != nil {
+		if data, timings, ok := s.cachedSegment(job); ok {
+			log.Info("cache hit", "key", job.cacheKey)
+			job.cached = true
+			job.audio.fill(data, timings)
+			return
+		}
+		log.Debug("cache miss", "key", job.cacheKey)
+	}
+
+	// Identical parts of concurrent requests share one upstream request.
+	key := flightKey(job)
+	fl, upstream := s.flights.join(ctx, key, job)
+	defer s.flights.leave(key, fl, job)
+	if upstream != nil {
+		go s.fly(upstream, voiceID, req, job, key, fl, log)
+	} else {
+		log.Info("joined in-flight synthesis", "key", key)
+	}
+	s.flights.relay(ctx, fl, job)
+}
+
+// flightKey identifies the upstream request of job among concurrent ones.
+// Unlike the cache key it includes the generations the part is stitched to,
+// so a part continuing one session's conversation is never shared with
+// another session.
+func flightKey(job *segmentJob) string {
+	key := cmp.Or(job.alignmentKey, job.cacheKey)
+	if len(job.previousRequestIDs) > 0 {
+		key += "/" + strings.Join(job.previousRequestIDs, ",")
+	}
+	return key
+}
+
+// fly synthesizes job upstream into the audio of fl, on behalf of all its
+// subscribers, and stores the audio in the cache.
+func (s *Server) fly(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest, job *segmentJob, key string, fl *flight, log *slog.Logger) {
+	defer fl.cancel()
+	defer s.flights.land(key, fl)
+
+	if s.admission != nil {
+		release, err := s.admission.Acquire(ctx, job.priority, func(position int) {
+			s.flights.queued(fl, position)
+		})
+		if err != nil {
+			fl.audio.finish(&segmentError{stage: "synthesis not admitted", err: err})
+			return
+		}
+		defer release()
+	}
+
+	req.Text = job.text
+	req.PreviousText = job.previousText
+	req.NextText = job.nextText
+	req.PreviousRequestIDs = job.previousRequestIDs
+	audioStream, err := s.client.SynthesizeStream(ctx, voiceID, req)
+	if err != nil {
+		fl.audio.finish(&segmentError{stage: "synthesis failed", err: err})
+		return
+	}
+	defer audioStream.Close()
+
+	// The audio is written to the cache as it arrives; the entry appears
+	// only once the synthesis completed.
+	var entry *cache.Writer
+	if s.cache != nil {
+		if entry, err = s.cache.Writer(job.cacheKey); err != nil {
+			log.Warn("failed to store in cache", "error", err)
+		} else {
+			defer entry.Abort()
+		}
+	}
+
+	buffer := make([]byte, chunkSize)
+	seen := 0 // timings already passed to fl.audio
+	for {
+		if err := fl.audio.wait(ctx); err != nil {
+			fl.audio.finish(&segmentError{stage: "stream read error", err: err})
+			return
+		}
+		n, err := audioStream.Read(buffer)
+		var timings []elevenlabs.CharacterTiming
+		if all := elevenlabs.Alignment(audioStream); len(all) > seen {
+			timings = all[seen:]
+			seen = len(all)
+		}
+		if n > 0 || len(timings) > 0 {
+			fl.audio.append(buffer[:n], timings)
+		}
+		if entry != nil && n > 0 {
+			if _, err := entry.Write(buffer[:n]); err != nil {
+				log.Warn("failed to store in cache", "error", err)
+				entry.Abort()
+				entry = nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fl.audio.finish(&segmentError{stage: "stream read error", err: err})
+			return
+		}
+	}
+
+	// The entry is committed before the audio is finished, so a request
+	// arriving once the flight has landed finds it in the cache. Timings are
+	// stored only with their audio.
+	if size, _ := fl.audio.size(); entry != nil && size > 0 {
+		if err := entry.Commit(); err != nil {
+			log.Warn("failed to store in cache", "error", err)
+		} else if job.alignmentKey != "" {
+			if err := s.cache.Put(job.alignmentKey, encodeAlignment(fl.audio.alignment())); err != nil {
+				log.Warn("failed to store alignment in cache", "error", err)
+			}
+		}
+	}
+	fl.requestID = elevenlabs.RequestID(audioStream)
+	fl.audio.finish(nil)
+}
+
+// cachedSegment returns the cached audio of job and, when timestamps are
+// requested, its character timings. Audio without its timings is a miss.
+func (s *Server) cachedSegment(job *segmentJob) ([]byte, []elevenlabs.CharacterTiming, bool) {
+	data, ok := s.cache.Get(job.cacheKey)
+	if !ok || job.alignmentKey == "" {
+		return data, nil, ok
+	}
+	encoded, ok := s.cache.Get(job.alignmentKey)
+	if !ok {
+		return nil, nil, false
+	}
+	timings, err := decodeAlignment(encoded)
+	if err != nil {
+		return nil, nil, false
+	}
+	return data, timings, true
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSegmentAudioDropsReadAudio(t *testing.T) {
+	ctx := context.Background()
+	a := newSegmentAudio(0)
+	a.append(make([]byte, 100), nil)
+
+	data, done, err := a.read(ctx, 0, 60)
+	if err != nil || done || len(data) != 60 {
+		t.Fatalf("read = %d bytes, %v, %v; want 60 bytes, not done", len(data), done, err)
+	}
+	if len(a.data) != 40 {
+		t.Errorf("buffered %d bytes after reading 60 of 100, want 40", len(a.data))
+	}
+
+	a.append(make([]byte, 20), nil)
+	a.finish(nil)
+	if size, _ := a.size(); size != 120 {
+		t.Errorf("size = %d, want 120", size)
+	}
+	data, done, err = a.read(ctx, 60, 100)
+	if err != nil || !done || len(data) != 60 {
+		t.Fatalf("read = %d bytes, %v, %v; want the last 60 bytes", len(data), done, err)
+	}
+	if len(a.data) != 0 {
+		t.Errorf("buffered %d bytes after reading everything, want 0", len(a.data))
+	}
+}
+
+func TestSharedAudioDropsOnlyOnRequest(t *testing.T) {
+	ctx := context.Background()
+	a := newSharedAudio(0)
+	a.append(make([]byte, 100), nil)
+	a.finish(nil)
+
+	// A second reader still gets the audio from the start.
+	for range 2 {
+		data, done, err := a.read(ctx, 0, 100)
+		if err != nil || !done || len(data) != 100 {
+			t.Fatalf("read = %d bytes, %v, %v; want all 100 bytes", len(data), done, err)
+		}
+	}
+	if a.dropped() {
+		t.Error("dropped before drop was called")
+	}
+
+	a.drop(60)
+	if !a.dropped() || len(a.data) != 40 {
+		t.Errorf("buffered %d bytes after dropping 60 of 100, want 40", len(a.data))
+	}
+	data, done, err := a.read(ctx, 60, 100)
+	if err != nil || !done || len(data) != 40 {
+		t.Fatalf("read = %d bytes, %v, %v; want the last 40 bytes", len(data), done, err)
+	}
+}
@@ -1,26 +1,47 @@
 package server
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
 
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/adapterinfo"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/admission"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/cache"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/lexicon"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/segment"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/ssml"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/telemetry"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/textnorm"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/viseme"
 )
 
 const (
-	defaultSampleRate = 16000
-	defaultChannels   = 1
-	defaultBitDepth   = 16
-	chunkSize         = 4096 // bytes per chunk (~128ms at 16kHz mono PCM16)
+	defaultChannels = 1
+	chunkSize       = 4096 // upstream bytes framed at a time (~128ms at 16kHz mono PCM16)
+
+	// metaOutputFormat overrides the configured output format per request.
+	metaOutputFormat = "elevenlabs.output_format"
+
+	// metaTextFormat marks the request text as textFormatPlain (default) or
+	// textFormatSSML.
+	metaTextFormat  = "nupi.text_format"
+	textFormatPlain = "plain"
+	textFormatSSML  = "ssml"
 )
 
 // Server implements the TextToSpeechService and synthesizes audio via ElevenLabs.
@@ -32,10 +53,37 @@ type Server struct {
 	client  elevenlabs.Synthesizer
 	metrics *telemetry.Recorder
 	cache   *cache.Cache // nil when caching is disabled
+
+	normalizer *textnorm.Normalizer
+	history    *sessionHistory // nil when request stitching is disabled
+	levels     *voiceLevels    // nil unless loudness is normalized
+	sessions   *duplexSessions
+	flights    *flights
+	admission  *admission.Controller // nil without a limit on upstream requests
+
+	// streaming receives duplex text as it is appended; nil unless the
+	// WebSocket backend is configured.
+	streaming elevenlabs.StreamingSynthesizer
+
+	lexicon    *lexicon.Lexicon                           // nil when no lexicon is configured
+	dictionary *elevenlabs.PronunciationDictionaryLocator // set when the lexicon was uploaded
+}
+
+// Option configures optional Server features.
+type Option func(*Server)
+
+// WithLexicon applies a pronunciation lexicon to every request. With a nil
+// dictionary the request text is rewritten locally; otherwise the uploaded
+// dictionary is referenced and the text is sent unchanged.
+func WithLexicon(lex *lexicon.Lexicon, dictionary *elevenlabs.PronunciationDictionaryLocator) Option {
+	return func(s *Server) {
+		s.lexicon = lex
+		s.dictionary = dictionary
+	}
 }
 
 // New returns a new Server instance.
-func New(cfg config.Config, logger *slog.Logger, client elevenlabs.Synthesizer, metrics *telemetry.Recorder, audioCache *cache.Cache) *Server {
+func New(cfg config.Config, logger *slog.Logger, client elevenlabs.Synthesizer, metrics *telemetry.Recorder, audioCache *cache.Cache, opts ...Option) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -45,17 +93,37 @@ func New(cfg config.Config, logger *slog.Logger, client elevenlabs.Synthesizer,
 	if metrics == nil {
 		metrics = telemetry.NewRecorder(logger)
 	}
-	return &Server{
-		cfg: cfg,
-		log: logger.With(
-			"component", "server",
-			"model", cfg.Model,
-			"voice_id", cfg.VoiceID,
-		),
-		client:  client,
-		metrics: metrics,
-		cache:   audioCache,
+	rules, err := textnorm.ParseRules(cfg.TextNormalization)
+	if err != nil {
+		logger.Warn("invalid text normalization rules, normalization disabled", "error", err)
+	}
+	s := &Server{
+		cfg:        cfg,
+		log:        logger.With("component", "server"),
+		client:     client,
+		metrics:    metrics,
+		cache:      audioCache,
+		normalizer: textnorm.New(rules),
+		sessions:   newDuplexSessions(),
+		flights:    newFlights(),
+	}
+	if streaming, ok := client.(elevenlabs.StreamingSynthesizer); ok && cfg.Backend == config.BackendWebSocket {
+		s.streaming = streaming
 	}
+	if cfg.MaxConcurrentRequests > 0 {
+		timeout := time.Duration(cmp.Or(cfg.QueueTimeoutMs, config.DefaultQueueTimeoutMs)) * time.Millisecond
+		s.admission = admission.New(cfg.MaxConcurrentRequests, timeout)
+	}
+	if cfg.LoudnessNormalization {
+		s.levels = newVoiceLevels(maxLevelVoices)
+	}
+	if cfg.RequestStitching {
+		s.history = newSessionHistory(time.Duration(cfg.RequestStitchingTTLSec)*time.Second, maxStitchingSessions, time.Now)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // StreamSynthesis accepts a text synthesis request and streams back audio chunks.
@@ -76,13 +144,54 @@ func (s *Server) StreamSynthesis(req *napv1.StreamSynthesisRequest, stream napv1
 
 	if text == "" {
 		logEntry.Warn("empty text in synthesis request")
-		return s.sendError(stream, "text is required")
+		return s.sendInvalidRequest(stream, "text is required")
 	}
 
 	// Resolve language from config mode and request metadata.
 	resolvedLang := resolveLanguage(s.cfg.Language, req.GetMetadata())
 	logEntry = logEntry.With("language", resolvedLang)
 
+	params, err := resolveSynthesisParams(s.cfg, req.GetMetadata())
+	if err != nil {
+		logEntry.Warn("invalid synthesis override", "error", err)
+		return s.sendInvalidRequest(stream, err.Error())
+	}
+	logEntry = logEntry.With("voice_id", params.VoiceID, "model", params.Model)
+
+	priority, err := requestPriority(req.GetMetadata())
+	if err != nil {
+		logEntry.Warn("invalid priority", "error", err)
+		return s.sendInvalidRequest(stream, err.Error())
+	}
+
+	format, err := resolveOutputFormat(s.cfg.OutputFormat, req.GetMetadata())
+	if err != nil {
+		logEntry.Warn("invalid output format", "error", err)
+		return s.sendInvalidRequest(stream, err.Error())
+	}
+	logEntry = logEntry.With("output_format", format.Name)
+
+	voice := levelKey(params.VoiceID, params.Model)
+	pipeline, err := s.newPipeline(format, voice)
+	if err != nil {
+		logEntry.Warn("unsupported audio processing", "error", err)
+		return s.sendInvalidRequest(stream, err.Error())
+	}
+	if s.levels != nil {
+		// Whatever was heard of the voice informs its next utterance.
+		defer func() {
+			if level, ok := pipeline.loudness(); ok {
+				s.levels.record(voice, level)
+			}
+		}()
+	}
+
+	spoken, err := s.prepareText(text, resolvedLang, req.GetMetadata())
+	if err != nil {
+		logEntry.Warn("invalid request text", "error", err)
+		return s.sendInvalidRequest(stream, err.Error())
+	}
+
 	logEntry.Info("synthesis request received")
 
 	// Send STARTED status
@@ -91,213 +200,368 @@ func (s *Server) StreamSynthesis(req *napv1.StreamSynthesisRequest, stream napv1
 		return err
 	}
 
-	// Build synthesis request
-	synthesisReq := elevenlabs.SynthesizeRequest{
-		Text:    text,
-		ModelID: s.cfg.Model,
+	if spoken == "" {
+		logEntry.Info("nothing left to speak after text normalization")
+		return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, map[string]string{
+			"text_length": fmt.Sprintf("%d", len(text)),
+			"source":      "normalization",
+		})
 	}
 
-	// Pass language_code to ElevenLabs when a specific language is resolved.
-	// "auto" means let ElevenLabs auto-detect, so we omit the field.
-	if resolvedLang != "auto" {
-		synthesisReq.LanguageCode = resolvedLang
+	// The synthesis request shared by all segments; each sets its own Text.
+	synthesisReq := s.synthesisRequest(params, format, resolvedLang)
+
+	// A duplex utterance may have been sent upstream as it was appended;
+	// its audio is then read from that stream, as a single part.
+	var input *duplexInput
+	if u, ok := stream.(*utteranceStream); ok {
+		input = u.utterance.input
 	}
 
-	// Apply voice settings if configured
-	if s.cfg.Stability != nil || s.cfg.SimilarityBoost != nil {
-		synthesisReq.VoiceSettings = &elevenlabs.VoiceSettings{
-			Stability:       s.cfg.Stability,
-			SimilarityBoost: s.cfg.SimilarityBoost,
+	// Long texts are synthesized sentence by sentence so playback can start
+	// after the first sentence while the following ones are prefetched. Parts
+	// over the model's character limit are split further in any case.
+	sentences := []string{spoken}
+	if s.cfg.SentenceSegmentation && input == nil {
+		if split := segment.Split(spoken, resolvedLang, segment.DefaultMinChars); len(split) > 0 {
+			sentences = split
 		}
 	}
-
-	// Apply latency optimization if configured
-	if s.cfg.OptimizeStreamingLatency != nil {
-		synthesisReq.OptimizeStreamingLatency = s.cfg.OptimizeStreamingLatency
+	limit := elevenlabs.MaxTextLength(params.Model)
+
+	keyParams := cache.KeyParams{
+		Model:                    params.Model,
+		VoiceID:                  params.VoiceID,
+		LanguageCode:             resolvedLang,
+		OutputFormat:             format.Name,
+		Stability:                params.Stability,
+		SimilarityBoost:          params.SimilarityBoost,
+		OptimizeStreamingLatency: params.OptimizeStreamingLatency,
+		Style:                    params.Style,
+		UseSpeakerBoost:          params.UseSpeakerBoost,
+		Speed:                    params.Speed,
+		Lexicon:                  s.lexiconVersion(),
 	}
-
-	// Compute cache key
-	var cacheKey string
-	if s.cache != nil {
-		cacheKey = cache.Key(text, s.cfg.Model, s.cfg.VoiceID, resolvedLang, s.cfg.Stability, s.cfg.SimilarityBoost, s.cfg.OptimizeStreamingLatency)
+	// With paced delivery upstream audio is read at most the lead ahead of
+	// the audio sent, so cancelling stops the upstream requests promptly.
+	lead := time.Duration(s.cfg.PacingLeadMs) * time.Millisecond
+	window := 0
+	if s.cfg.PacedDelivery {
+		window = max(int(int64(format.BytesPerSecond())*int64(lead)/int64(time.Second)), chunkSize)
 	}
-
-	// Cache hit path
-	if s.cache != nil {
-		if data, ok := s.cache.Get(cacheKey); ok {
-			logEntry.Info("cache hit", "key", cacheKey)
-			return s.streamFromBytes(data, text, stream, logEntry)
+	var jobs []*segmentJob
+	for _, sentence := range sentences {
+		// The parts of a sentence split for the character limit are sent
+		// with their neighbours as context, so intonation carries across
+		// them. Whole sentences are not: their audio then depends on their
+		// own text only and is cached on its own.
+		parts := []string{sentence}
+		if input == nil {
+			parts = segment.Chunk(sentence, resolvedLang, limit)
+		}
+		for i, part := range parts {
+			job := &segmentJob{text: part, audio: newSegmentAudio(window), priority: priority}
+			if i > 0 {
+				job.previousText = contextBefore(parts[i-1])
+			}
+			if i < len(parts)-1 {
+				job.nextText = contextAfter(parts[i+1])
+			}
+			// The keys also identify identical parts of concurrent requests,
+			// so they are needed without a cache too.
+			keyParams.Text = part
+			keyParams.PreviousText = job.previousText
+			keyParams.NextText = job.nextText
+			job.cacheKey = cache.Key(keyParams)
+			if s.requestsTimings() {
+				keyParams.Alignment = true
+				job.alignmentKey = cache.Key(keyParams)
+				keyParams.Alignment = false
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	// The first part continues the session's previous utterance. Request IDs
+	// are unique per generation, so they are not part of the cache key.
+	if s.history != nil && sessionID != "" && input == nil {
+		jobs[0].previousRequestIDs = s.history.previous(sessionID)
+	}
+	logEntry = logEntry.With("segments", len(jobs))
+
+	// Producers report while the first part waits for an upstream slot,
+	// the wait that holds up playback.
+	stream = &lockedStream{TextToSpeechService_StreamSynthesisServer: stream}
+	jobs[0].queued = func(position int) {
+		logEntry.Info("synthesis queued", "queue_position", position, "priority", priority)
+		if err := s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_STARTED, map[string]string{
+			metaQueued:        "true",
+			metaQueuePosition: strconv.Itoa(position),
+		}); err != nil {
+			logEntry.Debug("failed to send queue position", "error", err)
 		}
-		logEntry.Debug("cache miss", "key", cacheKey)
 	}
 
-	ctx := stream.Context()
-	start := time.Now()
+	emitter := &chunkEmitter{
+		stream:   stream,
+		pipeline: pipeline,
+		framer:   newFramer(pipeline.sampleBytes(), pipeline.sampleRate()*cmp.Or(s.cfg.FrameMs, config.DefaultFrameMs)/1000),
+		metadata: chunkMetadata(params, pipeline),
+		log:      logEntry,
+		paced:    s.cfg.PacedDelivery,
+		lead:     lead,
+	}
 
-	// Call ElevenLabs streaming API
-	audioStream, err := s.client.SynthesizeStream(ctx, s.cfg.VoiceID, synthesisReq)
-	if err != nil {
-		logEntry.Error("elevenlabs synthesis failed", "error", err)
-		return s.sendError(stream, fmt.Sprintf("synthesis failed: %v", err))
+	// Producers are cancelled when the handler returns and waited for, so
+	// no upstream request outlives the call.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	concurrency := max(s.cfg.SynthesisConcurrency, 1)
+	started := 0
+	start := time.Now()
+	playing := false
+	cachedSegments := 0
+	var requestIDs []string   // of the trailing parts generated upstream
+	var elapsed time.Duration // upstream audio of the parts already played
+	var played []string       // text of the parts already played
+	var visemes *viseme.Mapper
+	if s.cfg.Visemes {
+		visemes = viseme.NewMapper(resolvedLang)
 	}
-	defer audioStream.Close()
 
-	// Send PLAYING status
-	if err := s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING, nil); err != nil {
-		logEntry.Error("failed to send playing status", "error", err)
-		return err
+	// interrupted reports how far playback got when the client went away
+	// while job was playing.
+	interrupted := func(job *segmentJob, reason error) error {
+		delivered := emitter.delivered()
+		metadata := map[string]string{"reason": reason.Error()}
+		describeInterruption(metadata, played, heardText(partTimings(job, format), emitter.position()-elapsed), delivered)
+		logEntry.Info("synthesis interrupted",
+			"reason", reason,
+			"audio_ms_delivered", delivered.Milliseconds(),
+			"spoken_chars", metadata[metaSpokenChars],
+		)
+		return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, metadata)
 	}
 
-	// Stream audio chunks
-	var sequence uint64
-	buffer := make([]byte, chunkSize)
-	totalBytes := 0
-	var accumulated []byte
-
-	for {
-		select {
-		case <-ctx.Done():
-			logEntry.Info("synthesis interrupted", "reason", ctx.Err())
-			return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, map[string]string{
-				"reason": ctx.Err().Error(),
-			})
-		default:
+	for i, job := range jobs {
+		// Keep up to concurrency segments in flight, starting with this one.
+		for ; started < len(jobs) && started < i+concurrency; started++ {
+			next := jobs[started]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if input != nil {
+					s.relayInput(ctx, input, next)
+					return
+				}
+				s.synthesizeSegment(ctx, params.VoiceID, synthesisReq, next, logEntry)
+			}()
 		}
 
-		n, err := audioStream.Read(buffer)
-		if n > 0 {
-			totalBytes += n
-			sequence++
-
-			chunk := &napv1.AudioChunk{
-				Data:     append([]byte{}, buffer[:n]...),
-				Sequence: sequence,
-				First:    sequence == 1,
-				Last:     err == io.EOF,
-				Metadata: adapterinfo.SynthesisMetadata(s.cfg.Model, s.cfg.VoiceID),
+		aligned := 0                               // timings of this part already queued on the emitter
+		var estimated []elevenlabs.CharacterTiming // stand-in timings for the visemes
+		for offset := 0; ; {
+			data, done, err := job.audio.read(ctx, offset, chunkSize)
+			if err != nil {
+				if ctxErr := stream.Context().Err(); ctxErr != nil {
+					return interrupted(job, ctxErr)
+				}
+				if err := emitter.drain(); err != nil {
+					return err
+				}
+				var segErr *segmentError
+				if errors.As(err, &segErr) {
+					logEntry.Error("elevenlabs synthesis failed", "segment", i, "stage", segErr.stage, "error", segErr.err)
+					return s.sendSynthesisError(stream, segErr.stage, segErr.err)
+				}
+				return s.sendSynthesisError(stream, "synthesis failed", err)
 			}
 
-			// Calculate duration (PCM16, mono, 16kHz)
-			samples := n / 2 // 16-bit = 2 bytes per sample
-			durationMs := uint32((samples * 1000) / defaultSampleRate)
-			chunk.DurationMs = durationMs
+			if !playing {
+				if err := s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING, nil); err != nil {
+					logEntry.Error("failed to send playing status", "error", err)
+					return err
+				}
+				playing = true
+			}
 
-			resp := &napv1.SynthesisResponse{
-				Status: napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING,
-				Chunk:  chunk,
+			if s.cfg.Timestamps || visemes != nil {
+				// Queue the characters whose speech starts in this chunk,
+				// on the utterance's timeline. Visemes fall back to
+				// estimated timings when ElevenLabs reports none: at once
+				// when none were requested, else once the part is done.
+				timings := job.audio.alignment()
+				if len(timings) == 0 && visemes != nil && (done || !s.requestsTimings()) {
+					if estimated == nil {
+						estimated = estimateTimings(job, format)
+					}
+					timings = estimated
+				}
+				end := format.Duration(offset + len(data))
+				n := aligned
+				for n < len(timings) && (done || timings[n].Start < end) {
+					n++
+				}
+				queued := shiftTimings(timings[aligned:n], elapsed)
+				if aligned == 0 && n > 0 && i > 0 {
+					queued = append([]elevenlabs.CharacterTiming{{Char: " ", Start: elapsed, End: elapsed}}, queued...)
+				}
+				aligned = n
+				if s.cfg.Timestamps && estimated == nil {
+					emitter.align(queued...)
+				}
+				if visemes != nil {
+					emitter.viseme(visemes.Push(visemeTimings(queued)...)...)
+					if done && i == len(jobs)-1 {
+						emitter.viseme(visemes.Flush()...)
+					}
+				}
 			}
 
-			if err := stream.Send(resp); err != nil {
-				logEntry.Error("failed to send audio chunk", "error", err, "sequence", sequence)
+			if err := emitter.emit(data, done && i == len(jobs)-1); err != nil {
+				if ctxErr := stream.Context().Err(); ctxErr != nil {
+					return interrupted(job, ctxErr)
+				}
 				return err
 			}
-
-			// Accumulate for cache
-			if s.cache != nil {
-				accumulated = append(accumulated, buffer[:n]...)
+			offset += len(data)
+			if done {
+				elapsed += format.Duration(offset)
+				if text := heardText(partTimings(job, format), wholePart); text != "" {
+					played = append(played, text)
+				}
+				break
 			}
-
-			logEntry.Debug("sent audio chunk",
-				"sequence", sequence,
-				"bytes", n,
-				"duration_ms", durationMs,
-			)
 		}
 
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			logEntry.Error("error reading audio stream", "error", err)
-			return s.sendError(stream, fmt.Sprintf("stream read error: %v", err))
+		if job.cached {
+			cachedSegments++
+		}
+		if job.requestID == "" {
+			requestIDs = nil
+		} else {
+			requestIDs = append(requestIDs, job.requestID)
 		}
+		jobs[i] = nil // release the segment's audio
 	}
 
-	duration := time.Since(start)
-	logEntry.Info("synthesis completed",
-		"total_bytes", totalBytes,
-		"chunks", sequence,
-		"duration_sec", duration.Seconds(),
-	)
+	if s.history != nil && sessionID != "" {
+		s.history.record(sessionID, requestIDs)
+	}
 
-	// Store in cache
-	if s.cache != nil && len(accumulated) > 0 {
-		if err := s.cache.Put(cacheKey, accumulated); err != nil {
-			logEntry.Warn("failed to store in cache", "error", err)
-		}
+	duration := time.Since(start)
+	fromCache := cachedSegments == len(jobs)
+	if fromCache {
+		logEntry.Info("served from cache",
+			"total_bytes", emitter.bytes,
+			"chunks", emitter.sequence,
+		)
+	} else {
+		logEntry.Info("synthesis completed",
+			"total_bytes", emitter.bytes,
+			"chunks", emitter.sequence,
+			"cached_segments", cachedSegments,
+			"duration_sec", duration.Seconds(),
+		)
 	}
 
 	// Send FINISHED status
 	metadata := map[string]string{
-		"total_bytes":  fmt.Sprintf("%d", totalBytes),
-		"total_chunks": fmt.Sprintf("%d", sequence),
-		"duration_sec": fmt.Sprintf("%.2f", duration.Seconds()),
-		"text_length":  fmt.Sprintf("%d", len(text)),
+		"total_bytes":   fmt.Sprintf("%d", emitter.bytes),
+		"total_chunks":  fmt.Sprintf("%d", emitter.sequence),
+		"duration_sec":  fmt.Sprintf("%.2f", duration.Seconds()),
+		"text_length":   fmt.Sprintf("%d", len(text)),
+		"segments":      fmt.Sprintf("%d", len(jobs)),
+		"output_format": format.Name,
+	}
+	if fromCache {
+		metadata["source"] = "cache"
+	}
+	if s.cfg.Timestamps {
+		words, _ := json.Marshal(wordTimings(emitter.place(emitter.timings)))
+		metadata[metaWordAlignment] = string(words)
 	}
 
 	return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, metadata)
 }
 
-// streamFromBytes streams pre-cached audio data using the same chunking logic as the live path.
-func (s *Server) streamFromBytes(data []byte, text string, stream napv1.TextToSpeechService_StreamSynthesisServer, logEntry *slog.Logger) error {
-	// Send PLAYING status
-	if err := s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING, nil); err != nil {
-		return err
+// synthesisRequest returns the request settings for params, format and the
+// resolved language, without text.
+func (s *Server) synthesisRequest(params synthesisParams, format elevenlabs.OutputFormat, lang string) elevenlabs.SynthesizeRequest {
+	req := elevenlabs.SynthesizeRequest{
+		ModelID:                  params.Model,
+		VoiceSettings:            params.voiceSettings(),
+		OptimizeStreamingLatency: params.OptimizeStreamingLatency,
+		OutputFormat:             format.Name,
+		WithTimestamps:           s.requestsTimings(),
 	}
+	if s.dictionary != nil {
+		req.PronunciationDictionaryLocators = []elevenlabs.PronunciationDictionaryLocator{*s.dictionary}
+	}
+	// Pass language_code to ElevenLabs when a specific language is resolved.
+	// "auto" means let ElevenLabs auto-detect, so we omit the field.
+	if lang != "auto" {
+		req.LanguageCode = lang
+	}
+	return req
+}
 
-	var sequence uint64
-	totalBytes := len(data)
-
-	ctx := stream.Context()
-	for offset := 0; offset < len(data); offset += chunkSize {
-		if err := ctx.Err(); err != nil {
-			return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED, map[string]string{
-				"reason": err.Error(),
-			})
-		}
-
-		end := offset + chunkSize
-		if end > len(data) {
-			end = len(data)
-		}
-
-		n := end - offset
-		sequence++
-
-		chunk := &napv1.AudioChunk{
-			Data:     data[offset:end],
-			Sequence: sequence,
-			First:    sequence == 1,
-			Last:     end == len(data),
-			Metadata: adapterinfo.SynthesisMetadata(s.cfg.Model, s.cfg.VoiceID),
-		}
-
-		samples := n / 2
-		chunk.DurationMs = uint32((samples * 1000) / defaultSampleRate)
-
-		resp := &napv1.SynthesisResponse{
-			Status: napv1.SynthesisStatus_SYNTHESIS_STATUS_PLAYING,
-			Chunk:  chunk,
-		}
-
-		if err := stream.Send(resp); err != nil {
-			return err
+// prepareText turns the request text into what is sent to ElevenLabs: SSML
+// is translated to the tags ElevenLabs supports, and the text in between is
+// normalized for lang and rewritten with the lexicon.
+func (s *Server) prepareText(text, lang string, metadata map[string]string) (string, error) {
+	speakable := func(t string) string {
+		return s.applyLexicon(s.normalizer.Normalize(t, lang))
+	}
+	switch format := strings.ToLower(strings.TrimSpace(metadata[metaTextFormat])); format {
+	case "", textFormatPlain:
+		return speakable(text), nil
+	case textFormatSSML:
+		segments, err := ssml.Parse(text)
+		if err != nil {
+			return "", err
 		}
+		return ssml.Render(segments, speakable), nil
+	default:
+		return "", fmt.Errorf("%s: unsupported text format %q", metaTextFormat, format)
 	}
+}
 
-	logEntry.Info("served from cache",
-		"total_bytes", totalBytes,
-		"chunks", sequence,
-	)
+// applyLexicon rewrites text with the configured lexicon unless it was
+// uploaded as a pronunciation dictionary.
+func (s *Server) applyLexicon(text string) string {
+	if s.lexicon == nil || s.dictionary != nil {
+		return text
+	}
+	return s.lexicon.Rewrite(text)
+}
 
-	metadata := map[string]string{
-		"total_bytes":  fmt.Sprintf("%d", totalBytes),
-		"total_chunks": fmt.Sprintf("%d", sequence),
-		"text_length":  fmt.Sprintf("%d", len(text)),
-		"source":       "cache",
+// lexiconVersion identifies how pronunciation is adjusted, for the cache key.
+func (s *Server) lexiconVersion() string {
+	switch {
+	case s.dictionary != nil:
+		return "dictionary:" + s.dictionary.PronunciationDictionaryID + ":" + s.dictionary.VersionID
+	case s.lexicon != nil:
+		return "rewrite:" + s.lexicon.Version
 	}
+	return ""
+}
 
-	return s.sendStatus(stream, napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED, metadata)
+// requestsTimings reports whether character timings are requested from
+// ElevenLabs: for timestamps, and for visemes unless the backend cannot
+// provide them.
+func (s *Server) requestsTimings() bool {
+	return s.cfg.Timestamps || (s.cfg.Visemes && s.cfg.Backend != config.BackendWebSocket)
+}
+
+// chunkMetadata returns the metadata attached to every audio chunk, describing
+// the producing model, voice and settings and how to decode the audio.
+func chunkMetadata(params synthesisParams, pipeline *audioPipeline) map[string]string {
+	metadata := adapterinfo.SynthesisMetadata(params.Model, params.VoiceID, params.settingsMetadata())
+	pipeline.describe(metadata)
+	return metadata
 }
 
 func (s *Server) sendStatus(stream napv1.TextToSpeechService_StreamSynthesisServer, status napv1.SynthesisStatus, metadata map[string]string) error {
@@ -308,15 +572,37 @@ func (s *Server) sendStatus(stream napv1.TextToSpeechService_StreamSynthesisServ
 	return stream.Send(resp)
 }
 
-func (s *Server) sendError(stream napv1.TextToSpeechService_StreamSynthesisServer, message string) error {
+// sendError sends a STATUS_ERROR response carrying message and metadata, and
+// returns the matching gRPC status error to end the call with.
+func (s *Server) sendError(stream napv1.TextToSpeechService_StreamSynthesisServer, code codes.Code, message string, metadata map[string]string) error {
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[metaErrorCode] = code.String()
 	resp := &napv1.SynthesisResponse{
 		Status:       napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR,
 		ErrorMessage: message,
+		Metadata:     metadata,
 	}
 	if err := stream.Send(resp); err != nil {
 		return err
 	}
-	return fmt.Errorf("synthesis error: %s", message)
+	return status.Error(code, message)
+}
+
+// sendInvalidRequest reports a request the adapter rejects before calling
+// ElevenLabs.
+func (s *Server) sendInvalidRequest(stream napv1.TextToSpeechService_StreamSynthesisServer, message string) error {
+	return s.sendError(stream, codes.InvalidArgument, message, map[string]string{
+		metaErrorKind:      errorKindInvalidRequest,
+		metaErrorRetryable: "false",
+	})
+}
+
+// sendSynthesisError reports an upstream failure, classified by classifyError.
+func (s *Server) sendSynthesisError(stream napv1.TextToSpeechService_StreamSynthesisServer, prefix string, err error) error {
+	code, metadata := classifyError(err)
+	return s.sendError(stream, code, fmt.Sprintf("%s: %v", prefix, err), metadata)
 }
 
 // resolveLanguage returns the effective ISO 639-1 language code to pass to the
@@ -336,3 +622,14 @@ func resolveLanguage(configLang string, metadata map[string]string) string {
 	}
 	return "auto"
 }
+
+// resolveOutputFormat returns the output format for a request: the
+// elevenlabs.output_format metadata value when present, the configured format
+// otherwise.
+func resolveOutputFormat(configFormat string, metadata map[string]string) (elevenlabs.OutputFormat, error) {
+	name := configFormat
+	if override := strings.TrimSpace(metadata[metaOutputFormat]); override != "" {
+		name = override
+	}
+	return elevenlabs.ParseOutputFormat(name)
+}
@@ -3,21 +3,34 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 
 	napv1 "github.com/nupi-ai/nupi/api/nap/v1"
 
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/audio"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/cache"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/config"
 	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/elevenlabs"
+	"github.com/nupi-ai/plugin-tts-remote-elevenlabs/internal/lexicon"
 )
 
 // mockSynthesizer implements elevenlabs.Synthesizer for testing.
@@ -58,12 +71,12 @@ func setup(t *testing.T, synth elevenlabs.Synthesizer, audioCache *cache.Cache)
 }
 
 // setupWithConfig creates a bufconn gRPC server+client pair with a custom config.
-func setupWithConfig(t *testing.T, cfg config.Config, synth elevenlabs.Synthesizer, audioCache *cache.Cache) (napv1.TextToSpeechServiceClient, func()) {
+func setupWithConfig(t *testing.T, cfg config.Config, synth elevenlabs.Synthesizer, audioCache *cache.Cache, opts ...Option) (napv1.TextToSpeechServiceClient, func()) {
 	t.Helper()
 	buf := bufconn.Listen(1024 * 1024)
 
 	srv := grpc.NewServer()
-	svc := New(cfg, slog.Default(), synth, nil, audioCache)
+	svc := New(cfg, slog.Default(), synth, nil, audioCache, opts...)
 	napv1.RegisterTextToSpeechServiceServer(srv, svc)
 
 	go func() {
@@ -123,7 +136,7 @@ func collectResponsesAllowError(stream napv1.TextToSpeechService_StreamSynthesis
 }
 
 func TestStreamSynthesisSuccess(t *testing.T) {
-	// 8192 bytes = 2 chunks of 4096
+	// 8192 bytes = 100ms frames of 3200 + 3200 + 1792
 	pcm := make([]byte, 8192)
 	for i := range pcm {
 		pcm[i] = byte(i % 256)
@@ -141,7 +154,7 @@ func TestStreamSynthesisSuccess(t *testing.T) {
 
 	responses := collectResponses(t, stream)
 
-	// Expect: STARTED, PLAYING (no chunk), PLAYING+chunk1, PLAYING+chunk2, FINISHED
+	// Expect: STARTED, PLAYING (no chunk), PLAYING+chunks, FINISHED
 	if len(responses) < 4 {
 		t.Fatalf("got %d responses, want at least 4", len(responses))
 	}
@@ -165,9 +178,9 @@ func TestStreamSynthesisSuccess(t *testing.T) {
 			if resp.Chunk.Sequence > maxSeq {
 				maxSeq = resp.Chunk.Sequence
 			}
-			// Verify duration: 4096 bytes / 2 = 2048 samples / 16000 * 1000 = 128ms
-			if len(resp.Chunk.Data) == 4096 && resp.Chunk.DurationMs != 128 {
-				t.Errorf("chunk seq %d: DurationMs = %d, want 128", resp.Chunk.Sequence, resp.Chunk.DurationMs)
+			// Verify duration: 3200 bytes / 2 = 1600 samples / 16000 * 1000 = 100ms
+			if len(resp.Chunk.Data) == 3200 && resp.Chunk.DurationMs != 100 {
+				t.Errorf("chunk seq %d: DurationMs = %d, want 100", resp.Chunk.Sequence, resp.Chunk.DurationMs)
 			}
 		}
 	}
@@ -175,8 +188,8 @@ func TestStreamSynthesisSuccess(t *testing.T) {
 	if totalAudioBytes != 8192 {
 		t.Errorf("total audio bytes = %d, want 8192", totalAudioBytes)
 	}
-	if maxSeq != 2 {
-		t.Errorf("max sequence = %d, want 2", maxSeq)
+	if maxSeq != 3 {
+		t.Errorf("max sequence = %d, want 3", maxSeq)
 	}
 
 	// Last response: FINISHED
@@ -187,7 +200,7 @@ func TestStreamSynthesisSuccess(t *testing.T) {
 }
 
 func TestStreamSynthesisPartialChunk(t *testing.T) {
-	// 5000 bytes = 4096 + 904
+	// 5000 bytes = 3200 + 1800
 	pcm := make([]byte, 5000)
 	mock := &mockSynthesizer{data: pcm}
 	client, cleanup := setup(t, mock, nil)
@@ -212,15 +225,284 @@ func TestStreamSynthesisPartialChunk(t *testing.T) {
 	if len(chunks) != 2 {
 		t.Fatalf("got %d chunks, want 2", len(chunks))
 	}
-	if len(chunks[0].Data) != 4096 {
-		t.Errorf("chunk[0] = %d bytes, want 4096", len(chunks[0].Data))
+	if len(chunks[0].Data) != 3200 {
+		t.Errorf("chunk[0] = %d bytes, want 3200", len(chunks[0].Data))
 	}
-	if len(chunks[1].Data) != 904 {
-		t.Errorf("chunk[1] = %d bytes, want 904", len(chunks[1].Data))
+	if len(chunks[1].Data) != 1800 {
+		t.Errorf("chunk[1] = %d bytes, want 1800", len(chunks[1].Data))
 	}
-	// 904 bytes / 2 = 452 samples; 452 * 1000 / 16000 = 28ms
-	if chunks[1].DurationMs != 28 {
-		t.Errorf("chunk[1] DurationMs = %d, want 28", chunks[1].DurationMs)
+	// 5000 bytes end at 156.25ms, 56ms after the first chunk.
+	if chunks[1].DurationMs != 56 {
+		t.Errorf("chunk[1] DurationMs = %d, want 56", chunks[1].DurationMs)
+	}
+}
+
+func TestStreamSynthesisFrameDuration(t *testing.T) {
+	cfg := testConfig()
+	cfg.FrameMs = 20
+	// An odd byte count: the trailing half sample is dropped.
+	mock := &mockSynthesizer{data: make([]byte, 5001)}
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "framed"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var chunks []*napv1.AudioChunk
+	for _, r := range collectResponses(t, stream) {
+		if r.Chunk != nil {
+			chunks = append(chunks, r.Chunk)
+		}
+	}
+
+	// 20ms frames are 640 bytes: seven of them and 520 bytes (16.25ms).
+	if len(chunks) != 8 {
+		t.Fatalf("got %d chunks, want 8", len(chunks))
+	}
+	var total int
+	var totalMs uint32
+	for i, c := range chunks {
+		total += len(c.Data)
+		totalMs += c.DurationMs
+		if i < 7 && (len(c.Data) != 640 || c.DurationMs != 20) {
+			t.Errorf("chunk %d = %d bytes, %dms, want 640 bytes, 20ms", i, len(c.Data), c.DurationMs)
+		}
+	}
+	if last := chunks[7]; len(last.Data) != 520 || !last.Last {
+		t.Errorf("last chunk = %d bytes, Last %v, want 520 bytes marked Last", len(last.Data), last.Last)
+	}
+	if total != 5000 || totalMs != 156 {
+		t.Errorf("delivered %d bytes in %dms, want 5000 bytes in 156ms", total, totalMs)
+	}
+}
+
+func TestStreamSynthesisLastChunkAfterWholeFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{"pcm", "pcm_16000"},
+		{"compressed", "mp3_44100_128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Whole 100ms frames, with the end of the stream reported by a
+			// read of its own.
+			synth := synthesizerFunc(func(context.Context, string, elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+				pr, pw := io.Pipe()
+				go func() {
+					pw.Write(make([]byte, 3200))
+					time.Sleep(50 * time.Millisecond)
+					pw.Close()
+				}()
+				return pr, nil
+			})
+			client, cleanup := setup(t, synth, nil)
+			defer cleanup()
+
+			stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+				Text:     "framed",
+				Metadata: map[string]string{metaOutputFormat: tt.format},
+			})
+			if err != nil {
+				t.Fatalf("StreamSynthesis: %v", err)
+			}
+			var chunks []*napv1.AudioChunk
+			for _, r := range collectResponses(t, stream) {
+				if r.Chunk != nil {
+					chunks = append(chunks, r.Chunk)
+				}
+			}
+			if len(chunks) != 2 || len(chunks[0].Data) != 3200 || chunks[0].Last {
+				t.Fatalf("got %d chunks, want the 3200 bytes of audio followed by a Last chunk", len(chunks))
+			}
+			if last := chunks[1]; !last.Last || len(last.Data) != 0 || last.DurationMs != 0 {
+				t.Errorf("last chunk = %d bytes, %dms, Last %v, want an empty Last chunk", len(last.Data), last.DurationMs, last.Last)
+			}
+		})
+	}
+}
+
+// synthesizerFunc adapts a function to elevenlabs.Synthesizer.
+type synthesizerFunc func(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error)
+
+func (f synthesizerFunc) SynthesizeStream(ctx context.Context, voiceID string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+	return f(ctx, voiceID, req)
+}
+
+// paddedSpeech returns pcm_16000 audio of a constant tone between leading and
+// trailing silence.
+func paddedSpeech(leading, tone, trailing time.Duration) []byte {
+	bytesFor := func(d time.Duration) int { return int(d.Milliseconds()) * 32 }
+	pcm := make([]byte, bytesFor(leading)+bytesFor(tone)+bytesFor(trailing))
+	for i := bytesFor(leading); i < bytesFor(leading)+bytesFor(tone); i += 2 {
+		binary.LittleEndian.PutUint16(pcm[i:], 1000)
+	}
+	return pcm
+}
+
+func TestStreamSynthesisTrimSilence(t *testing.T) {
+	cfg := testConfig()
+	cfg.TrimSilence = true
+	cfg.SilencePaddingMs = 50
+	mock := &mockSynthesizer{data: paddedSpeech(200*time.Millisecond, 300*time.Millisecond, 400*time.Millisecond)}
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "trimmed"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var pcm []byte
+	var lastChunk *napv1.AudioChunk
+	for _, r := range collectResponses(t, stream) {
+		if r.Chunk != nil {
+			pcm = append(pcm, r.Chunk.Data...)
+			lastChunk = r.Chunk
+		}
+	}
+
+	// 50ms padding, 300ms tone, 50ms padding.
+	want := paddedSpeech(50*time.Millisecond, 300*time.Millisecond, 50*time.Millisecond)
+	if !bytes.Equal(pcm, want) {
+		t.Errorf("delivered %d bytes, want %d bytes of padded tone", len(pcm), len(want))
+	}
+	if lastChunk == nil || !lastChunk.Last {
+		t.Error("final chunk not marked Last")
+	}
+}
+
+func TestStreamSynthesisTrimSilenceShiftsAlignment(t *testing.T) {
+	cfg := testConfig()
+	cfg.TrimSilence = true
+	cfg.Timestamps = true
+	cfg.TextNormalization = "none"
+	// Five characters of 100ms each; the first two are silent.
+	var timings []elevenlabs.CharacterTiming
+	for i, c := range "abcde" {
+		start := time.Duration(i) * 100 * time.Millisecond
+		timings = append(timings, elevenlabs.CharacterTiming{Char: string(c), Start: start, End: start + 100*time.Millisecond})
+	}
+	pcm := paddedSpeech(200*time.Millisecond, 300*time.Millisecond, 0)
+	synth := synthesizerFunc(func(context.Context, string, elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+		return alignedStream{io.NopCloser(bytes.NewReader(pcm)), timings}, nil
+	})
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "abcde"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var starts []int64
+	for _, r := range collectResponses(t, stream) {
+		if raw, ok := r.GetChunk().GetMetadata()["alignment"]; ok {
+			var a alignmentJSON
+			if err := json.Unmarshal([]byte(raw), &a); err != nil {
+				t.Fatalf("alignment: %v", err)
+			}
+			starts = append(starts, a.StartMs...)
+		}
+	}
+	// The 200ms of leading silence were trimmed, and with them the
+	// characters in it.
+	if want := []int64{0, 0, 0, 100, 200}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("character starts = %v, want %v", starts, want)
+	}
+}
+
+// sineTone returns d of a 1kHz tone at the given peak amplitude (full scale
+// 1) as 16kHz PCM16.
+func sineTone(amplitude float64, d time.Duration) []byte {
+	pcm := make([]byte, int(d.Milliseconds())*32)
+	for i := 0; i < len(pcm)/2; i++ {
+		v := amplitude * 32767 * math.Sin(2*math.Pi*1000*float64(i)/16000)
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(math.Round(v))))
+	}
+	return pcm
+}
+
+// loudness measures the integrated loudness of 16kHz PCM16 audio.
+func loudness(t *testing.T, pcm []byte) float64 {
+	t.Helper()
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768
+	}
+	meter := audio.NewLoudnessMeter(16000, 1)
+	meter.Add(samples)
+	lufs, ok := meter.Integrated()
+	if !ok {
+		t.Fatal("no loudness measured")
+	}
+	return lufs
+}
+
+func TestStreamSynthesisLoudnessNormalization(t *testing.T) {
+	cfg := testConfig()
+	cfg.LoudnessNormalization = true
+	target := -20.0
+	cfg.LoudnessTargetLUFS = &target
+	// A -20 dBFS tone reads -23 LUFS.
+	mock := &mockSynthesizer{data: sineTone(0.1, 2*time.Second)}
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	synthesize := func() [][]byte {
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "level"})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		var chunks [][]byte
+		for _, r := range collectResponses(t, stream) {
+			if r.Chunk != nil {
+				chunks = append(chunks, r.Chunk.Data)
+			}
+		}
+		return chunks
+	}
+
+	first := synthesize()
+	if got := loudness(t, bytes.Join(first[len(first)/2:], nil)); math.Abs(got+20) > 0.5 {
+		t.Errorf("settled loudness = %.2f LUFS, want -20", got)
+	}
+	// The voice's level is remembered, so the next utterance starts at the
+	// target.
+	second := synthesize()
+	if got := loudness(t, second[0]); math.Abs(got+20) > 0.5 {
+		t.Errorf("first chunk of the next utterance = %.2f LUFS, want -20", got)
+	}
+}
+
+func TestStreamSynthesisFades(t *testing.T) {
+	cfg := testConfig()
+	cfg.FadeMs = 10
+	mock := &mockSynthesizer{data: paddedSpeech(0, 300*time.Millisecond, 0)}
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "faded"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var pcm []byte
+	for _, r := range collectResponses(t, stream) {
+		if r.Chunk != nil {
+			pcm = append(pcm, r.Chunk.Data...)
+		}
+	}
+	if len(pcm) != 300*32 {
+		t.Fatalf("delivered %d bytes, want %d", len(pcm), 300*32)
+	}
+	sample := func(i int) int16 { return int16(binary.LittleEndian.Uint16(pcm[i*2:])) }
+	// 10ms at 16kHz is 160 samples of ramp at each end.
+	last := len(pcm)/2 - 1
+	if sample(0) != 0 || sample(80) != 500 || sample(160) != 1000 {
+		t.Errorf("fade-in samples = %d, %d, %d, want 0, 500, 1000", sample(0), sample(80), sample(160))
+	}
+	if sample(last) != 0 || sample(last-159) != 994 || sample(last-160) != 1000 {
+		t.Errorf("fade-out samples = %d, %d, %d, want 1000, 994, 0", sample(last-160), sample(last-159), sample(last))
 	}
 }
 
@@ -354,7 +636,13 @@ func TestStreamSynthesisCacheHit(t *testing.T) {
 	}
 
 	cfg := testConfig()
-	key := cache.Key("cached text", cfg.Model, cfg.VoiceID, "auto", cfg.Stability, cfg.SimilarityBoost, cfg.OptimizeStreamingLatency)
+	key := cache.Key(cache.KeyParams{
+		Text:         "cached text",
+		Model:        cfg.Model,
+		VoiceID:      cfg.VoiceID,
+		LanguageCode: "auto",
+		OutputFormat: "pcm_16000",
+	})
 	cachedData := make([]byte, 4096)
 	for i := range cachedData {
 		cachedData[i] = 0xAB
@@ -434,7 +722,13 @@ func TestStreamSynthesisCacheMiss(t *testing.T) {
 
 	// Verify data was cached
 	cfg := testConfig()
-	key := cache.Key("new text", cfg.Model, cfg.VoiceID, "auto", cfg.Stability, cfg.SimilarityBoost, cfg.OptimizeStreamingLatency)
+	key := cache.Key(cache.KeyParams{
+		Text:         "new text",
+		Model:        cfg.Model,
+		VoiceID:      cfg.VoiceID,
+		LanguageCode: "auto",
+		OutputFormat: "pcm_16000",
+	})
 	cached, ok := audioCache.Get(key)
 	if !ok {
 		t.Error("data should have been stored in cache after miss")
@@ -525,3 +819,1853 @@ func TestStreamSynthesisClientModeWithMetadata(t *testing.T) {
 		t.Errorf("LanguageCode = %q, want %q", mock.req.LanguageCode, "de")
 	}
 }
+
+func TestStreamSynthesisOutputFormatOverride(t *testing.T) {
+	// 8000 bytes of 8 kHz μ-law = 1000 ms.
+	mock := &mockSynthesizer{data: make([]byte, 8000)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "telephony",
+		Metadata: map[string]string{"elevenlabs.output_format": "ulaw_8000"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.req.OutputFormat != "ulaw_8000" {
+		t.Errorf("OutputFormat = %q, want %q", mock.req.OutputFormat, "ulaw_8000")
+	}
+
+	var totalMs uint32
+	for _, r := range responses {
+		if r.Chunk == nil {
+			continue
+		}
+		totalMs += r.Chunk.DurationMs
+		if r.Chunk.Metadata["encoding"] != "mulaw" {
+			t.Errorf("chunk encoding = %q, want %q", r.Chunk.Metadata["encoding"], "mulaw")
+		}
+		if r.Chunk.Metadata["sample_rate"] != "8000" {
+			t.Errorf("chunk sample_rate = %q, want %q", r.Chunk.Metadata["sample_rate"], "8000")
+		}
+	}
+	if totalMs != 1000 {
+		t.Errorf("total DurationMs = %d, want 1000", totalMs)
+	}
+}
+
+func TestStreamSynthesisConfiguredOutputFormat(t *testing.T) {
+	// 2400 bytes of 24 kHz PCM16 = 50 ms.
+	mock := &mockSynthesizer{data: make([]byte, 2400)}
+	cfg := testConfig()
+	cfg.OutputFormat = "pcm_24000"
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "hi-fi"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.req.OutputFormat != "pcm_24000" {
+		t.Errorf("OutputFormat = %q, want %q", mock.req.OutputFormat, "pcm_24000")
+	}
+	for _, r := range responses {
+		if r.Chunk != nil && r.Chunk.DurationMs != 50 {
+			t.Errorf("chunk DurationMs = %d, want 50", r.Chunk.DurationMs)
+		}
+	}
+}
+
+func TestStreamSynthesisInvalidOutputFormat(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "bad format",
+		Metadata: map[string]string{"elevenlabs.output_format": "wav_16000"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponsesAllowError(stream)
+
+	if mock.called {
+		t.Error("synthesizer should not be called for an invalid output format")
+	}
+	if len(responses) == 0 || responses[len(responses)-1].Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR {
+		t.Error("expected STATUS_ERROR for invalid output format")
+	}
+}
+
+func TestStreamSynthesisCacheKeyIncludesOutputFormat(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+
+	mock := &mockSynthesizer{data: make([]byte, 800)}
+	client, cleanup := setup(t, mock, audioCache)
+	defer cleanup()
+
+	for _, format := range []string{"pcm_16000", "ulaw_8000"} {
+		mock.called = false
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+			Text:     "same text",
+			Metadata: map[string]string{"elevenlabs.output_format": format},
+		})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		collectResponses(t, stream)
+		if !mock.called {
+			t.Errorf("format %s: expected cache miss and upstream call", format)
+		}
+	}
+}
+
+func TestStreamSynthesisLocalResampling(t *testing.T) {
+	// 16000 bytes of 16 kHz mono PCM16 = 500 ms; at 48 kHz stereo that is
+	// 96000 bytes.
+	mock := &mockSynthesizer{data: make([]byte, 16000)}
+	cfg := testConfig()
+	cfg.OutputSampleRate = 48000
+	cfg.OutputChannels = 2
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "resample"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.req.OutputFormat != "pcm_16000" {
+		t.Errorf("upstream OutputFormat = %q, want pcm_16000 (unchanged)", mock.req.OutputFormat)
+	}
+
+	var totalBytes int
+	var lastChunk *napv1.AudioChunk
+	for _, r := range responses {
+		if r.Chunk == nil {
+			continue
+		}
+		totalBytes += len(r.Chunk.Data)
+		lastChunk = r.Chunk
+		if r.Chunk.Metadata["sample_rate"] != "48000" || r.Chunk.Metadata["channels"] != "2" {
+			t.Errorf("chunk metadata = %v, want sample_rate=48000 channels=2", r.Chunk.Metadata)
+		}
+	}
+	if totalBytes != 96000 {
+		t.Errorf("total bytes = %d, want 96000", totalBytes)
+	}
+	if lastChunk == nil || !lastChunk.Last {
+		t.Error("final chunk should carry the flushed resampler tail and be marked Last")
+	}
+}
+
+func TestStreamSynthesisLocalMuLawFromCache(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	cfg := testConfig()
+	cfg.OutputFormat = "pcm_24000"
+	cfg.OutputSampleRate = 8000
+	cfg.OutputEncoding = "mulaw"
+
+	// The cache stores upstream audio, so conversion runs on hits as well.
+	key := cache.Key(cache.KeyParams{
+		Text:         "from cache",
+		Model:        cfg.Model,
+		VoiceID:      cfg.VoiceID,
+		LanguageCode: "auto",
+		OutputFormat: "pcm_24000",
+	})
+	audioCache.Put(key, make([]byte, 4800)) // 100 ms
+
+	mock := &mockSynthesizer{}
+	client, cleanup := setupWithConfig(t, cfg, mock, audioCache)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "from cache"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.called {
+		t.Fatal("synthesizer was called despite cache hit")
+	}
+	var totalBytes int
+	for _, r := range responses {
+		if r.Chunk != nil {
+			totalBytes += len(r.Chunk.Data)
+			if r.Chunk.Metadata["encoding"] != "mulaw" {
+				t.Errorf("chunk encoding = %q, want mulaw", r.Chunk.Metadata["encoding"])
+			}
+		}
+	}
+	// 100 ms at 8 kHz μ-law = 800 bytes.
+	if totalBytes != 800 {
+		t.Errorf("total bytes = %d, want 800", totalBytes)
+	}
+}
+
+func TestStreamSynthesisLocalProcessingRejectsCompressed(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	cfg := testConfig()
+	cfg.OutputSampleRate = 48000
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "mp3 please",
+		Metadata: map[string]string{"elevenlabs.output_format": "mp3_44100_128"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponsesAllowError(stream)
+
+	if mock.called {
+		t.Error("synthesizer should not be called when conversion is impossible")
+	}
+	if len(responses) == 0 || responses[len(responses)-1].Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR {
+		t.Error("expected STATUS_ERROR for compressed upstream with local processing")
+	}
+}
+
+func TestStreamSynthesisTypedErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  codes.Code
+		wantKind  string
+		retryable string
+	}{
+		{
+			name:      "quota_exceeded",
+			err:       &elevenlabs.APIError{StatusCode: 401, Kind: elevenlabs.KindQuotaExceeded, Code: "quota_exceeded", Message: "quota"},
+			wantCode:  codes.ResourceExhausted,
+			wantKind:  "quota_exceeded",
+			retryable: "false",
+		},
+		{
+			name:      "invalid_api_key",
+			err:       &elevenlabs.APIError{StatusCode: 401, Kind: elevenlabs.KindInvalidAPIKey, Code: "invalid_api_key"},
+			wantCode:  codes.Unauthenticated,
+			wantKind:  "invalid_api_key",
+			retryable: "false",
+		},
+		{
+			name:      "voice_not_found",
+			err:       &elevenlabs.APIError{StatusCode: 404, Kind: elevenlabs.KindVoiceNotFound, Code: "voice_not_found"},
+			wantCode:  codes.NotFound,
+			wantKind:  "voice_not_found",
+			retryable: "false",
+		},
+		{
+			name:      "unsupported_model",
+			err:       &elevenlabs.APIError{StatusCode: 400, Kind: elevenlabs.KindUnsupportedModel, Code: "model_not_found"},
+			wantCode:  codes.InvalidArgument,
+			wantKind:  "unsupported_model",
+			retryable: "false",
+		},
+		{
+			name:      "text_too_long",
+			err:       &elevenlabs.APIError{StatusCode: 400, Kind: elevenlabs.KindTextTooLong, Code: "max_character_limit_exceeded"},
+			wantCode:  codes.InvalidArgument,
+			wantKind:  "text_too_long",
+			retryable: "false",
+		},
+		{
+			name:      "rate_limited",
+			err:       &elevenlabs.APIError{StatusCode: 429, Kind: elevenlabs.KindRateLimited, RetryAfter: 2 * time.Second},
+			wantCode:  codes.ResourceExhausted,
+			wantKind:  "rate_limited",
+			retryable: "true",
+		},
+		{
+			name:      "server_error",
+			err:       &elevenlabs.APIError{StatusCode: 503, Kind: elevenlabs.KindUnknown},
+			wantCode:  codes.Unavailable,
+			wantKind:  "unknown",
+			retryable: "true",
+		},
+		{
+			name:      "circuit_open",
+			err:       elevenlabs.ErrCircuitOpen,
+			wantCode:  codes.Unavailable,
+			wantKind:  "circuit_open",
+			retryable: "false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, cleanup := setup(t, &mockSynthesizer{err: tt.err}, nil)
+			defer cleanup()
+
+			stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "fail"})
+			if err != nil {
+				t.Fatalf("StreamSynthesis: %v", err)
+			}
+
+			var errResp *napv1.SynthesisResponse
+			var callErr error
+			for {
+				resp, err := stream.Recv()
+				if err != nil {
+					callErr = err
+					break
+				}
+				if resp.Status == napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR {
+					errResp = resp
+				}
+			}
+
+			if got := status.Code(callErr); got != tt.wantCode {
+				t.Errorf("call status = %v, want %v", got, tt.wantCode)
+			}
+			if errResp == nil {
+				t.Fatal("expected STATUS_ERROR response")
+			}
+			if errResp.Metadata["error_kind"] != tt.wantKind {
+				t.Errorf("error_kind = %q, want %q", errResp.Metadata["error_kind"], tt.wantKind)
+			}
+			if errResp.Metadata["grpc_code"] != tt.wantCode.String() {
+				t.Errorf("grpc_code = %q, want %q", errResp.Metadata["grpc_code"], tt.wantCode.String())
+			}
+			if errResp.Metadata["retryable"] != tt.retryable {
+				t.Errorf("retryable = %q, want %q", errResp.Metadata["retryable"], tt.retryable)
+			}
+		})
+	}
+}
+
+func TestStreamSynthesisRetryAfterMetadata(t *testing.T) {
+	mock := &mockSynthesizer{err: &elevenlabs.APIError{StatusCode: 429, Kind: elevenlabs.KindRateLimited, RetryAfter: 1500 * time.Millisecond}}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "slow down"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponsesAllowError(stream)
+	last := responses[len(responses)-1]
+	if last.Metadata["retry_after_ms"] != "1500" || last.Metadata["http_status"] != "429" {
+		t.Errorf("metadata = %v, want retry_after_ms=1500 http_status=429", last.Metadata)
+	}
+}
+
+func TestStreamSynthesisAdmission(t *testing.T) {
+	release := make(chan struct{})
+	synth := synthesizerFunc(func(ctx context.Context, _ string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+		if req.Text == "Hold." {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return io.NopCloser(bytes.NewReader(make([]byte, 3200))), nil
+	})
+	cfg := testConfig()
+	cfg.MaxConcurrentRequests = 1
+	cfg.QueueTimeoutMs = 200
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+	defer close(release)
+
+	holding, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Hold."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	if resp, err := holding.Recv(); err != nil || resp.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_STARTED {
+		t.Fatalf("first response = %v, %v; want STARTED", resp, err)
+	}
+
+	// The only slot is taken: the next request queues, then is shed.
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "Queued.",
+		Metadata: map[string]string{metaPriority: "5"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var position string
+	var errResp *napv1.SynthesisResponse
+	var callErr error
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			callErr = err
+			break
+		}
+		if resp.Metadata[metaQueued] == "true" {
+			position = resp.Metadata[metaQueuePosition]
+		}
+		if resp.Status == napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR {
+			errResp = resp
+		}
+	}
+	if position != "1" {
+		t.Errorf("queue position = %q, want 1", position)
+	}
+	if got := status.Code(callErr); got != codes.ResourceExhausted {
+		t.Errorf("call status = %v, want ResourceExhausted", got)
+	}
+	if errResp == nil || errResp.Metadata[metaErrorKind] != errorKindQueueTimeout || errResp.Metadata[metaErrorRetryable] != "true" {
+		t.Errorf("error response = %v, want a retryable queue timeout", errResp)
+	}
+}
+
+func TestStreamSynthesisInvalidPriority(t *testing.T) {
+	client, cleanup := setup(t, &mockSynthesizer{}, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "Hello.",
+		Metadata: map[string]string{metaPriority: "urgent"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponsesAllowError(stream)
+	last := responses[len(responses)-1]
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR || last.Metadata[metaErrorKind] != errorKindInvalidRequest {
+		t.Errorf("last response = %v, want an invalid request error", last)
+	}
+}
+
+func TestStreamSynthesisEmptyTextInvalidArgument(t *testing.T) {
+	client, cleanup := setup(t, &mockSynthesizer{}, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	var callErr error
+	for callErr == nil {
+		_, callErr = stream.Recv()
+	}
+	if status.Code(callErr) != codes.InvalidArgument {
+		t.Errorf("call status = %v, want InvalidArgument", status.Code(callErr))
+	}
+}
+
+func TestStreamSynthesisMetadataOverrides(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: "persona",
+		Metadata: map[string]string{
+			"elevenlabs.voice_id":                   "other-voice",
+			"elevenlabs.model":                      "eleven_multilingual_v2",
+			"elevenlabs.stability":                  "0.3",
+			"elevenlabs.similarity_boost":           "0.9",
+			"elevenlabs.optimize_streaming_latency": "2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.voiceID != "other-voice" {
+		t.Errorf("voiceID = %q, want %q", mock.voiceID, "other-voice")
+	}
+	if mock.req.ModelID != "eleven_multilingual_v2" {
+		t.Errorf("ModelID = %q, want %q", mock.req.ModelID, "eleven_multilingual_v2")
+	}
+	if mock.req.VoiceSettings == nil || *mock.req.VoiceSettings.Stability != 0.3 || *mock.req.VoiceSettings.SimilarityBoost != 0.9 {
+		t.Errorf("VoiceSettings = %+v, want stability 0.3, similarity 0.9", mock.req.VoiceSettings)
+	}
+	if mock.req.OptimizeStreamingLatency == nil || *mock.req.OptimizeStreamingLatency != 2 {
+		t.Errorf("OptimizeStreamingLatency = %v, want 2", mock.req.OptimizeStreamingLatency)
+	}
+
+	for _, r := range responses {
+		if r.Chunk == nil {
+			continue
+		}
+		md := r.Chunk.Metadata
+		if md["voice_id"] != "other-voice" || md["model"] != "eleven_multilingual_v2" {
+			t.Errorf("chunk voice/model = %q/%q, want overrides", md["voice_id"], md["model"])
+		}
+		if md["stability"] != "0.3" || md["similarity_boost"] != "0.9" || md["optimize_streaming_latency"] != "2" {
+			t.Errorf("chunk settings = %v, want echoed overrides", md)
+		}
+	}
+}
+
+func TestStreamSynthesisInvalidOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+	}{
+		{"stability_range", map[string]string{"elevenlabs.stability": "1.5"}},
+		{"stability_not_number", map[string]string{"elevenlabs.stability": "high"}},
+		{"stability_nan", map[string]string{"elevenlabs.stability": "NaN"}},
+		{"stability_inf", map[string]string{"elevenlabs.stability": "-Inf"}},
+		{"similarity_range", map[string]string{"elevenlabs.similarity_boost": "-0.1"}},
+		{"similarity_nan", map[string]string{"elevenlabs.similarity_boost": "nan"}},
+		{"latency_range", map[string]string{"elevenlabs.optimize_streaming_latency": "7"}},
+		{"voice_path", map[string]string{"elevenlabs.voice_id": "../voices"}},
+		{"model_chars", map[string]string{"elevenlabs.model": "model?x=1"}},
+		{"style_range", map[string]string{"elevenlabs.style": "2"}},
+		{"speaker_boost_not_bool", map[string]string{"elevenlabs.use_speaker_boost": "maybe"}},
+		{"speed_range", map[string]string{"elevenlabs.speed": "3"}},
+		{"style_nan", map[string]string{"elevenlabs.style": "NaN"}},
+		{"speed_nan", map[string]string{"elevenlabs.speed": "NaN"}},
+		{"speed_inf", map[string]string{"elevenlabs.speed": "+Inf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockSynthesizer{data: make([]byte, 100)}
+			client, cleanup := setup(t, mock, nil)
+			defer cleanup()
+
+			stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+				Text:     "bad override",
+				Metadata: tt.metadata,
+			})
+			if err != nil {
+				t.Fatalf("StreamSynthesis: %v", err)
+			}
+			responses := collectResponsesAllowError(stream)
+
+			if mock.called {
+				t.Error("synthesizer should not be called for an invalid override")
+			}
+			if len(responses) == 0 || responses[len(responses)-1].Metadata["error_kind"] != "invalid_request" {
+				t.Errorf("expected invalid_request error, got %v", responses)
+			}
+		})
+	}
+}
+
+func TestStreamSynthesisCacheKeyIncludesOverrides(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, audioCache)
+	defer cleanup()
+
+	synthesize := func(metadata map[string]string) {
+		t.Helper()
+		mock.called = false
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+			Text:     "same text",
+			Metadata: metadata,
+		})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		collectResponses(t, stream)
+	}
+
+	synthesize(nil)
+	synthesize(map[string]string{"elevenlabs.voice_id": "other-voice"})
+	if !mock.called {
+		t.Error("voice override must not be served from the default voice's cache entry")
+	}
+	synthesize(map[string]string{"elevenlabs.voice_id": "other-voice"})
+	if mock.called {
+		t.Error("repeated override should be served from cache")
+	}
+	synthesize(map[string]string{"elevenlabs.voice_id": "other-voice", "elevenlabs.stability": "0.1"})
+	if !mock.called {
+		t.Error("stability override must change the cache key")
+	}
+}
+
+func TestStreamSynthesisExtendedVoiceSettingOverrides(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	speed := 0.8
+	cfg := testConfig()
+	cfg.Speed = &speed
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setupWithConfig(t, cfg, mock, audioCache)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: "expressive",
+		Metadata: map[string]string{
+			"elevenlabs.style":             "0.6",
+			"elevenlabs.use_speaker_boost": "false",
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	vs := mock.req.VoiceSettings
+	if vs == nil || vs.Style == nil || *vs.Style != 0.6 || vs.UseSpeakerBoost == nil || *vs.UseSpeakerBoost || vs.Speed == nil || *vs.Speed != 0.8 {
+		t.Fatalf("VoiceSettings = %+v, want style 0.6, speaker boost false, configured speed 0.8", vs)
+	}
+	for _, r := range responses {
+		if r.Chunk != nil && (r.Chunk.Metadata["style"] != "0.6" || r.Chunk.Metadata["use_speaker_boost"] != "false" || r.Chunk.Metadata["speed"] != "0.8") {
+			t.Errorf("chunk metadata = %v, want echoed style/use_speaker_boost/speed", r.Chunk.Metadata)
+		}
+	}
+
+	// A different speed must not be served from the cached entry.
+	mock.called = false
+	stream, err = client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: "expressive",
+		Metadata: map[string]string{
+			"elevenlabs.style":             "0.6",
+			"elevenlabs.use_speaker_boost": "false",
+			"elevenlabs.speed":             "1.1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+	if !mock.called {
+		t.Error("speed override must change the cache key")
+	}
+}
+
+func testLexicon(t *testing.T, aliases string) *lexicon.Lexicon {
+	t.Helper()
+	lex, err := lexicon.ParseYAML([]byte("aliases:\n" + aliases))
+	if err != nil {
+		t.Fatalf("ParseYAML: %v", err)
+	}
+	return lex
+}
+
+func TestStreamSynthesisLexiconRewrite(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	lex := testLexicon(t, "  Nupi: noo pee\n")
+	client, cleanup := setupWithConfig(t, testConfig(), mock, nil, WithLexicon(lex, nil))
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Hello from Nupi"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	if mock.req.Text != "Hello from noo pee" {
+		t.Errorf("Text = %q, want lexicon applied", mock.req.Text)
+	}
+	if len(mock.req.PronunciationDictionaryLocators) != 0 {
+		t.Error("rewrite mode must not send dictionary locators")
+	}
+}
+
+func TestStreamSynthesisLexiconDictionary(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	lex := testLexicon(t, "  Nupi: noo pee\n")
+	loc := &elevenlabs.PronunciationDictionaryLocator{PronunciationDictionaryID: "dict", VersionID: "v1"}
+	client, cleanup := setupWithConfig(t, testConfig(), mock, nil, WithLexicon(lex, loc))
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Hello from Nupi"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	if mock.req.Text != "Hello from Nupi" {
+		t.Errorf("Text = %q, want unchanged text in dictionary mode", mock.req.Text)
+	}
+	if len(mock.req.PronunciationDictionaryLocators) != 1 || mock.req.PronunciationDictionaryLocators[0] != *loc {
+		t.Errorf("locators = %+v, want %+v", mock.req.PronunciationDictionaryLocators, *loc)
+	}
+}
+
+func TestStreamSynthesisLexiconVersionInCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	synthesize := func(lex *lexicon.Lexicon) bool {
+		t.Helper()
+		audioCache, err := cache.New(dir, 1024*1024, nil)
+		if err != nil {
+			t.Fatalf("cache.New: %v", err)
+		}
+		mock := &mockSynthesizer{data: make([]byte, 100)}
+		client, cleanup := setupWithConfig(t, testConfig(), mock, audioCache, WithLexicon(lex, nil))
+		defer cleanup()
+
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Nupi"})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		collectResponses(t, stream)
+		return mock.called
+	}
+
+	v1 := testLexicon(t, "  Nupi: noo pee\n")
+	v2 := testLexicon(t, "  Nupi: new pea\n")
+	if !synthesize(v1) {
+		t.Fatal("first request should call the synthesizer")
+	}
+	if synthesize(v1) {
+		t.Error("same lexicon should be served from cache")
+	}
+	if !synthesize(v2) {
+		t.Error("changed lexicon must invalidate cached audio")
+	}
+}
+
+func TestStreamSynthesisNormalizesText(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text:     "**Kosten:** 5 € pro Tag, siehe https://nupi.ai/docs",
+		Metadata: map[string]string{"nupi.lang.iso1": "de"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	want := "Kosten: fünf Euro pro Tag, siehe nupi Punkt ai"
+	if mock.req.Text != want {
+		t.Errorf("Text = %q, want %q", mock.req.Text, want)
+	}
+}
+
+func TestStreamSynthesisNormalizationDisabled(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	cfg := testConfig()
+	cfg.TextNormalization = "none"
+	client, cleanup := setupWithConfig(t, cfg, mock, nil)
+	defer cleanup()
+
+	text := "**Price:** $5"
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	if mock.req.Text != text {
+		t.Errorf("Text = %q, want unchanged %q", mock.req.Text, text)
+	}
+}
+
+func TestStreamSynthesisNothingToSpeak(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "```\nrm -rf build\n```"})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	if mock.called {
+		t.Error("synthesizer should not be called for a code-only message")
+	}
+	last := responses[len(responses)-1]
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED {
+		t.Errorf("last status = %v, want FINISHED", last.Status)
+	}
+	for _, r := range responses {
+		if r.GetChunk() != nil {
+			t.Error("no audio expected")
+		}
+	}
+}
+
+func TestStreamSynthesisSSML(t *testing.T) {
+	mock := &mockSynthesizer{data: make([]byte, 100)}
+	client, cleanup := setup(t, mock, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: `<speak>Wait 2 s<break time="1.5s"/><sub alias="Nupi AI">NAI</sub> says ` +
+			`<phoneme alphabet="ipa" ph="næp">NAP</phoneme> <prosody rate="slow">slowly</prosody></speak>`,
+		Metadata: map[string]string{"nupi.text_format": "ssml", "nupi.lang.iso1": "en"},
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	want := `Wait two seconds <break time="1.5s" /> Nupi AI says <phoneme alphabet="ipa" ph="næp">NAP</phoneme> slowly`
+	if mock.req.Text != want {
+		t.Errorf("Text = %q, want %q", mock.req.Text, want)
+	}
+}
+
+func TestStreamSynthesisTextFormatInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		metadata map[string]string
+	}{
+		{"malformed_ssml", "<speak>unclosed", map[string]string{"nupi.text_format": "ssml"}},
+		{"unknown_format", "hello", map[string]string{"nupi.text_format": "html"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockSynthesizer{data: make([]byte, 100)}
+			client, cleanup := setup(t, mock, nil)
+			defer cleanup()
+
+			stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: tt.text, Metadata: tt.metadata})
+			if err != nil {
+				t.Fatalf("StreamSynthesis: %v", err)
+			}
+			var callErr error
+			for callErr == nil {
+				_, callErr = stream.Recv()
+			}
+			if status.Code(callErr) != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument", status.Code(callErr))
+			}
+			if mock.called {
+				t.Error("synthesizer should not be called")
+			}
+		})
+	}
+}
+
+// sentenceSynthesizer returns distinct audio per request text, optionally
+// after a delay or failing, and records the requests. Without audio every
+// text yields 100 bytes. Streams report request IDs req-1, req-2, ... in
+// call order.
+type sentenceSynthesizer struct {
+	audio map[string][]byte
+	delay map[string]time.Duration
+	fail  map[string]error
+
+	mu   sync.Mutex
+	reqs []elevenlabs.SynthesizeRequest
+}
+
+// stitchedStream is an audio stream reporting an ElevenLabs request ID.
+type stitchedStream struct {
+	io.ReadCloser
+	id string
+}
+
+func (s stitchedStream) RequestID() string { return s.id }
+
+func (m *sentenceSynthesizer) SynthesizeStream(ctx context.Context, _ string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.reqs = append(m.reqs, req)
+	id := fmt.Sprintf("req-%d", len(m.reqs))
+	m.mu.Unlock()
+
+	select {
+	case <-time.After(m.delay[req.Text]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := m.fail[req.Text]; err != nil {
+		return nil, err
+	}
+	data := make([]byte, 100)
+	if m.audio != nil {
+		var ok bool
+		if data, ok = m.audio[req.Text]; !ok {
+			return nil, fmt.Errorf("unexpected text %q", req.Text)
+		}
+	}
+	return stitchedStream{io.NopCloser(bytes.NewReader(data)), id}, nil
+}
+
+func (m *sentenceSynthesizer) requests() []elevenlabs.SynthesizeRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]elevenlabs.SynthesizeRequest(nil), m.reqs...)
+}
+
+func (m *sentenceSynthesizer) requested() []string {
+	var texts []string
+	for _, req := range m.requests() {
+		texts = append(texts, req.Text)
+	}
+	return texts
+}
+
+const (
+	sentence1 = "The first sentence is fairly long."
+	sentence2 = "The second one follows right after."
+	sentence3 = "And the third sentence ends it."
+)
+
+func segmentedConfig() config.Config {
+	cfg := testConfig()
+	cfg.SentenceSegmentation = true
+	cfg.SynthesisConcurrency = 2
+	cfg.TextNormalization = "none"
+	return cfg
+}
+
+func sentenceAudio() map[string][]byte {
+	return map[string][]byte{
+		sentence1: bytes.Repeat([]byte{1}, 5000),
+		sentence2: bytes.Repeat([]byte{2}, 3000),
+		sentence3: bytes.Repeat([]byte{3}, 1000),
+	}
+}
+
+func TestStreamSynthesisSentenceSegmentation(t *testing.T) {
+	audio := sentenceAudio()
+	// The first sentence is the slowest, so later sentences finish first and
+	// must still be played in order.
+	synth := &sentenceSynthesizer{audio: audio, delay: map[string]time.Duration{sentence1: 50 * time.Millisecond}}
+	client, cleanup := setupWithConfig(t, segmentedConfig(), synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: sentence1 + " " + sentence2 + " " + sentence3,
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	var got []byte
+	var chunks []*napv1.AudioChunk
+	for _, r := range responses {
+		if r.Chunk != nil {
+			got = append(got, r.Chunk.Data...)
+			chunks = append(chunks, r.Chunk)
+		}
+	}
+	want := append(append(append([]byte(nil), audio[sentence1]...), audio[sentence2]...), audio[sentence3]...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("audio is not the sentences in order (%d bytes, want %d)", len(got), len(want))
+	}
+	for i, c := range chunks {
+		if c.Sequence != uint64(i+1) {
+			t.Errorf("chunk %d: Sequence = %d, want %d", i, c.Sequence, i+1)
+		}
+		if c.First != (i == 0) || c.Last != (i == len(chunks)-1) {
+			t.Errorf("chunk %d: First=%v Last=%v", i, c.First, c.Last)
+		}
+	}
+
+	if n := len(synth.requested()); n != 3 {
+		t.Errorf("upstream requests = %d, want 3 (one per sentence)", n)
+	}
+	last := responses[len(responses)-1]
+	if last.Metadata["segments"] != "3" {
+		t.Errorf("FINISHED segments = %q, want 3", last.Metadata["segments"])
+	}
+}
+
+func TestStreamSynthesisSegmentationDisabled(t *testing.T) {
+	cfg := segmentedConfig()
+	cfg.SentenceSegmentation = false
+	text := sentence1 + " " + sentence2
+	synth := &sentenceSynthesizer{audio: map[string][]byte{text: make([]byte, 100)}}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	if got := synth.requested(); len(got) != 1 || got[0] != text {
+		t.Errorf("upstream texts = %q, want the whole text once", got)
+	}
+}
+
+func TestStreamSynthesisSegmentCache(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	cfg := segmentedConfig()
+	key := func(text string) string {
+		return cache.Key(cache.KeyParams{
+			Text:         text,
+			Model:        cfg.Model,
+			VoiceID:      cfg.VoiceID,
+			LanguageCode: "auto",
+			OutputFormat: "pcm_16000",
+		})
+	}
+	audio := sentenceAudio()
+	audioCache.Put(key(sentence2), audio[sentence2])
+
+	synth := &sentenceSynthesizer{audio: audio}
+	client, cleanup := setupWithConfig(t, cfg, synth, audioCache)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: sentence1 + " " + sentence2 + " " + sentence3,
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	for _, text := range synth.requested() {
+		if text == sentence2 {
+			t.Error("cached sentence was synthesized again")
+		}
+	}
+	if last := responses[len(responses)-1]; last.Metadata["source"] == "cache" {
+		t.Error("partially cached request must not report source=cache")
+	}
+	if _, ok := audioCache.Get(key(sentence1)); !ok {
+		t.Error("first sentence was not cached")
+	}
+	if _, ok := audioCache.Get(key(sentence3)); !ok {
+		t.Error("last sentence was not cached")
+	}
+}
+
+func TestStreamSynthesisSegmentFailure(t *testing.T) {
+	synth := &sentenceSynthesizer{
+		audio: sentenceAudio(),
+		fail:  map[string]error{sentence2: &elevenlabs.APIError{StatusCode: 500, Body: "internal"}},
+	}
+	cfg := segmentedConfig()
+	cfg.SynthesisConcurrency = 1
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: sentence1 + " " + sentence2 + " " + sentence3,
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponsesAllowError(stream)
+
+	var audioBytes int
+	for _, r := range responses {
+		if r.Chunk != nil {
+			audioBytes += len(r.Chunk.Data)
+			if r.Chunk.Last {
+				t.Error("no chunk may be marked Last when synthesis fails")
+			}
+		}
+	}
+	if audioBytes != 5000 {
+		t.Errorf("audio before the failure = %d bytes, want the first sentence (5000)", audioBytes)
+	}
+	last := responses[len(responses)-1]
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_ERROR {
+		t.Fatalf("last status = %v, want ERROR", last.Status)
+	}
+	for _, text := range synth.requested() {
+		if text == sentence3 {
+			t.Error("synthesis must stop after a failed sentence")
+		}
+	}
+}
+
+func TestStreamSynthesisSegmentContext(t *testing.T) {
+	synth := &sentenceSynthesizer{audio: sentenceAudio()}
+	client, cleanup := setupWithConfig(t, segmentedConfig(), synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{
+		Text: sentence1 + " " + sentence2 + " " + sentence3,
+	})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	collectResponses(t, stream)
+
+	// Sentences are synthesized without their neighbours, so each can be
+	// served from the cache whatever surrounds it.
+	for _, req := range synth.requests() {
+		if req.PreviousText != "" || req.NextText != "" {
+			t.Errorf("%q: previous/next = %q/%q, want none", req.Text, req.PreviousText, req.NextText)
+		}
+	}
+}
+
+func TestStreamSynthesisCharacterLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Model = "eleven_multilingual_v2"
+	synth := &sentenceSynthesizer{}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	limit := elevenlabs.MaxTextLength(cfg.Model)
+	text := strings.TrimSpace(strings.Repeat("This sentence is repeated many times over. ", limit/20))
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+
+	reqs := synth.requests()
+	if len(reqs) < 2 {
+		t.Fatalf("upstream requests = %d, want the text split into parts", len(reqs))
+	}
+	var joined []string
+	for i, req := range reqs {
+		if n := len([]rune(req.Text)); n > limit {
+			t.Errorf("part %d has %d characters, limit %d", i, n, limit)
+		}
+		if !strings.HasSuffix(req.Text, ".") {
+			t.Errorf("part %d does not end at a sentence boundary: ...%q", i, req.Text[len(req.Text)-20:])
+		}
+		if (i > 0) != (req.PreviousText != "") || (i < len(reqs)-1) != (req.NextText != "") {
+			t.Errorf("part %d: unexpected context presence previous=%t next=%t", i, req.PreviousText != "", req.NextText != "")
+		}
+		joined = append(joined, req.Text)
+	}
+	if strings.Join(joined, " ") != text {
+		t.Error("parts do not add up to the original text")
+	}
+
+	var sequence uint64
+	for _, r := range responses {
+		if r.Chunk != nil {
+			sequence++
+			if r.Chunk.Sequence != sequence {
+				t.Fatalf("chunk Sequence = %d, want %d", r.Chunk.Sequence, sequence)
+			}
+		}
+	}
+}
+
+func TestStreamSynthesisRequestStitching(t *testing.T) {
+	cfg := testConfig()
+	cfg.RequestStitching = true
+	cfg.RequestStitchingTTLSec = 60
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	useClock := func(s *Server) { s.history.now = clock.now }
+
+	synth := &sentenceSynthesizer{}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil, useClock)
+	defer cleanup()
+
+	say := func(session, text string) elevenlabs.SynthesizeRequest {
+		t.Helper()
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{SessionId: session, Text: text})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		collectResponses(t, stream)
+		reqs := synth.requests()
+		return reqs[len(reqs)-1]
+	}
+
+	if req := say("s1", "Hello there."); req.PreviousRequestIDs != nil {
+		t.Errorf("first utterance: previous_request_ids = %v, want none", req.PreviousRequestIDs)
+	}
+	if req := say("s1", "How are you?"); !reflect.DeepEqual(req.PreviousRequestIDs, []string{"req-1"}) {
+		t.Errorf("second utterance: previous_request_ids = %v, want [req-1]", req.PreviousRequestIDs)
+	}
+	if req := say("s2", "Another session."); req.PreviousRequestIDs != nil {
+		t.Errorf("other session: previous_request_ids = %v, want none", req.PreviousRequestIDs)
+	}
+	if req := say("", "No session."); req.PreviousRequestIDs != nil {
+		t.Errorf("no session: previous_request_ids = %v, want none", req.PreviousRequestIDs)
+	}
+	if req := say("s1", "Still here?"); !reflect.DeepEqual(req.PreviousRequestIDs, []string{"req-1", "req-2"}) {
+		t.Errorf("third utterance: previous_request_ids = %v, want [req-1 req-2]", req.PreviousRequestIDs)
+	}
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	if req := say("s1", "After a long pause."); req.PreviousRequestIDs != nil {
+		t.Errorf("after TTL: previous_request_ids = %v, want none", req.PreviousRequestIDs)
+	}
+}
+
+func TestStreamSynthesisStitchedNotCoalesced(t *testing.T) {
+	cfg := testConfig()
+	cfg.RequestStitching = true
+	cfg.RequestStitchingTTLSec = 60
+	synth := &sentenceSynthesizer{delay: map[string]time.Duration{"Same phrase.": 200 * time.Millisecond}}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	start := func(session, text string) napv1.TextToSpeechService_StreamSynthesisClient {
+		t.Helper()
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{SessionId: session, Text: text})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		return stream
+	}
+	collectResponses(t, start("a", "Hello."))
+
+	// Session a's phrase continues its conversation, session b's does not:
+	// they are synthesized separately although concurrent and identical.
+	a, b := start("a", "Same phrase."), start("b", "Same phrase.")
+	collectResponses(t, a)
+	collectResponses(t, b)
+	collectResponses(t, start("b", "Next."))
+
+	reqs := synth.requests()
+	if len(reqs) != 4 {
+		t.Fatalf("upstream requests = %d, want 4", len(reqs))
+	}
+	var stitched, plain int // indexes of the phrase requests
+	for i := 1; i <= 2; i++ {
+		if reqs[i].PreviousRequestIDs != nil {
+			stitched = i
+		} else {
+			plain = i
+		}
+	}
+	if stitched == 0 || plain == 0 || !reflect.DeepEqual(reqs[stitched].PreviousRequestIDs, []string{"req-1"}) {
+		t.Fatalf("phrase requests stitched to %v and %v, want one to [req-1] and one to none", reqs[1].PreviousRequestIDs, reqs[2].PreviousRequestIDs)
+	}
+	want := fmt.Sprintf("req-%d", plain+1)
+	if got := reqs[3].PreviousRequestIDs; !reflect.DeepEqual(got, []string{want}) {
+		t.Errorf("session b continues %v, want its own generation %s", got, want)
+	}
+}
+
+// alignedStream is an audio stream reporting character timings.
+type alignedStream struct {
+	io.ReadCloser
+	timings []elevenlabs.CharacterTiming
+}
+
+func (s alignedStream) Alignment() []elevenlabs.CharacterTiming { return s.timings }
+
+// alignedSynthesizer returns 100ms of PCM16 audio at 16kHz per character,
+// with matching timings.
+type alignedSynthesizer struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *alignedSynthesizer) SynthesizeStream(_ context.Context, _ string, req elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	if !req.WithTimestamps {
+		return nil, fmt.Errorf("timestamps not requested")
+	}
+	var timings []elevenlabs.CharacterTiming
+	for i, r := range []rune(req.Text) {
+		start := time.Duration(i) * 100 * time.Millisecond
+		timings = append(timings, elevenlabs.CharacterTiming{Char: string(r), Start: start, End: start + 100*time.Millisecond})
+	}
+	pcm := make([]byte, len(timings)*3200)
+	return alignedStream{io.NopCloser(bytes.NewReader(pcm)), timings}, nil
+}
+
+func TestStreamSynthesisTimestamps(t *testing.T) {
+	audioCache, err := cache.New(t.TempDir(), 10*1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	cfg := segmentedConfig()
+	cfg.Timestamps = true
+	synth := &alignedSynthesizer{}
+	client, cleanup := setupWithConfig(t, cfg, synth, audioCache)
+	defer cleanup()
+
+	text := sentence1 + " " + sentence2
+	for _, source := range []string{"live", "cache"} {
+		stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		responses := collectResponses(t, stream)
+
+		var chars strings.Builder
+		var chunkStart int64
+		for _, r := range responses {
+			if r.Chunk == nil {
+				continue
+			}
+			chunkEnd := chunkStart + int64(r.Chunk.DurationMs)
+			if raw, ok := r.Chunk.Metadata["alignment"]; ok {
+				var a alignmentJSON
+				if err := json.Unmarshal([]byte(raw), &a); err != nil {
+					t.Fatalf("%s: chunk %d alignment: %v", source, r.Chunk.Sequence, err)
+				}
+				for i, c := range a.Chars {
+					chars.WriteString(c)
+					if a.StartMs[i] < chunkStart || a.StartMs[i] >= chunkEnd {
+						t.Errorf("%s: chunk %d [%d, %d) carries %q starting at %d", source, r.Chunk.Sequence, chunkStart, chunkEnd, c, a.StartMs[i])
+					}
+				}
+			}
+			chunkStart = chunkEnd
+		}
+		if chars.String() != text {
+			t.Errorf("%s: aligned characters = %q, want %q", source, chars.String(), text)
+		}
+
+		last := responses[len(responses)-1]
+		var words []wordTiming
+		if err := json.Unmarshal([]byte(last.Metadata["word_alignment"]), &words); err != nil {
+			t.Fatalf("%s: word_alignment: %v", source, err)
+		}
+		if len(words) != len(strings.Fields(text)) {
+			t.Fatalf("%s: %d words aligned, want %d", source, len(words), len(strings.Fields(text)))
+		}
+		// The second sentence starts after the first one's audio.
+		second := words[len(strings.Fields(sentence1))]
+		wantStart := int64(len([]rune(sentence1))) * 100
+		if second.Word != "The" || second.StartMs != wantStart || second.Offset != len(sentence1)+1 {
+			t.Errorf("%s: first word of the second sentence = %+v, want The at %dms, offset %d", source, second, wantStart, len(sentence1)+1)
+		}
+	}
+	if synth.calls != 2 {
+		t.Errorf("upstream calls = %d, want 2 (second request from cache)", synth.calls)
+	}
+}
+
+// chunkVisemes decodes the visemes of all chunks, checking that each is
+// attached to the chunk it starts in or, when its grapheme crosses the chunk
+// boundary, to the following one.
+func chunkVisemes(t *testing.T, responses []*napv1.SynthesisResponse, checkStart bool) []visemeJSON {
+	t.Helper()
+	var all []visemeJSON
+	var previousStart, chunkStart int64
+	for _, r := range responses {
+		if r.Chunk == nil {
+			continue
+		}
+		if _, ok := r.Chunk.Metadata["alignment"]; ok {
+			t.Errorf("chunk %d carries alignment without timestamps enabled", r.Chunk.Sequence)
+		}
+		chunkEnd := chunkStart + int64(r.Chunk.DurationMs)
+		if raw, ok := r.Chunk.Metadata["visemes"]; ok {
+			var events []visemeJSON
+			if err := json.Unmarshal([]byte(raw), &events); err != nil {
+				t.Fatalf("chunk %d visemes: %v", r.Chunk.Sequence, err)
+			}
+			for _, e := range events {
+				if checkStart && (e.StartMs < previousStart || e.StartMs >= chunkEnd) {
+					t.Errorf("chunk %d [%d, %d) carries %s starting at %d", r.Chunk.Sequence, chunkStart, chunkEnd, e.Viseme, e.StartMs)
+				}
+			}
+			all = append(all, events...)
+		}
+		previousStart, chunkStart = chunkStart, chunkEnd
+	}
+	return all
+}
+
+func visemeNames(events []visemeJSON) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Viseme)
+	}
+	return names
+}
+
+func TestStreamSynthesisVisemes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Visemes = true
+	cfg.TextNormalization = "none"
+	synth := &alignedSynthesizer{}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	// Visemes need timings from ElevenLabs even with timestamps disabled;
+	// alignedSynthesizer fails requests without them.
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Papa bought fish."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+	if last := responses[len(responses)-1]; last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED {
+		t.Fatalf("last status = %v, want FINISHED", last.Status)
+	}
+
+	events := chunkVisemes(t, responses, true)
+	want := []string{"PP", "aa", "PP", "aa", "PP", "aa", "kk", "DD", "FF", "I", "CH", "sil"}
+	if got := visemeNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("visemes = %q, want %q", got, want)
+	}
+	// "ough" is o-u (aa) then g (kk), silent h; each character lasts 100ms.
+	if e := events[5]; e.StartMs != 600 || e.EndMs != 800 {
+		t.Errorf("ou = %+v, want 600-800ms", e)
+	}
+	if e := events[len(events)-1]; e.StartMs != 1600 || e.EndMs != 1700 {
+		t.Errorf("final sil = %+v, want 1600-1700ms", e)
+	}
+}
+
+func TestStreamSynthesisVisemesEstimated(t *testing.T) {
+	cfg := testConfig()
+	cfg.Visemes = true
+	cfg.TextNormalization = "none"
+	cfg.Backend = config.BackendWebSocket
+	const text = "Bob is here."
+	synth := &sentenceSynthesizer{audio: map[string][]byte{text: make([]byte, 32000)}}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+	if reqs := synth.requests(); len(reqs) != 1 || reqs[0].WithTimestamps {
+		t.Fatalf("requests = %+v, want one without timestamps", reqs)
+	}
+
+	// Timings are estimated from the text, so only the shapes and their
+	// order are certain.
+	events := chunkVisemes(t, responses, false)
+	want := []string{"PP", "O", "PP", "I", "SS", "E", "RR", "E", "sil"}
+	if got := visemeNames(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("visemes = %q, want %q", got, want)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].StartMs < events[i-1].EndMs {
+			t.Errorf("viseme %d starts at %d before the previous ends at %d", i, events[i].StartMs, events[i-1].EndMs)
+		}
+	}
+}
+
+// cancellingStream is a server stream that records the responses sent and
+// cancels its context once cancelAfterMs of audio has been sent. Unlike a
+// client stream it still sees the INTERRUPTED status sent after cancellation.
+type cancellingStream struct {
+	grpc.ServerStream
+	ctx           context.Context
+	cancel        context.CancelFunc
+	cancelAfterMs uint32
+
+	deliveredMs uint32
+	responses   []*napv1.SynthesisResponse
+}
+
+func newCancellingStream(cancelAfterMs uint32) *cancellingStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancellingStream{ctx: ctx, cancel: cancel, cancelAfterMs: cancelAfterMs}
+}
+
+func (s *cancellingStream) Context() context.Context { return s.ctx }
+
+func (s *cancellingStream) Send(resp *napv1.SynthesisResponse) error {
+	s.responses = append(s.responses, resp)
+	if resp.Chunk != nil {
+		s.deliveredMs += resp.Chunk.DurationMs
+		if s.deliveredMs >= s.cancelAfterMs {
+			s.cancel()
+		}
+	}
+	return nil
+}
+
+func TestStreamSynthesisInterruptedPosition(t *testing.T) {
+	cfg := segmentedConfig()
+	cfg.Timestamps = true
+	svc := New(cfg, slog.Default(), &alignedSynthesizer{}, nil, nil)
+
+	// Each character lasts 100ms, as does each chunk: the tenth chunk ends
+	// at 1000ms, when the first ten characters have been spoken.
+	stream := newCancellingStream(1000)
+	if err := svc.StreamSynthesis(&napv1.StreamSynthesisRequest{Text: sentence1 + " " + sentence2}, stream); err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	last := stream.responses[len(stream.responses)-1]
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED {
+		t.Fatalf("last status = %v, want INTERRUPTED", last.Status)
+	}
+	want := map[string]string{
+		"reason":             context.Canceled.Error(),
+		"spoken_text":        "The first ",
+		"spoken_chars":       "10",
+		"audio_ms_delivered": "1000",
+	}
+	if !reflect.DeepEqual(last.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", last.Metadata, want)
+	}
+}
+
+func TestStreamSynthesisInterruptedInLaterPart(t *testing.T) {
+	cfg := segmentedConfig()
+	cfg.Timestamps = true
+	svc := New(cfg, slog.Default(), &alignedSynthesizer{}, nil, nil)
+
+	// The first sentence lasts 3.4s; cancel 0.5s into the second.
+	stream := newCancellingStream(3900)
+	if err := svc.StreamSynthesis(&napv1.StreamSynthesisRequest{Text: sentence1 + " " + sentence2}, stream); err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	md := stream.responses[len(stream.responses)-1].Metadata
+	if text := md["spoken_text"]; !strings.HasPrefix(text, sentence1+" The s") || len(text) >= len(sentence1+" "+sentence2) {
+		t.Errorf("spoken_text = %q, want the first sentence and the start of the second", text)
+	}
+	if md["spoken_chars"] != fmt.Sprintf("%d", len(md["spoken_text"])) {
+		t.Errorf("spoken_chars = %s for %q", md["spoken_chars"], md["spoken_text"])
+	}
+}
+
+func TestStreamSynthesisInterruptedEstimated(t *testing.T) {
+	cfg := testConfig()
+	cfg.TextNormalization = "none"
+	const text = "Without timings the spoken text is estimated."
+	// 3.2s of audio for 45 characters, close to the nominal rate.
+	synth := &sentenceSynthesizer{audio: map[string][]byte{text: make([]byte, 102400)}}
+	svc := New(cfg, slog.Default(), synth, nil, nil)
+
+	stream := newCancellingStream(1500)
+	if err := svc.StreamSynthesis(&napv1.StreamSynthesisRequest{Text: text}, stream); err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	md := stream.responses[len(stream.responses)-1].Metadata
+	if md["audio_ms_delivered"] != "1500" {
+		t.Errorf("audio_ms_delivered = %s, want 1500", md["audio_ms_delivered"])
+	}
+	spoken := md["spoken_text"]
+	if spoken == "" || len(spoken) >= len(text) || !strings.HasPrefix(text, spoken) {
+		t.Errorf("spoken_text = %q, want a proper prefix of %q", spoken, text)
+	}
+}
+
+func TestStreamSynthesisPacedDelivery(t *testing.T) {
+	cfg := testConfig()
+	cfg.TextNormalization = "none"
+	cfg.PacedDelivery = true
+	cfg.PacingLeadMs = 100
+	const text = "Paced text."
+	// 800ms of audio in eight chunks; the last one starts at 700ms and is
+	// due 100ms before that.
+	synth := &sentenceSynthesizer{audio: map[string][]byte{text: make([]byte, 25600)}}
+	client, cleanup := setupWithConfig(t, cfg, synth, nil)
+	defer cleanup()
+
+	start := time.Now()
+	stream, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: text})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	responses := collectResponses(t, stream)
+	if took := time.Since(start); took < 580*time.Millisecond {
+		t.Errorf("paced delivery of 800ms of audio took %v, want at least 600ms", took)
+	}
+	if last := responses[len(responses)-1]; last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED || last.Metadata["total_bytes"] != "25600" {
+		t.Errorf("last response = %v %v, want FINISHED with all audio", last.Status, last.Metadata)
+	}
+}
+
+func TestStreamSynthesisPacedInterruption(t *testing.T) {
+	cfg := testConfig()
+	cfg.TextNormalization = "none"
+	cfg.PacedDelivery = true
+	cfg.PacingLeadMs = 100
+	const text = "A long answer."
+	// Ten seconds of audio, cancelled after 300ms of playback.
+	synth := &sentenceSynthesizer{audio: map[string][]byte{text: make([]byte, 320000)}}
+	svc := New(cfg, slog.Default(), synth, nil, nil)
+
+	stream := newCancellingStream(math.MaxUint32)
+	time.AfterFunc(300*time.Millisecond, stream.cancel)
+	start := time.Now()
+	if err := svc.StreamSynthesis(&napv1.StreamSynthesisRequest{Text: text}, stream); err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("interrupted paced stream returned after %v", took)
+	}
+	last := stream.responses[len(stream.responses)-1]
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_INTERRUPTED {
+		t.Fatalf("last status = %v, want INTERRUPTED", last.Status)
+	}
+	delivered, _ := strconv.Atoi(last.Metadata["audio_ms_delivered"])
+	if delivered < 300 || delivered > 1000 {
+		t.Errorf("audio_ms_delivered = %d, want about 300ms plus the 100ms lead", delivered)
+	}
+}
+
+// countingReader serves size zero bytes and counts those read.
+type countingReader struct {
+	size int
+
+	mu   sync.Mutex
+	read int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.read == r.size {
+		return 0, io.EOF
+	}
+	n := min(len(p), r.size-r.read)
+	clear(p[:n])
+	r.read += n
+	return n, nil
+}
+
+func (r *countingReader) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.read
+}
+
+func TestStreamSynthesisPacedBackpressure(t *testing.T) {
+	cfg := testConfig()
+	cfg.TextNormalization = "none"
+	cfg.PacedDelivery = true
+	cfg.PacingLeadMs = 100
+	// Ten seconds of audio, cancelled after 300ms of playback.
+	upstream := &countingReader{size: 320000}
+	synth := synthesizerFunc(func(context.Context, string, elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+		return io.NopCloser(upstream), nil
+	})
+	svc := New(cfg, slog.Default(), synth, nil, nil)
+
+	stream := newCancellingStream(math.MaxUint32)
+	time.AfterFunc(300*time.Millisecond, stream.cancel)
+	if err := svc.StreamSynthesis(&napv1.StreamSynthesisRequest{Text: "A long answer."}, stream); err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+
+	// Upstream reads keep within the lead and a few buffers of the ~400ms
+	// delivered, instead of running through all ten seconds.
+	if read := upstream.count(); read > 32000 {
+		t.Errorf("read %d bytes upstream by the interruption, want at most 1s of audio", read)
+	}
+}
+
+// sharedSynthesizer streams 100ms of audio per call, then stalls until
+// release is closed and streams another 100ms. With hold set, nothing is
+// streamed until hold is closed.
+type sharedSynthesizer struct {
+	hold      chan struct{}
+	release   chan struct{}
+	cancelled chan struct{} // closed when a call's context ends early
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *sharedSynthesizer) SynthesizeStream(ctx context.Context, _ string, _ elevenlabs.SynthesizeRequest) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+
+	pr, pw := io.Pipe()
+	go func() {
+		if m.hold != nil {
+			<-m.hold
+		}
+		pw.Write(make([]byte, 3200))
+		select {
+		case <-m.release:
+			pw.Write(make([]byte, 3200))
+			pw.Close()
+		case <-ctx.Done():
+			close(m.cancelled)
+			pw.CloseWithError(ctx.Err())
+		}
+	}()
+	return pr, nil
+}
+
+func (m *sharedSynthesizer) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// recvChunk receives responses until the first audio chunk.
+func recvChunk(t *testing.T, stream napv1.TextToSpeechService_StreamSynthesisClient) *napv1.AudioChunk {
+	t.Helper()
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if resp.Chunk != nil {
+			return resp.Chunk
+		}
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamSynthesisCoalescesIdenticalRequests(t *testing.T) {
+	synth := &sharedSynthesizer{hold: make(chan struct{}), release: make(chan struct{}), cancelled: make(chan struct{})}
+	client, cleanup := setup(t, synth, nil)
+	defer cleanup()
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+	first, err := client.StreamSynthesis(firstCtx, &napv1.StreamSynthesisRequest{Text: "Welcome back."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	waitFor(t, func() bool { return synth.callCount() == 1 })
+
+	// The second request joins the synthesis in flight before any audio
+	// arrived, and gets its audio numbered on its own.
+	second, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Welcome back."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(synth.hold)
+	recvChunk(t, first)
+	if chunk := recvChunk(t, second); chunk.Sequence != 1 {
+		t.Errorf("first chunk of the second request: Sequence = %d, want 1", chunk.Sequence)
+	}
+
+	// The request that started the synthesis leaving does not stop it.
+	cancelFirst()
+	collectResponsesAllowError(first)
+	close(synth.release)
+
+	var audio int
+	var last *napv1.SynthesisResponse
+	for _, resp := range collectResponses(t, second) {
+		if resp.Chunk != nil {
+			audio += len(resp.Chunk.Data)
+		}
+		last = resp
+	}
+	if last.Status != napv1.SynthesisStatus_SYNTHESIS_STATUS_FINISHED {
+		t.Errorf("last status = %v, want FINISHED", last.Status)
+	}
+	// 100ms were delivered with the first chunk.
+	if want := 6400 - 3200; audio != want {
+		t.Errorf("remaining audio = %d bytes, want %d", audio, want)
+	}
+	if n := synth.callCount(); n != 1 {
+		t.Errorf("upstream calls = %d, want 1", n)
+	}
+}
+
+func TestStreamSynthesisLateRequestNotCoalesced(t *testing.T) {
+	synth := &sharedSynthesizer{release: make(chan struct{}), cancelled: make(chan struct{})}
+	client, cleanup := setup(t, synth, nil)
+	defer cleanup()
+
+	first, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Welcome back."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	recvChunk(t, first)
+
+	// The flight dropped the audio its only subscriber relayed, so a request
+	// arriving now cannot get it from the start and synthesizes its own.
+	second, err := client.StreamSynthesis(context.Background(), &napv1.StreamSynthesisRequest{Text: "Welcome back."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	recvChunk(t, second)
+	close(synth.release)
+	for _, stream := range []napv1.TextToSpeechService_StreamSynthesisClient{first, second} {
+		var audio int
+		for _, resp := range collectResponses(t, stream) {
+			if resp.Chunk != nil {
+				audio += len(resp.Chunk.Data)
+			}
+		}
+		if want := 6400 - 3200; audio != want {
+			t.Errorf("remaining audio = %d bytes, want %d", audio, want)
+		}
+	}
+	if n := synth.callCount(); n != 2 {
+		t.Errorf("upstream calls = %d, want 2", n)
+	}
+}
+
+func TestStreamSynthesisCoalescedCancel(t *testing.T) {
+	synth := &sharedSynthesizer{hold: make(chan struct{}), release: make(chan struct{}), cancelled: make(chan struct{})}
+	client, cleanup := setup(t, synth, nil)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var streams []napv1.TextToSpeechService_StreamSynthesisClient
+	for range 2 {
+		stream, err := client.StreamSynthesis(ctx, &napv1.StreamSynthesisRequest{Text: "Welcome back."})
+		if err != nil {
+			t.Fatalf("StreamSynthesis: %v", err)
+		}
+		streams = append(streams, stream)
+		waitFor(t, func() bool { return synth.callCount() == 1 })
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(synth.hold)
+	for _, stream := range streams {
+		recvChunk(t, stream)
+	}
+
+	// The upstream request is cancelled once the last subscriber left.
+	cancel()
+	for _, stream := range streams {
+		collectResponsesAllowError(stream)
+	}
+	select {
+	case <-synth.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream synthesis not cancelled")
+	}
+	if n := synth.callCount(); n != 1 {
+		t.Errorf("upstream calls = %d, want 1", n)
+	}
+}
+
+func TestStreamSynthesisInterruptedNotCached(t *testing.T) {
+	dir := t.TempDir()
+	audioCache, err := cache.New(dir, 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	synth := &sharedSynthesizer{release: make(chan struct{}), cancelled: make(chan struct{})}
+	client, cleanup := setup(t, synth, audioCache)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.StreamSynthesis(ctx, &napv1.StreamSynthesisRequest{Text: "Cut short."})
+	if err != nil {
+		t.Fatalf("StreamSynthesis: %v", err)
+	}
+	recvChunk(t, stream)
+	cancel()
+	collectResponsesAllowError(stream)
+	<-synth.cancelled
+
+	// The partial entry is discarded once the upstream read fails.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache dir holds %d files after an interrupted synthesis", len(entries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
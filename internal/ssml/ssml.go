@@ -0,0 +1,278 @@
+// Package ssml translates SSML into the markup ElevenLabs understands.
+//
+// ElevenLabs accepts plain text with <break time="..."/> and <phoneme> tags.
+// Parse reduces an SSML document to text, pauses and phonemes; everything
+// else degrades to the text a listener should hear: <sub> becomes its alias,
+// <say-as interpret-as="characters"> is spelled out, and <prosody>, <lang>,
+// <emphasis> and unknown elements keep only their content.
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBreak is the longest pause ElevenLabs honours in a single break tag.
+// Longer SSML breaks are clamped.
+const MaxBreak = 3 * time.Second
+
+// strengthBreaks maps SSML break strengths to pauses.
+var strengthBreaks = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     250 * time.Millisecond,
+	"medium":   500 * time.Millisecond,
+	"strong":   800 * time.Millisecond,
+	"x-strong": 1200 * time.Millisecond,
+}
+
+// Phonemes in these alphabets are passed to ElevenLabs; others degrade to
+// the written word.
+var phonemeAlphabets = map[string]bool{
+	"ipa":         true,
+	"cmu-arpabet": true,
+}
+
+// Segment is one piece of a parsed document: exactly one of Text, Break and
+// Phoneme is set.
+type Segment struct {
+	Text    string
+	Break   time.Duration
+	Phoneme *Phoneme
+}
+
+// Phoneme is a word with an explicit pronunciation.
+type Phoneme struct {
+	Alphabet string
+	PH       string
+	Text     string
+}
+
+// Parse reads an SSML document. The <speak> root is optional, so fragments
+// such as `Hello <break time="1s"/> world` are accepted too.
+func Parse(doc string) ([]Segment, error) {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Entity = xml.HTMLEntity
+
+	p := &parser{}
+	if err := p.run(d); err != nil {
+		return nil, fmt.Errorf("ssml: %w", err)
+	}
+	p.flush()
+	return p.segments, nil
+}
+
+type parser struct {
+	segments []Segment
+	text     strings.Builder
+}
+
+// flush ends the current text segment.
+func (p *parser) flush() {
+	if p.text.Len() > 0 {
+		p.segments = append(p.segments, Segment{Text: p.text.String()})
+		p.text.Reset()
+	}
+}
+
+func (p *parser) run(d *xml.Decoder) error {
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			p.text.Write(t)
+		case xml.StartElement:
+			if err := p.element(d, t); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// element handles one start tag; elements that replace their content consume
+// it up to the matching end tag.
+func (p *parser) element(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "break":
+		pause, err := breakDuration(start)
+		if err != nil {
+			return err
+		}
+		if pause > 0 {
+			p.flush()
+			p.segments = append(p.segments, Segment{Break: pause})
+		} else {
+			p.text.WriteString(" ")
+		}
+		return d.Skip()
+
+	case "sub":
+		content, err := innerText(d)
+		if err != nil {
+			return err
+		}
+		if alias, ok := attr(start, "alias"); ok {
+			content = alias
+		}
+		p.text.WriteString(content)
+
+	case "say-as":
+		content, err := innerText(d)
+		if err != nil {
+			return err
+		}
+		interpretAs, _ := attr(start, "interpret-as")
+		switch interpretAs {
+		case "characters", "spell-out", "letters", "verbatim", "digits", "telephone":
+			content = spellOut(content)
+		}
+		p.text.WriteString(content)
+
+	case "phoneme":
+		content, err := innerText(d)
+		if err != nil {
+			return err
+		}
+		alphabet, _ := attr(start, "alphabet")
+		alphabet = strings.ToLower(alphabet)
+		ph, _ := attr(start, "ph")
+		if !phonemeAlphabets[alphabet] || ph == "" {
+			p.text.WriteString(content)
+			return nil
+		}
+		p.flush()
+		p.segments = append(p.segments, Segment{Phoneme: &Phoneme{Alphabet: alphabet, PH: ph, Text: content}})
+
+	case "audio":
+		// Our output is speech only; keep the fallback content.
+	case "mark", "desc":
+		return d.Skip()
+
+	case "p", "s":
+		// Paragraphs and sentences separate words even when the document
+		// has no whitespace between them.
+		p.text.WriteString(" ")
+	}
+	return nil
+}
+
+// breakDuration reads a break's time or strength; a bare <break/> is a
+// medium pause.
+func breakDuration(start xml.StartElement) (time.Duration, error) {
+	if value, ok := attr(start, "time"); ok {
+		pause, err := parseTime(value)
+		if err != nil {
+			return 0, err
+		}
+		return min(pause, MaxBreak), nil
+	}
+	strength, ok := attr(start, "strength")
+	if !ok {
+		return strengthBreaks["medium"], nil
+	}
+	pause, known := strengthBreaks[strength]
+	if !known {
+		return 0, fmt.Errorf("unknown break strength %q", strength)
+	}
+	return pause, nil
+}
+
+// parseTime parses an SSML time value such as "500ms" or "1.5s".
+func parseTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	unit := time.Second
+	number, ok := strings.CutSuffix(value, "ms")
+	if ok {
+		unit = time.Millisecond
+	} else if number, ok = strings.CutSuffix(value, "s"); !ok {
+		return 0, fmt.Errorf("invalid break time %q", value)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid break time %q", value)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// innerText returns the text inside the current element, dropping any
+// nested markup.
+func innerText(d *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for depth := 1; depth > 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return b.String(), nil
+}
+
+func attr(start xml.StartElement, name string) (string, bool) {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// spellOut separates characters so they are read one by one.
+func spellOut(s string) string {
+	var letters []string
+	for _, r := range s {
+		if r != ' ' && r != '\t' && r != '\n' && r != '-' {
+			letters = append(letters, string(r))
+		}
+	}
+	return strings.Join(letters, " ")
+}
+
+// Render produces ElevenLabs input from segments. transform, if not nil, is
+// applied to every text segment, so text normalization and lexicons never
+// touch the generated tags.
+func Render(segments []Segment, transform func(string) string) string {
+	var parts []string
+	for _, s := range segments {
+		switch {
+		case s.Phoneme != nil:
+			parts = append(parts, fmt.Sprintf(`<phoneme alphabet="%s" ph="%s">%s</phoneme>`,
+				escape(s.Phoneme.Alphabet), escape(s.Phoneme.PH), escape(s.Phoneme.Text)))
+		case s.Break > 0:
+			parts = append(parts, fmt.Sprintf(`<break time="%ss" />`, strconv.FormatFloat(s.Break.Seconds(), 'f', -1, 64)))
+		default:
+			text := s.Text
+			if transform != nil {
+				text = transform(text)
+			}
+			if text = strings.Join(strings.Fields(text), " "); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
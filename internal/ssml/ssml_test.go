@@ -0,0 +1,78 @@
+package ssml
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain_speak", "<speak>Hello world</speak>", "Hello world"},
+		{"fragment", `Hello <break time="1s"/> world`, `Hello <break time="1s" /> world`},
+		{"break_ms", `<speak>A<break time="250ms"/>B</speak>`, `A <break time="0.25s" /> B`},
+		{"break_strength", `<speak>A<break strength="strong"/>B<break strength="none"/>C</speak>`, `A <break time="0.8s" /> B C`},
+		{"break_default", `<speak>A<break/>B</speak>`, `A <break time="0.5s" /> B`},
+		{"break_clamped", `<speak>A<break time="10s"/>B</speak>`, `A <break time="3s" /> B`},
+		{"sub", `<speak><sub alias="World Wide Web">WWW</sub> rocks</speak>`, "World Wide Web rocks"},
+		{"say_as_characters", `<speak>Code <say-as interpret-as="characters">AB1</say-as></speak>`, "Code A B 1"},
+		{"say_as_other", `<speak><say-as interpret-as="date" format="ymd">2024-03-15</say-as></speak>`, "2024-03-15"},
+		{"phoneme", `<speak>Say <phoneme alphabet="ipa" ph="təˈmɑːtoʊ">tomato</phoneme>.</speak>`, `Say <phoneme alphabet="ipa" ph="təˈmɑːtoʊ">tomato</phoneme> .`},
+		{"phoneme_unsupported_alphabet", `<speak><phoneme alphabet="x-sampa" ph="t@mA:toU">tomato</phoneme></speak>`, "tomato"},
+		{"prosody_lang_degrade", `<speak><prosody rate="slow" pitch="+2st">Slowly</prosody> and <lang xml:lang="de-DE">Guten Tag</lang></speak>`, "Slowly and Guten Tag"},
+		{"nested_markup", "<speak><p><s>First.</s><s>Second <emphasis>now</emphasis>.</s></p></speak>", "First. Second now."},
+		{"mark_dropped", `<speak>A<mark name="m1"/> B</speak>`, "A B"},
+		{"entities", "<speak>Tom &amp; Jerry&nbsp;ok</speak>", "Tom & Jerry ok"},
+		{"whitespace", "<speak>\n  Hello\n    there\n</speak>", "Hello there"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := Render(segments, nil); got != tt.want {
+				t.Errorf("Render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, doc := range []string{
+		"<speak>unclosed",
+		"<speak><break time=\"soon\"/></speak>",
+		"<speak><break strength=\"huge\"/></speak>",
+		"<speak></p></speak>",
+	} {
+		if _, err := Parse(doc); err == nil {
+			t.Errorf("Parse(%q): expected error", doc)
+		}
+	}
+}
+
+func TestRenderTransformSkipsTags(t *testing.T) {
+	segments, err := Parse(`<speak>pause 2s <break time="1.5s"/> then <phoneme alphabet="ipa" ph="næp">NAP</phoneme></speak>`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got := Render(segments, strings.ToUpper)
+	want := `PAUSE 2S <break time="1.5s" /> THEN <phoneme alphabet="ipa" ph="næp">NAP</phoneme>`
+	if got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestParseSegments(t *testing.T) {
+	segments, err := Parse(`<speak>One<break time="2s"/>Two</speak>`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(segments) != 3 || segments[0].Text != "One" || segments[1].Break != 2*time.Second || segments[2].Text != "Two" {
+		t.Errorf("segments = %+v", segments)
+	}
+}
@@ -0,0 +1,423 @@
+package textnorm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pluralForm is the noun form a counted amount needs.
+type pluralForm int
+
+const (
+	formOne pluralForm = iota
+	formFew
+	formMany
+	formFraction
+)
+
+// forms holds a noun in every plural form a language distinguishes, plus
+// the gender numbers must agree with.
+type forms struct {
+	words  [4]string
+	gender gender
+}
+
+// enForms is for languages with only singular and plural.
+func enForms(one, other string) forms {
+	return forms{words: [4]string{one, other, other, other}, gender: masculine}
+}
+
+func plForms(one, few, many, fraction string) forms {
+	return forms{words: [4]string{one, few, many, fraction}, gender: masculine}
+}
+
+func (f forms) fem() forms {
+	f.gender = feminine
+	return f
+}
+
+func (f forms) neut() forms {
+	f.gender = neuter
+	return f
+}
+
+func (f forms) one() string { return f.words[formOne] }
+
+func (f forms) pick(p pluralForm) string { return f.words[p] }
+
+func singularPlural(n int64) pluralForm {
+	if n == 1 {
+		return formOne
+	}
+	return formMany
+}
+
+func plPlural(n int64) pluralForm {
+	switch {
+	case n == 1:
+		return formOne
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return formFew
+	default:
+		return formMany
+	}
+}
+
+// abbreviation expands a written abbreviation. sentenceEnd marks ones that
+// commonly close a sentence, whose period is kept there. beforeNumber marks
+// ambiguous ones only expanded in front of a number.
+type abbreviation struct {
+	short, long  string
+	sentenceEnd  bool
+	beforeNumber bool
+}
+
+// language collects everything a locale-specific rule needs.
+type language struct {
+	code string
+
+	// decimal and thousands are the separators used in written numbers.
+	decimal   string
+	thousands string
+
+	point, minus string
+	dot, at      string
+
+	cardinal func(n int64, g gender) string
+	plural   func(n int64) pluralForm
+
+	// year reads a four-digit number as a year, or returns false where the
+	// cardinal is said; nil reads every year as a cardinal.
+	year func(n int64) (string, bool)
+
+	// ratio joins the sides of a ratio ("three to two") and through the ends
+	// of a range ("ten to twenty").
+	ratio, through string
+
+	// and joins major and minor currency units ("five dollars and ten
+	// cents"); empty for languages that just list them.
+	and        string
+	currencies map[string][2]forms
+
+	units         map[string]forms
+	abbreviations []abbreviation
+
+	formatDate func(day, month int, year string) string
+	formatTime func(hour, minute int, spell bool) string
+
+	// ordinalSuffixes are written ordinal endings such as "1st", if any.
+	ordinalSuffixes []string
+	ordinal         func(n int64) string
+}
+
+var languages = map[string]*language{
+	"en": english,
+	"de": german,
+	"pl": polish,
+}
+
+// lookupLanguage returns the rules for a language code such as "en-GB", or
+// nil when the language is unsupported.
+func lookupLanguage(code string) *language {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	return languages[code]
+}
+
+var currencySymbols = map[string]string{
+	"$":  "USD",
+	"€":  "EUR",
+	"£":  "GBP",
+	"zł": "PLN",
+}
+
+var english = &language{
+	code:      "en",
+	decimal:   ".",
+	thousands: ",",
+	point:     "point",
+	minus:     "minus",
+	dot:       "dot",
+	at:        "at",
+	cardinal:  enCardinal,
+	plural:    singularPlural,
+	year:      enYear,
+	ratio:     "to",
+	through:   "to",
+	and:       "and",
+	currencies: map[string][2]forms{
+		"USD": {enForms("dollar", "dollars"), enForms("cent", "cents")},
+		"EUR": {enForms("euro", "euros"), enForms("cent", "cents")},
+		"GBP": {enForms("pound", "pounds"), enForms("penny", "pence")},
+		"PLN": {enForms("zloty", "zlotys"), enForms("grosz", "groszy")},
+	},
+	units: map[string]forms{
+		"km/h": enForms("kilometer per hour", "kilometers per hour"),
+		"mph":  enForms("mile per hour", "miles per hour"),
+		"km":   enForms("kilometer", "kilometers"),
+		"m":    enForms("meter", "meters"),
+		"cm":   enForms("centimeter", "centimeters"),
+		"mm":   enForms("millimeter", "millimeters"),
+		"kg":   enForms("kilogram", "kilograms"),
+		"g":    enForms("gram", "grams"),
+		"mg":   enForms("milligram", "milligrams"),
+		"l":    enForms("liter", "liters"),
+		"ml":   enForms("milliliter", "milliliters"),
+		"°C":   enForms("degree Celsius", "degrees Celsius"),
+		"°F":   enForms("degree Fahrenheit", "degrees Fahrenheit"),
+		"°":    enForms("degree", "degrees"),
+		"%":    enForms("percent", "percent"),
+		"KB":   enForms("kilobyte", "kilobytes"),
+		"kB":   enForms("kilobyte", "kilobytes"),
+		"MB":   enForms("megabyte", "megabytes"),
+		"GB":   enForms("gigabyte", "gigabytes"),
+		"TB":   enForms("terabyte", "terabytes"),
+		"Hz":   enForms("hertz", "hertz"),
+		"kHz":  enForms("kilohertz", "kilohertz"),
+		"MHz":  enForms("megahertz", "megahertz"),
+		"GHz":  enForms("gigahertz", "gigahertz"),
+		"ms":   enForms("millisecond", "milliseconds"),
+		"s":    enForms("second", "seconds"),
+		"min":  enForms("minute", "minutes"),
+		"h":    enForms("hour", "hours"),
+		"W":    enForms("watt", "watts"),
+		"kW":   enForms("kilowatt", "kilowatts"),
+		"kWh":  enForms("kilowatt hour", "kilowatt hours"),
+		"V":    enForms("volt", "volts"),
+	},
+	abbreviations: []abbreviation{
+		{short: "e.g.", long: "for example"},
+		{short: "i.e.", long: "that is"},
+		{short: "etc.", long: "et cetera", sentenceEnd: true},
+		{short: "vs.", long: "versus"},
+		{short: "approx.", long: "approximately"},
+		{short: "Dr.", long: "Doctor"},
+		{short: "Mr.", long: "Mister"},
+		{short: "Mrs.", long: "Missus"},
+		{short: "Prof.", long: "Professor"},
+		{short: "No.", long: "number", beforeNumber: true},
+	},
+	formatDate: func(day, month int, year string) string {
+		return enMonths[month-1] + " " + enOrdinal(int64(day)) + ", " + year
+	},
+	formatTime: func(hour, minute int, spell bool) string {
+		h, m := count(enCardinal, hour, spell), count(enCardinal, minute, spell)
+		switch {
+		case minute == 0:
+			return h + " o'clock"
+		case minute < 10:
+			return h + " oh " + m
+		default:
+			return h + " " + m
+		}
+	},
+	ordinalSuffixes: []string{"st", "nd", "rd", "th"},
+	ordinal:         enOrdinal,
+}
+
+var enMonths = []string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+var german = &language{
+	code:      "de",
+	decimal:   ",",
+	thousands: ".",
+	point:     "Komma",
+	minus:     "minus",
+	dot:       "Punkt",
+	at:        "at",
+	cardinal:  deCardinal,
+	plural:    singularPlural,
+	year:      deYear,
+	ratio:     "zu",
+	through:   "bis",
+	currencies: map[string][2]forms{
+		"USD": {enForms("Dollar", "Dollar"), enForms("Cent", "Cent")},
+		"EUR": {enForms("Euro", "Euro"), enForms("Cent", "Cent")},
+		"GBP": {enForms("Pfund", "Pfund"), enForms("Penny", "Pence")},
+		"PLN": {enForms("Złoty", "Złoty"), enForms("Groschen", "Groschen")},
+	},
+	units: map[string]forms{
+		"km/h": enForms("Kilometer pro Stunde", "Kilometer pro Stunde"),
+		"mph":  enForms("Meile pro Stunde", "Meilen pro Stunde").fem(),
+		"km":   enForms("Kilometer", "Kilometer"),
+		"m":    enForms("Meter", "Meter"),
+		"cm":   enForms("Zentimeter", "Zentimeter"),
+		"mm":   enForms("Millimeter", "Millimeter"),
+		"kg":   enForms("Kilogramm", "Kilogramm"),
+		"g":    enForms("Gramm", "Gramm"),
+		"mg":   enForms("Milligramm", "Milligramm"),
+		"l":    enForms("Liter", "Liter"),
+		"ml":   enForms("Milliliter", "Milliliter"),
+		"°C":   enForms("Grad Celsius", "Grad Celsius"),
+		"°F":   enForms("Grad Fahrenheit", "Grad Fahrenheit"),
+		"°":    enForms("Grad", "Grad"),
+		"%":    enForms("Prozent", "Prozent"),
+		"KB":   enForms("Kilobyte", "Kilobyte"),
+		"kB":   enForms("Kilobyte", "Kilobyte"),
+		"MB":   enForms("Megabyte", "Megabyte"),
+		"GB":   enForms("Gigabyte", "Gigabyte"),
+		"TB":   enForms("Terabyte", "Terabyte"),
+		"Hz":   enForms("Hertz", "Hertz"),
+		"kHz":  enForms("Kilohertz", "Kilohertz"),
+		"MHz":  enForms("Megahertz", "Megahertz"),
+		"GHz":  enForms("Gigahertz", "Gigahertz"),
+		"ms":   enForms("Millisekunde", "Millisekunden").fem(),
+		"s":    enForms("Sekunde", "Sekunden").fem(),
+		"min":  enForms("Minute", "Minuten").fem(),
+		"h":    enForms("Stunde", "Stunden").fem(),
+		"W":    enForms("Watt", "Watt"),
+		"kW":   enForms("Kilowatt", "Kilowatt"),
+		"kWh":  enForms("Kilowattstunde", "Kilowattstunden").fem(),
+		"V":    enForms("Volt", "Volt"),
+		"Mio.": enForms("Million", "Millionen").fem(),
+		"Mrd.": enForms("Milliarde", "Milliarden").fem(),
+	},
+	abbreviations: []abbreviation{
+		{short: "z.B.", long: "zum Beispiel"},
+		{short: "z. B.", long: "zum Beispiel"},
+		{short: "d.h.", long: "das heißt"},
+		{short: "d. h.", long: "das heißt"},
+		{short: "u.a.", long: "unter anderem"},
+		{short: "u. a.", long: "unter anderem"},
+		{short: "usw.", long: "und so weiter", sentenceEnd: true},
+		{short: "etc.", long: "et cetera", sentenceEnd: true},
+		{short: "bzw.", long: "beziehungsweise"},
+		{short: "ca.", long: "circa"},
+		{short: "evtl.", long: "eventuell"},
+		{short: "ggf.", long: "gegebenenfalls"},
+		{short: "inkl.", long: "inklusive"},
+		{short: "vgl.", long: "vergleiche"},
+		{short: "Nr.", long: "Nummer"},
+		{short: "Dr.", long: "Doktor"},
+		{short: "Prof.", long: "Professor"},
+	},
+	formatDate: func(day, month int, year string) string {
+		return deOrdinal(day) + " " + deMonths[month-1] + " " + year
+	},
+	formatTime: func(hour, minute int, spell bool) string {
+		// "ein Uhr", not "eins Uhr".
+		h := strconv.Itoa(hour)
+		if spell {
+			h = deCardinal(int64(hour), masculine)
+		}
+		if minute == 0 {
+			return h + " Uhr"
+		}
+		return h + " Uhr " + count(deCardinal, minute, spell)
+	},
+}
+
+var deMonths = []string{
+	"Januar", "Februar", "März", "April", "Mai", "Juni",
+	"Juli", "August", "September", "Oktober", "November", "Dezember",
+}
+
+var polish = &language{
+	code:      "pl",
+	decimal:   ",",
+	thousands: " ",
+	point:     "przecinek",
+	minus:     "minus",
+	dot:       "kropka",
+	at:        "małpa",
+	cardinal:  plCardinal,
+	plural:    plPlural,
+	ratio:     "do",
+	through:   "do",
+	currencies: map[string][2]forms{
+		"USD": {plForms("dolar", "dolary", "dolarów", "dolara"), plForms("cent", "centy", "centów", "centa")},
+		"EUR": {plForms("euro", "euro", "euro", "euro").neut(), plForms("cent", "centy", "centów", "centa")},
+		"GBP": {plForms("funt", "funty", "funtów", "funta"), plForms("pens", "pensy", "pensów", "pensa")},
+		"PLN": {plForms("złoty", "złote", "złotych", "złotego"), plForms("grosz", "grosze", "groszy", "grosza")},
+	},
+	units: map[string]forms{
+		"km/h": plForms("kilometr na godzinę", "kilometry na godzinę", "kilometrów na godzinę", "kilometra na godzinę"),
+		"mph":  plForms("mila na godzinę", "mile na godzinę", "mil na godzinę", "mili na godzinę").fem(),
+		"km":   plForms("kilometr", "kilometry", "kilometrów", "kilometra"),
+		"m":    plForms("metr", "metry", "metrów", "metra"),
+		"cm":   plForms("centymetr", "centymetry", "centymetrów", "centymetra"),
+		"mm":   plForms("milimetr", "milimetry", "milimetrów", "milimetra"),
+		"kg":   plForms("kilogram", "kilogramy", "kilogramów", "kilograma"),
+		"g":    plForms("gram", "gramy", "gramów", "grama"),
+		"mg":   plForms("miligram", "miligramy", "miligramów", "miligrama"),
+		"l":    plForms("litr", "litry", "litrów", "litra"),
+		"ml":   plForms("mililitr", "mililitry", "mililitrów", "mililitra"),
+		"°C":   plForms("stopień Celsjusza", "stopnie Celsjusza", "stopni Celsjusza", "stopnia Celsjusza"),
+		"°F":   plForms("stopień Fahrenheita", "stopnie Fahrenheita", "stopni Fahrenheita", "stopnia Fahrenheita"),
+		"°":    plForms("stopień", "stopnie", "stopni", "stopnia"),
+		"%":    plForms("procent", "procent", "procent", "procent"),
+		"KB":   plForms("kilobajt", "kilobajty", "kilobajtów", "kilobajta"),
+		"kB":   plForms("kilobajt", "kilobajty", "kilobajtów", "kilobajta"),
+		"MB":   plForms("megabajt", "megabajty", "megabajtów", "megabajta"),
+		"GB":   plForms("gigabajt", "gigabajty", "gigabajtów", "gigabajta"),
+		"TB":   plForms("terabajt", "terabajty", "terabajtów", "terabajta"),
+		"Hz":   plForms("herc", "herce", "herców", "herca"),
+		"kHz":  plForms("kiloherc", "kiloherce", "kiloherców", "kiloherca"),
+		"MHz":  plForms("megaherc", "megaherce", "megaherców", "megaherca"),
+		"GHz":  plForms("gigaherc", "gigaherce", "gigaherców", "gigaherca"),
+		"ms":   plForms("milisekunda", "milisekundy", "milisekund", "milisekundy").fem(),
+		"s":    plForms("sekunda", "sekundy", "sekund", "sekundy").fem(),
+		"min":  plForms("minuta", "minuty", "minut", "minuty").fem(),
+		"h":    plForms("godzina", "godziny", "godzin", "godziny").fem(),
+		"W":    plForms("wat", "waty", "watów", "wata"),
+		"kW":   plForms("kilowat", "kilowaty", "kilowatów", "kilowata"),
+		"kWh":  plForms("kilowatogodzina", "kilowatogodziny", "kilowatogodzin", "kilowatogodziny").fem(),
+		"V":    plForms("wolt", "wolty", "woltów", "wolta"),
+		"tys.": plScales[1],
+		"mln":  plScales[2],
+		"mld":  plScales[3],
+	},
+	abbreviations: []abbreviation{
+		{short: "np.", long: "na przykład"},
+		{short: "tzn.", long: "to znaczy"},
+		{short: "tj.", long: "to jest"},
+		{short: "m.in.", long: "między innymi"},
+		{short: "itd.", long: "i tak dalej", sentenceEnd: true},
+		{short: "itp.", long: "i tym podobne", sentenceEnd: true},
+		{short: "ok.", long: "około", beforeNumber: true},
+		{short: "godz.", long: "godzina"},
+		{short: "ul.", long: "ulica"},
+		{short: "nr", long: "numer"},
+		{short: "dr", long: "doktor"},
+		{short: "prof.", long: "profesor"},
+		{short: "mgr", long: "magister"},
+		{short: "inż.", long: "inżynier"},
+	},
+	formatDate: func(day, month int, year string) string {
+		return plOrdinal(day) + " " + plMonths[month-1] + " " + year
+	},
+	formatTime: func(hour, minute int, spell bool) string {
+		h := plHourOrdinals[hour]
+		if !spell {
+			h = strconv.Itoa(hour)
+		}
+		switch {
+		case minute == 0:
+			return h
+		case minute < 10:
+			return h + " zero " + count(plCardinal, minute, spell)
+		default:
+			return h + " " + count(plCardinal, minute, spell)
+		}
+	},
+}
+
+// plMonths are in the genitive, as used in dates.
+var plMonths = []string{
+	"stycznia", "lutego", "marca", "kwietnia", "maja", "czerwca",
+	"lipca", "sierpnia", "września", "października", "listopada", "grudnia",
+}
+
+// count renders a small number in words, or as digits when number spelling
+// is turned off.
+func count(cardinal func(int64, gender) string, n int, spell bool) string {
+	if !spell {
+		return strconv.Itoa(n)
+	}
+	return cardinal(int64(n), noGender)
+}
@@ -0,0 +1,65 @@
+package textnorm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// markdownRule is one rewrite in stripMarkdown; order matters.
+type markdownRule struct {
+	pattern *regexp.Regexp
+	replace string
+}
+
+var markdownRules = []markdownRule{
+	// Code blocks are dropped entirely: code read aloud is noise. An
+	// unterminated fence swallows the rest of the text.
+	{regexp.MustCompile("(?ms)^[ \t]*```.*?^[ \t]*```[ \t]*$"), ""},
+	{regexp.MustCompile("(?ms)^[ \t]*~~~.*?^[ \t]*~~~[ \t]*$"), ""},
+	{regexp.MustCompile("(?s)```.*\\z"), ""},
+	{regexp.MustCompile("`([^`\n]*)`"), "$1"},
+
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`), "$1"},
+
+	{regexp.MustCompile(`(?m)^[ \t]*(?:[-*_][ \t]*){3,}$`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+(.*?)[ \t#]*$`), "$1"},
+	{regexp.MustCompile(`(?m)^[ \t]*>+[ \t]?`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*(?:[-*+]|\d+[.)])[ \t]+`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)+\|?[ \t]*$`), ""},
+
+	{regexp.MustCompile(`\*\*([^*\n]+)\*\*`), "$1"},
+	{regexp.MustCompile(`__([^_\n]+)__`), "$1"},
+	{regexp.MustCompile(`~~([^~\n]+)~~`), "$1"},
+}
+
+// emphasisRules match single-marker emphasis, which is only removed where
+// the markers are not glued to a word: "a*b*c" and "snake_case" are kept.
+var emphasisRules = []*regexp.Regexp{
+	regexp.MustCompile(`\*([^*\s](?:[^*\n]*[^*\s])?)\*`),
+	regexp.MustCompile(`_([^_\n]+)_`),
+}
+
+var tableRow = regexp.MustCompile(`(?m)^[ \t]*\|(.*)\|[ \t]*$`)
+
+// stripMarkdown removes the formatting an LLM typically emits so it is not
+// read out: emphasis markers, headings, list bullets, quotes, tables, links
+// (their text is kept) and code.
+func stripMarkdown(text string) string {
+	for _, r := range markdownRules {
+		text = r.pattern.ReplaceAllString(text, r.replace)
+	}
+	for _, re := range emphasisRules {
+		text = replaceAll(re, text, func(m []string, before, after string) (string, bool) {
+			return m[1], standalone(before, after)
+		})
+	}
+	return tableRow.ReplaceAllStringFunc(text, func(row string) string {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(row), "|"), "|")
+		for i, c := range cells {
+			cells[i] = strings.TrimSpace(c)
+		}
+		return strings.Join(cells, ", ")
+	})
+}
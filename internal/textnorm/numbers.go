@@ -0,0 +1,350 @@
+package textnorm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxSpelled bounds the numbers spelled as a whole; longer digit runs are
+// read digit by digit, which is how people read IDs and phone numbers anyway.
+const maxSpelled = 999_999_999_999_999
+
+// gender selects the grammatical gender a counted noun needs from the
+// numbers one and two. noGender is plain counting ("eins" in German).
+type gender int
+
+const (
+	noGender gender = iota
+	masculine
+	feminine
+	neuter
+)
+
+var enOnes = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+	"seventeen", "eighteen", "nineteen",
+}
+
+var enTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+var enScales = []string{"", "thousand", "million", "billion", "trillion"}
+
+func enBelow1000(n int64) string {
+	var parts []string
+	if h := n / 100; h > 0 {
+		parts = append(parts, enOnes[h]+" hundred")
+	}
+	n %= 100
+	switch {
+	case n == 0:
+	case n < 20:
+		parts = append(parts, enOnes[n])
+	case n%10 == 0:
+		parts = append(parts, enTens[n/10])
+	default:
+		parts = append(parts, enTens[n/10]+"-"+enOnes[n%10])
+	}
+	return strings.Join(parts, " ")
+}
+
+func enCardinal(n int64, _ gender) string {
+	if n == 0 {
+		return enOnes[0]
+	}
+	var parts []string
+	for i, group := range groupsOf1000(n) {
+		if group == 0 {
+			continue
+		}
+		words := enBelow1000(group)
+		if scale := enScales[i]; scale != "" {
+			words += " " + scale
+		}
+		parts = append([]string{words}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+var enOrdinalWords = map[string]string{
+	"one": "first", "two": "second", "three": "third", "five": "fifth",
+	"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+}
+
+// enOrdinal turns a spelled cardinal into its ordinal ("twenty-one" →
+// "twenty-first").
+func enOrdinal(n int64) string {
+	words := enCardinal(n, noGender)
+	cut := strings.LastIndexAny(words, " -") + 1
+	last := words[cut:]
+	switch {
+	case enOrdinalWords[last] != "":
+		last = enOrdinalWords[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return words[:cut] + last
+}
+
+// enYear reads years from 1100 to 2099 in pairs of digits ("nineteen
+// ninety-nine", "nineteen oh five", "twenty twenty-four"); 2000 to 2009 are
+// read as cardinals ("two thousand five").
+func enYear(n int64) (string, bool) {
+	if n < 1100 || n > 2099 || n >= 2000 && n < 2010 {
+		return "", false
+	}
+	century, rest := enCardinal(n/100, noGender), n%100
+	switch {
+	case rest == 0:
+		return century + " hundred", true
+	case rest < 10:
+		return century + " oh " + enOnes[rest], true
+	}
+	return century + " " + enCardinal(rest, noGender), true
+}
+
+var deOnes = []string{
+	"null", "eins", "zwei", "drei", "vier", "fünf", "sechs", "sieben", "acht", "neun",
+	"zehn", "elf", "zwölf", "dreizehn", "vierzehn", "fünfzehn", "sechzehn",
+	"siebzehn", "achtzehn", "neunzehn",
+}
+
+var deTens = []string{"", "", "zwanzig", "dreißig", "vierzig", "fünfzig", "sechzig", "siebzig", "achtzig", "neunzig"}
+
+// deBelow1000 spells 1..999 as one word. German drops the final "s" of
+// "eins" inside compounds, so the caller decides through standalone.
+func deBelow1000(n int64, standalone bool) string {
+	var b strings.Builder
+	if h := n / 100; h > 0 {
+		if h == 1 {
+			b.WriteString("ein")
+		} else {
+			b.WriteString(deOnes[h])
+		}
+		b.WriteString("hundert")
+	}
+	n %= 100
+	switch {
+	case n == 0:
+	case n == 1 && !standalone:
+		b.WriteString("ein")
+	case n < 20:
+		b.WriteString(deOnes[n])
+	case n%10 == 0:
+		b.WriteString(deTens[n/10])
+	default:
+		unit := deOnes[n%10]
+		if n%10 == 1 {
+			unit = "ein"
+		}
+		b.WriteString(unit + "und" + deTens[n/10])
+	}
+	return b.String()
+}
+
+var deLargeScales = [][2]string{
+	{"Million", "Millionen"},
+	{"Milliarde", "Milliarden"},
+	{"Billion", "Billionen"},
+}
+
+func deCardinal(n int64, g gender) string {
+	if n == 0 {
+		return deOnes[0]
+	}
+	if n == 1 {
+		switch g {
+		case noGender:
+			return "eins"
+		case feminine:
+			return "eine"
+		}
+		return "ein"
+	}
+	groups := groupsOf1000(n)
+	var parts []string
+	for i := len(groups) - 1; i >= 2; i-- {
+		switch group := groups[i]; group {
+		case 0:
+		case 1:
+			parts = append(parts, "eine "+deLargeScales[i-2][0])
+		default:
+			parts = append(parts, deBelow1000(group, false)+" "+deLargeScales[i-2][1])
+		}
+	}
+	var low strings.Builder
+	if len(groups) > 1 && groups[1] > 0 {
+		low.WriteString(deBelow1000(groups[1], false) + "tausend")
+	}
+	if groups[0] > 0 {
+		low.WriteString(deBelow1000(groups[0], g == noGender))
+	}
+	if low.Len() > 0 {
+		parts = append(parts, low.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// deYear reads years from 1100 to 1999 in hundreds
+// ("neunzehnhundertneunundneunzig"); later years are read as cardinals.
+func deYear(n int64) (string, bool) {
+	if n < 1100 || n > 1999 {
+		return "", false
+	}
+	year := deOnes[n/100] + "hundert"
+	if rest := n % 100; rest > 0 {
+		year += deBelow1000(rest, true)
+	}
+	return year, true
+}
+
+var deOrdinalStems = map[int]string{1: "ers", 3: "drit", 7: "sieb", 8: "ach"}
+
+// deOrdinal spells a day of the month in the form used after "am" and
+// "den" ("dritten").
+func deOrdinal(n int) string {
+	if n >= 20 {
+		return deCardinal(int64(n), noGender) + "sten"
+	}
+	if stem, ok := deOrdinalStems[n]; ok {
+		return stem + "ten"
+	}
+	return deOnes[n] + "ten"
+}
+
+var plOnes = []string{
+	"zero", "jeden", "dwa", "trzy", "cztery", "pięć", "sześć", "siedem", "osiem", "dziewięć",
+	"dziesięć", "jedenaście", "dwanaście", "trzynaście", "czternaście", "piętnaście",
+	"szesnaście", "siedemnaście", "osiemnaście", "dziewiętnaście",
+}
+
+var plTens = []string{
+	"", "", "dwadzieścia", "trzydzieści", "czterdzieści", "pięćdziesiąt",
+	"sześćdziesiąt", "siedemdziesiąt", "osiemdziesiąt", "dziewięćdziesiąt",
+}
+
+var plHundreds = []string{
+	"", "sto", "dwieście", "trzysta", "czterysta", "pięćset",
+	"sześćset", "siedemset", "osiemset", "dziewięćset",
+}
+
+var plScales = []forms{
+	{},
+	plForms("tysiąc", "tysiące", "tysięcy", "tysiąca"),
+	plForms("milion", "miliony", "milionów", "miliona"),
+	plForms("miliard", "miliardy", "miliardów", "miliarda"),
+	plForms("bilion", "biliony", "bilionów", "biliona"),
+}
+
+func plBelow1000(n int64, g gender) string {
+	var parts []string
+	if h := n / 100; h > 0 {
+		parts = append(parts, plHundreds[h])
+	}
+	n %= 100
+	if n >= 20 {
+		parts = append(parts, plTens[n/10])
+		n %= 10
+	}
+	if n > 0 {
+		word := plOnes[n]
+		if n == 2 && g == feminine {
+			word = "dwie"
+		}
+		parts = append(parts, word)
+	}
+	return strings.Join(parts, " ")
+}
+
+func plCardinal(n int64, g gender) string {
+	switch n {
+	case 0:
+		return plOnes[0]
+	case 1:
+		switch g {
+		case feminine:
+			return "jedna"
+		case neuter:
+			return "jedno"
+		}
+		return "jeden"
+	}
+	var parts []string
+	for i, group := range groupsOf1000(n) {
+		if group == 0 {
+			continue
+		}
+		var words string
+		if i == 0 {
+			words = plBelow1000(group, g)
+		} else if group == 1 {
+			words = plScales[i].one()
+		} else {
+			words = plBelow1000(group, noGender) + " " + plScales[i].pick(plPlural(group))
+		}
+		parts = append([]string{words}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+var plDayOrdinals = []string{
+	"", "pierwszego", "drugiego", "trzeciego", "czwartego", "piątego", "szóstego",
+	"siódmego", "ósmego", "dziewiątego", "dziesiątego", "jedenastego", "dwunastego",
+	"trzynastego", "czternastego", "piętnastego", "szesnastego", "siedemnastego",
+	"osiemnastego", "dziewiętnastego",
+}
+
+// plOrdinal spells a day of the month in the genitive used in dates
+// ("piętnastego marca").
+func plOrdinal(n int) string {
+	switch {
+	case n < 20:
+		return plDayOrdinals[n]
+	case n < 30:
+		return strings.TrimSpace("dwudziestego " + plDayOrdinals[n-20])
+	default:
+		return strings.TrimSpace("trzydziestego " + plDayOrdinals[n-30])
+	}
+}
+
+var plHourOrdinals = []string{
+	"zero", "pierwsza", "druga", "trzecia", "czwarta", "piąta", "szósta", "siódma",
+	"ósma", "dziewiąta", "dziesiąta", "jedenasta", "dwunasta", "trzynasta",
+	"czternasta", "piętnasta", "szesnasta", "siedemnasta", "osiemnasta",
+	"dziewiętnasta", "dwudziesta", "dwudziesta pierwsza", "dwudziesta druga",
+	"dwudziesta trzecia",
+}
+
+// groupsOf1000 splits n into base-1000 digits, least significant first.
+func groupsOf1000(n int64) []int64 {
+	var groups []int64
+	for n > 0 {
+		groups = append(groups, n%1000)
+		n /= 1000
+	}
+	return groups
+}
+
+// spellDigits reads a digit string one digit at a time.
+func spellDigits(l *language, digits string) string {
+	words := make([]string, 0, len(digits))
+	for _, d := range digits {
+		words = append(words, l.cardinal(int64(d-'0'), noGender))
+	}
+	return strings.Join(words, " ")
+}
+
+// spellInteger reads a digit string as a whole number, falling back to
+// digit-by-digit reading for leading zeros and very long runs.
+func spellInteger(l *language, digits string, g gender) string {
+	if len(digits) > 1 && digits[0] == '0' {
+		return spellDigits(l, digits)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n > maxSpelled {
+		return spellDigits(l, digits)
+	}
+	return l.cardinal(n, g)
+}
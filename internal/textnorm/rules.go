@@ -0,0 +1,525 @@
+package textnorm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// patterns are the compiled expressions for one language.
+type patterns struct {
+	amount        *regexp.Regexp // a whole written number, anchored
+	token         *regexp.Regexp // a digit run with separators
+	currency      *regexp.Regexp
+	units         *regexp.Regexp
+	abbreviations *regexp.Regexp
+	ordinal       *regexp.Regexp
+	dates         []*regexp.Regexp
+}
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()\[\]{}]+`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+`)
+	timePattern  = regexp.MustCompile(`([01]?\d|2[0-3]):([0-5]\d)`)
+	isoDate      = regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+	dottedDate   = regexp.MustCompile(`(\d{1,2})\.(\d{1,2})\.(\d{4})`)
+	enMonthDay   = regexp.MustCompile(`(` + strings.Join(enMonths, "|") + `) (\d{1,2})(?:st|nd|rd|th)?`)
+	ratioPattern = regexp.MustCompile(`(\d+):(\d+)`)
+	digitGroups  = regexp.MustCompile(`\d+(?:-\d+)+`)
+)
+
+var compiled = map[*language]*patterns{}
+
+func init() {
+	for _, l := range languages {
+		compiled[l] = compile(l)
+	}
+}
+
+func compile(l *language) *patterns {
+	thousands := regexp.QuoteMeta(l.thousands)
+	if l.thousands == " " {
+		thousands = `[ \x{a0}]`
+	}
+	decimal := regexp.QuoteMeta(l.decimal)
+	amount := fmt.Sprintf(`\d{1,3}(?:%s\d{3})+(?:%s\d+)?|\d+(?:%s\d+)?`, thousands, decimal, decimal)
+
+	symbols := make([]string, 0, len(currencySymbols)+len(l.currencies))
+	for sym := range currencySymbols {
+		symbols = append(symbols, sym)
+	}
+	for code := range l.currencies {
+		symbols = append(symbols, code)
+	}
+	currency := alternation(symbols)
+
+	p := &patterns{
+		amount:        regexp.MustCompile(`^(?:` + amount + `)$`),
+		token:         regexp.MustCompile(`-?\d+(?:[.,]\d+|` + thousands + `\d{3}\b)*`),
+		currency:      regexp.MustCompile(`(?:` + currency + `)\s?(?:` + amount + `)|(?:` + amount + `)\s?(?:` + currency + `)`),
+		units:         regexp.MustCompile(`(` + amount + `)\s?(` + alternation(mapKeys(l.units)) + `)`),
+		abbreviations: regexp.MustCompile(abbreviationPattern(l.abbreviations)),
+		dates:         []*regexp.Regexp{isoDate},
+	}
+	if l.decimal != "." {
+		p.dates = append(p.dates, dottedDate)
+	}
+	if l == english {
+		p.dates = append(p.dates, enMonthDay)
+	}
+	if len(l.ordinalSuffixes) > 0 {
+		p.ordinal = regexp.MustCompile(`(\d+)(` + strings.Join(l.ordinalSuffixes, "|") + `)`)
+	}
+	return p
+}
+
+// alternation matches any of words, longest first so "km/h" wins over "km".
+func alternation(words []string) string {
+	sorted := slices.Clone(words)
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	for i, w := range sorted {
+		sorted[i] = regexp.QuoteMeta(w)
+	}
+	return strings.Join(sorted, "|")
+}
+
+func abbreviationPattern(abbrevs []abbreviation) string {
+	shorts := make([]string, len(abbrevs))
+	for i, a := range abbrevs {
+		shorts[i] = a.short
+	}
+	return alternation(shorts)
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// replaceAll is regexp.ReplaceAllStringFunc with access to submatches and to
+// the text on either side, so callers can check word boundaries the RE2
+// syntax cannot express. fn returns false to leave a match unchanged.
+func replaceAll(re *regexp.Regexp, text string, fn func(m []string, before, after string) (string, bool)) string {
+	var b strings.Builder
+	last := 0
+	for _, idx := range re.FindAllStringSubmatchIndex(text, -1) {
+		m := make([]string, len(idx)/2)
+		for i := range m {
+			if idx[2*i] >= 0 {
+				m[i] = text[idx[2*i]:idx[2*i+1]]
+			}
+		}
+		repl, ok := fn(m, text[:idx[0]], text[idx[1]:])
+		if !ok {
+			continue
+		}
+		b.WriteString(text[last:idx[0]])
+		b.WriteString(repl)
+		last = idx[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// endsWord reports whether s ends in a letter or digit.
+func endsWord(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return isAlnum(r)
+}
+
+// startsWord reports whether s starts with a letter or digit.
+func startsWord(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return isAlnum(r)
+}
+
+// standalone reports whether a match is not glued to surrounding words.
+func standalone(before, after string) bool {
+	return !endsWord(before) && !startsWord(after)
+}
+
+// expandURLs reduces links to their host name, which is what a listener
+// can use; paths and query strings are dropped.
+func expandURLs(text string, l *language) string {
+	return replaceAll(urlPattern, text, func(m []string, before, _ string) (string, bool) {
+		if strings.HasSuffix(before, "@") {
+			return "", false // the domain of an e-mail address
+		}
+		link := m[0]
+		trimmed := strings.TrimRight(link, ".,;:!?")
+		trailing := link[len(trimmed):]
+
+		host := trimmed
+		if i := strings.Index(host, "://"); i >= 0 {
+			host = host[i+3:]
+		}
+		if i := strings.IndexAny(host, "/?#"); i >= 0 {
+			host = host[:i]
+		}
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		if i := strings.Index(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		host = strings.TrimPrefix(strings.ToLower(host), "www.")
+		if l != nil {
+			host = strings.ReplaceAll(host, ".", " "+l.dot+" ")
+		}
+		return host + trailing, true
+	})
+}
+
+// expandEmails spells out the punctuation in e-mail addresses.
+func expandEmails(text string, l *language) string {
+	if l == nil {
+		return text
+	}
+	return replaceAll(emailPattern, text, func(m []string, before, after string) (string, bool) {
+		if !standalone(before, after) {
+			return "", false
+		}
+		addr := strings.ReplaceAll(m[0], "@", " "+l.at+" ")
+		return strings.ReplaceAll(addr, ".", " "+l.dot+" "), true
+	})
+}
+
+// splitAmount returns the integer and fractional digits of a number written
+// with l's separators.
+func splitAmount(l *language, s string) (whole, frac string) {
+	whole, frac, _ = strings.Cut(s, l.decimal)
+	whole = strings.NewReplacer(l.thousands, "", " ", "").Replace(whole)
+	return whole, frac
+}
+
+// pluralFor picks the noun form for an amount.
+func pluralFor(l *language, whole, frac string) pluralForm {
+	if strings.Trim(frac, "0") != "" {
+		return formFraction
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return formMany
+	}
+	return l.plural(n)
+}
+
+// sayAmount renders an amount in words, or normalized digits when number
+// spelling is off.
+func sayAmount(l *language, whole, frac string, g gender, spell bool) string {
+	if !spell {
+		if frac == "" {
+			return whole
+		}
+		return whole + l.decimal + frac
+	}
+	words := spellInteger(l, whole, g)
+	if frac != "" {
+		words += " " + l.point + " " + spellDigits(l, frac)
+	}
+	return words
+}
+
+// counted renders an amount followed by the matching form of a noun.
+func counted(l *language, whole, frac string, noun forms, spell bool) string {
+	return sayAmount(l, whole, frac, noun.gender, spell) + " " + noun.pick(pluralFor(l, whole, frac))
+}
+
+// expandCurrency reads prices such as "$5.99", "10 €" or "PLN 20".
+func expandCurrency(text string, l *language, spell bool) string {
+	p := compiled[l]
+	return replaceAll(p.currency, text, func(m []string, before, after string) (string, bool) {
+		if !standalone(before, after) {
+			return "", false
+		}
+		amount, code := splitCurrency(m[0], l)
+		names, ok := l.currencies[code]
+		if !ok {
+			return "", false
+		}
+		major, minor := names[0], names[1]
+		whole, frac := splitAmount(l, amount)
+
+		if len(frac) > 2 {
+			return counted(l, whole, frac, major, spell), true
+		}
+		cents := strings.TrimLeft((frac + "00")[:2], "0")
+		if frac == "" || cents == "" {
+			return counted(l, whole, "", major, spell), true
+		}
+		minorText := counted(l, cents, "", minor, spell)
+		if strings.Trim(whole, "0") == "" {
+			return minorText, true
+		}
+		parts := []string{counted(l, whole, "", major, spell)}
+		if l.and != "" {
+			parts = append(parts, l.and)
+		}
+		return strings.Join(append(parts, minorText), " "), true
+	})
+}
+
+// splitCurrency separates a matched price into its amount and ISO code.
+func splitCurrency(match string, l *language) (amount, code string) {
+	start := strings.IndexFunc(match, unicode.IsDigit)
+	end := strings.LastIndexFunc(match, unicode.IsDigit) + 1
+	amount = match[start:end]
+	symbol := strings.TrimSpace(match[:start] + match[end:])
+	if c, ok := currencySymbols[symbol]; ok {
+		return amount, c
+	}
+	return amount, symbol
+}
+
+// expandDates reads ISO dates, day.month.year dates where the language
+// writes them, English "March 15" and 24-hour times.
+func expandDates(text string, l *language, spell bool) string {
+	for _, re := range compiled[l].dates {
+		text = replaceAll(re, text, func(m []string, before, after string) (string, bool) {
+			if !standalone(before, after) {
+				return "", false
+			}
+			var day, month int
+			var year string
+			switch re {
+			case isoDate:
+				year = m[1]
+				month, _ = strconv.Atoi(m[2])
+				day, _ = strconv.Atoi(m[3])
+			case dottedDate:
+				day, _ = strconv.Atoi(m[1])
+				month, _ = strconv.Atoi(m[2])
+				year = m[3]
+			case enMonthDay:
+				day, _ = strconv.Atoi(m[2])
+				if day < 1 || day > 31 {
+					return "", false
+				}
+				return m[1] + " " + enOrdinal(int64(day)), true
+			}
+			if day < 1 || day > 31 || month < 1 || month > 12 {
+				return "", false
+			}
+			return l.formatDate(day, month, year), true
+		})
+	}
+	return replaceAll(timePattern, text, func(m []string, before, after string) (string, bool) {
+		if !standalone(before, after) || strings.HasSuffix(before, ":") || strings.HasPrefix(after, ":") {
+			return "", false
+		}
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		return l.formatTime(hour, minute, spell), true
+	})
+}
+
+// expandUnits reads measurements such as "5 km", "20°C" or "50%".
+func expandUnits(text string, l *language, spell bool) string {
+	return replaceAll(compiled[l].units, text, func(m []string, before, after string) (string, bool) {
+		if endsWord(before) || startsWord(after) {
+			return "", false
+		}
+		whole, frac := splitAmount(l, m[1])
+		return counted(l, whole, frac, l.units[m[2]], spell), true
+	})
+}
+
+// expandAbbreviations writes out common abbreviations.
+func expandAbbreviations(text string, l *language) string {
+	return replaceAll(compiled[l].abbreviations, text, func(m []string, before, after string) (string, bool) {
+		if endsWord(before) || strings.HasSuffix(before, ".") {
+			return "", false
+		}
+		if !strings.HasSuffix(m[0], ".") && startsWord(after) {
+			return "", false
+		}
+		a := l.abbreviation(m[0])
+		next := strings.TrimLeft(after, " \t")
+		if a.beforeNumber && !strings.HasPrefix(next, "-") && !startsWithDigit(next) {
+			return "", false
+		}
+		if a.sentenceEnd && (next == "" || next[0] == '\n' || startsUpper(next)) {
+			return a.long + ".", true
+		}
+		return a.long, true
+	})
+}
+
+func (l *language) abbreviation(short string) abbreviation {
+	for _, a := range l.abbreviations {
+		if a.short == short {
+			return a
+		}
+	}
+	return abbreviation{short: short, long: short}
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
+
+// expandNumbers spells out the digits the other rules left behind.
+func expandNumbers(text string, l *language) string {
+	p := compiled[l]
+	if p.ordinal != nil {
+		text = replaceAll(p.ordinal, text, func(m []string, before, after string) (string, bool) {
+			n, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil || n > maxSpelled || !standalone(before, after) {
+				return "", false
+			}
+			return l.ordinal(n), true
+		})
+	}
+	text = expandRatios(text, l)
+	text = expandDigitGroups(text, l)
+	return replaceAll(p.token, text, func(m []string, before, after string) (string, bool) {
+		token := m[0]
+		prefix := ""
+		if rest, ok := strings.CutPrefix(token, "-"); ok {
+			token = rest
+			if before == "" || strings.HasSuffix(before, " ") || strings.HasSuffix(before, "(") || strings.HasSuffix(before, "\n") {
+				prefix = l.minus + " "
+			} else {
+				prefix = "-"
+			}
+		} else if endsWord(before) {
+			return "", false
+		}
+		if startsWord(after) {
+			return "", false
+		}
+		if prefix == "" && len(token) == 4 {
+			if year, ok := sayYear(l, token); ok {
+				return year, true
+			}
+		}
+		if p.amount.MatchString(token) {
+			whole, frac := splitAmount(l, token)
+			return prefix + sayAmount(l, whole, frac, noGender, true), true
+		}
+		return prefix + spellSeparated(l, token), true
+	})
+}
+
+// sayYear reads a run of four digits as a year, if the language says years
+// differently from other numbers.
+func sayYear(l *language, digits string) (string, bool) {
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || l.year == nil || digits[0] == '0' {
+		return "", false
+	}
+	return l.year(n)
+}
+
+// expandRatios reads "3:2" as "three to two". A valid time the dates rule
+// left behind, because it is off, is still read as a time.
+func expandRatios(text string, l *language) string {
+	return replaceAll(ratioPattern, text, func(m []string, before, after string) (string, bool) {
+		if !standalone(before, after) || strings.HasSuffix(before, ":") || strings.HasPrefix(after, ":") {
+			return "", false
+		}
+		if timePattern.FindString(m[0]) == m[0] {
+			hour, _ := strconv.Atoi(m[1])
+			minute, _ := strconv.Atoi(m[2])
+			return l.formatTime(hour, minute, true), true
+		}
+		return spellInteger(l, m[1], noGender) + " " + l.ratio + " " + spellInteger(l, m[2], noGender), true
+	})
+}
+
+// isRange reports whether from-to is a range of small numbers or of years.
+func isRange(from, to string) bool {
+	if from[0] == '0' || to[0] == '0' {
+		return false
+	}
+	if len(from) <= 2 && len(to) <= 2 {
+		return true
+	}
+	return len(from) == 4 && len(to) == 4 && (from[0] == '1' || from[0] == '2') && from < to
+}
+
+// sayNumber reads a run of digits as a year where it looks like one, or as a
+// whole number.
+func sayNumber(l *language, digits string) string {
+	if len(digits) == 4 {
+		if year, ok := sayYear(l, digits); ok {
+			return year
+		}
+	}
+	return spellInteger(l, digits, noGender)
+}
+
+// expandDigitGroups reads numbers joined by hyphens. Two small numbers or two
+// years make a range ("10-20", "1999-2005"); anything else, such as a phone
+// number ("555-1234"), is read digit by digit, group by group.
+func expandDigitGroups(text string, l *language) string {
+	return replaceAll(digitGroups, text, func(m []string, before, after string) (string, bool) {
+		if !standalone(before, after) || strings.HasSuffix(before, "-") || strings.HasPrefix(after, "-") {
+			return "", false
+		}
+		groups := strings.Split(m[0], "-")
+		if len(groups) == 2 && isRange(groups[0], groups[1]) {
+			return sayNumber(l, groups[0]) + " " + l.through + " " + sayNumber(l, groups[1]), true
+		}
+		for i, g := range groups {
+			groups[i] = spellDigits(l, g)
+		}
+		return strings.Join(groups, ", "), true
+	})
+}
+
+// spellSeparated reads something like a version or IP address: each digit
+// run on its own, with the separators spoken.
+func spellSeparated(l *language, token string) string {
+	var b strings.Builder
+	start := -1
+	for i := 0; i <= len(token); i++ {
+		if i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			b.WriteString(spellInteger(l, token[start:i], noGender))
+			start = -1
+		}
+		if i == len(token) {
+			break
+		}
+		switch token[i] {
+		case '.':
+			b.WriteString(" " + l.dot + " ")
+		case ',':
+			b.WriteString(", ")
+		default:
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+// Package textnorm rewrites text into a form that reads well when spoken:
+// Markdown is stripped and numbers, dates, currencies, units, URLs, e-mail
+// addresses and abbreviations are expanded into words.
+//
+// Language-specific rules support English, German and Polish. For other
+// languages, or when the language is not known, only the language-neutral
+// parts run (Markdown stripping, and URLs reduced to their host name).
+package textnorm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Rule names a set of normalization rules that can be toggled independently.
+type Rule string
+
+const (
+	RuleMarkdown      Rule = "markdown"
+	RuleURLs          Rule = "urls"
+	RuleEmails        Rule = "emails"
+	RuleAbbreviations Rule = "abbreviations"
+	RuleCurrency      Rule = "currency"
+	RuleDates         Rule = "dates"
+	RuleUnits         Rule = "units"
+	RuleNumbers       Rule = "numbers"
+)
+
+// AllRules lists every rule in the order it is applied. Numbers come last so
+// the other rules can leave digits behind for it to spell out.
+var AllRules = []Rule{
+	RuleMarkdown,
+	RuleURLs,
+	RuleEmails,
+	RuleAbbreviations,
+	RuleCurrency,
+	RuleDates,
+	RuleUnits,
+	RuleNumbers,
+}
+
+// ParseRules parses a comma-separated rule list. "all" (also the meaning of
+// an empty spec) enables every rule, "none" disables normalization, and a
+// "-rule" entry removes a rule, e.g. "all,-numbers".
+func ParseRules(spec string) ([]Rule, error) {
+	enabled := make(map[Rule]bool)
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	if spec == "" {
+		spec = "all"
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		remove := strings.HasPrefix(item, "-")
+		item = strings.TrimPrefix(item, "-")
+		switch item {
+		case "":
+			continue
+		case "all":
+			for _, r := range AllRules {
+				enabled[r] = !remove
+			}
+			continue
+		case "none":
+			clear(enabled)
+			continue
+		}
+		if !slices.Contains(AllRules, Rule(item)) {
+			return nil, fmt.Errorf("unknown text normalization rule %q", item)
+		}
+		enabled[Rule(item)] = !remove
+	}
+
+	var rules []Rule
+	for _, r := range AllRules {
+		if enabled[r] {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// Normalizer applies a fixed set of rules.
+type Normalizer struct {
+	rules []Rule
+}
+
+// New returns a Normalizer applying rules in AllRules order.
+func New(rules []Rule) *Normalizer {
+	enabled := make(map[Rule]bool, len(rules))
+	for _, r := range rules {
+		enabled[r] = true
+	}
+	n := &Normalizer{}
+	for _, r := range AllRules {
+		if enabled[r] {
+			n.rules = append(n.rules, r)
+		}
+	}
+	return n
+}
+
+// Rules returns the enabled rules in application order.
+func (n *Normalizer) Rules() []Rule {
+	return append([]Rule(nil), n.rules...)
+}
+
+// Normalize rewrites text for the given language, an ISO 639-1 code
+// optionally followed by a region ("en", "de-AT"). Unsupported languages and
+// "auto" get the language-neutral rules only.
+func (n *Normalizer) Normalize(text, language string) string {
+	if len(n.rules) == 0 {
+		return text
+	}
+	lang := lookupLanguage(language)
+	// With number spelling off, the other rules keep amounts as digits.
+	spell := slices.Contains(n.rules, RuleNumbers)
+	for _, r := range n.rules {
+		switch r {
+		case RuleMarkdown:
+			text = stripMarkdown(text)
+		case RuleURLs:
+			text = expandURLs(text, lang)
+		case RuleEmails:
+			text = expandEmails(text, lang)
+		}
+		if lang == nil {
+			continue
+		}
+		switch r {
+		case RuleAbbreviations:
+			text = expandAbbreviations(text, lang)
+		case RuleCurrency:
+			text = expandCurrency(text, lang, spell)
+		case RuleDates:
+			text = expandDates(text, lang, spell)
+		case RuleUnits:
+			text = expandUnits(text, lang, spell)
+		case RuleNumbers:
+			text = expandNumbers(text, lang)
+		}
+	}
+	return collapseSpaces(text)
+}
+
+var (
+	spaceRun     = regexp.MustCompile(`[ \t]+`)
+	blankLineRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// collapseSpaces tidies whitespace left behind by removed markup.
+func collapseSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLineRun.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
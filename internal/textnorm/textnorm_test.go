@@ -0,0 +1,146 @@
+package textnorm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	n := New(AllRules)
+
+	tests := []struct {
+		name string
+		lang string
+		in   string
+		want string
+	}{
+		{"markdown", "en", "**Hello** _there_, `code` and [docs](https://x.io)!", "Hello there, code and docs!"},
+		{"markdown_blocks", "en", "# Title\n\n- one\n- two\n\n```go\nfmt.Println(1)\n```\n\n> quoted", "Title\n\none\ntwo\n\nquoted"},
+		{"markdown_table", "en", "| a | b |\n|---|---|\n| x | y |", "a, b\n\nx, y"},
+		{"snake_case_kept", "en", "set max_retry_count now", "set max_retry_count now"},
+		{"url", "en", "See https://www.example.com/path?q=1.", "See example dot com."},
+		{"email", "en", "Mail john.doe@example.com today", "Mail john dot doe at example dot com today"},
+		{"currency", "en", "It costs $5.99 or €1,000.", "It costs five dollars and ninety-nine cents or one thousand euros."},
+		{"currency_cents_only", "en", "Just $0.05", "Just five cents"},
+		{"date_time", "en", "On 2024-03-15 at 10:05", "On March fifteenth, twenty twenty-four at ten oh five"},
+		{"month_day", "en", "Due March 3rd", "Due March third"},
+		{"units", "en", "20°C, 50% and 1 kg", "twenty degrees Celsius, fifty percent and one kilogram"},
+		{"abbreviations", "en", "Fruit, e.g. apples etc. Dr. Who", "Fruit, for example apples et cetera. Doctor Who"},
+		{"ordinal", "en", "the 21st and 112th", "the twenty-first and one hundred twelfth"},
+		{"numbers", "en", "-5 and 3.14 and 0042", "minus five and three point one four and zero zero four two"},
+		{"version", "en", "Go 1.22.3", "Go one dot twenty-two dot three"},
+		{"glued_digits_kept", "en", "mp3 and 3D", "mp3 and 3D"},
+		{"years", "en", "In 1999, 1905, 1900, 2005 and 2024", "In nineteen ninety-nine, nineteen oh five, nineteen hundred, two thousand five and twenty twenty-four"},
+		{"year_range", "en", "from 1999-2005", "from nineteen ninety-nine to two thousand five"},
+		{"small_range", "en", "wait 5-10 minutes", "wait five to ten minutes"},
+		{"ratio", "en", "a 3:2 or 16:9 screen", "a three to two or sixteen to nine screen"},
+		{"phone_number", "en", "Call 555-1234 or 1-800-555-0199", "Call five five five, one two three four or one, eight zero zero, five five five, zero one nine nine"},
+		{"intraword_stars_kept", "en", "a*b*c and *this*", "a*b*c and this"},
+		{"adjacent_emphasis", "en", "*one* _two_ *three*", "one two three"},
+		{"region_code", "en-GB", "5 km", "five kilometers"},
+
+		{"de_currency", "de", "Es kostet 5,99 € bzw. 1.000 €.", "Es kostet fünf Euro neunundneunzig Cent beziehungsweise eintausend Euro."},
+		{"de_date", "de", "am 15.03.2024 um 1:00", "am fünfzehnten März zweitausendvierundzwanzig um ein Uhr"},
+		{"de_units", "de", "1 h und 2 h", "eine Stunde und zwei Stunden"},
+		{"de_numbers", "de", "1, 21 und 101", "eins, einundzwanzig und einhunderteins"},
+		{"de_years", "de", "1999 bis 2024, 1950-1960", "neunzehnhundertneunundneunzig bis zweitausendvierundzwanzig, neunzehnhundertfünfzig bis neunzehnhundertsechzig"},
+		{"de_ratio", "de", "im Verhältnis 3:2", "im Verhältnis drei zu zwei"},
+		{"de_abbreviation", "de", "z. B. Äpfel usw. Dann", "zum Beispiel Äpfel und so weiter. Dann"},
+
+		{"pl_currency", "pl", "Kosztuje 5,99 zł i 5 000 zł", "Kosztuje pięć złotych dziewięćdziesiąt dziewięć groszy i pięć tysięcy złotych"},
+		{"pl_units", "pl", "2 min, 22 s, 1 h, 12 kg", "dwie minuty, dwadzieścia dwie sekundy, jedna godzina, dwanaście kilogramów"},
+		{"pl_scale", "pl", "2 mln i 1 000 000", "dwa miliony i milion"},
+		{"pl_date", "pl", "15.03.2024 o 10:30", "piętnastego marca dwa tysiące dwadzieścia cztery o dziesiąta trzydzieści"},
+		{"pl_abbreviation", "pl", "ok. 3 km, np. tak, ok.", "około trzy kilometry, na przykład tak, ok."},
+
+		{"unsupported_language", "fr", "**Bonjour** 5 km https://www.example.fr/x", "Bonjour 5 km example.fr"},
+		{"auto", "auto", "Visit www.nupi.ai for 5 km", "Visit nupi.ai for 5 km"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Normalize(tt.in, tt.lang); got != tt.want {
+				t.Errorf("Normalize(%q, %q)\n got %q\nwant %q", tt.in, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeWithoutNumbers(t *testing.T) {
+	rules, err := ParseRules("all,-numbers")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	n := New(rules)
+
+	in := "It costs $5.99, about 5 km, at 10:00."
+	want := "It costs 5 dollars and 99 cents, about 5 kilometers, at 10 o'clock."
+	if got := n.Normalize(in, "en"); got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeTimesWithoutDates(t *testing.T) {
+	n := New([]Rule{RuleNumbers})
+	in := "Leave at 10:05, win 3:2"
+	want := "Leave at ten oh five, win three to two"
+	if got := n.Normalize(in, "en"); got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeNoRules(t *testing.T) {
+	in := "**keep** $5  as is\n"
+	if got := New(nil).Normalize(in, "en"); got != in {
+		t.Errorf("Normalize = %q, want input unchanged", got)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Rule
+		wantErr bool
+	}{
+		{"", AllRules, false},
+		{"all", AllRules, false},
+		{"none", nil, false},
+		{"numbers, Markdown", []Rule{RuleMarkdown, RuleNumbers}, false},
+		{"all,-numbers,-urls", []Rule{RuleMarkdown, RuleEmails, RuleAbbreviations, RuleCurrency, RuleDates, RuleUnits}, false},
+		{"none,urls", []Rule{RuleURLs}, false},
+		{"markdown,emoji", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRules(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRules(%q) err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRules(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestCardinals(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int64
+		want string
+	}{
+		{"en", 0, "zero"},
+		{"en", 1_000_001, "one million one"},
+		{"en", 2_500_000_000, "two billion five hundred million"},
+		{"de", 1_000_000, "eine Million"},
+		{"de", 2_101_000, "zwei Millionen einhunderteintausend"},
+		{"pl", 1_000, "tysiąc"},
+		{"pl", 22_000, "dwadzieścia dwa tysiące"},
+		{"pl", 15_000, "piętnaście tysięcy"},
+		{"pl", 1_002_000, "milion dwa tysiące"},
+	}
+	for _, tt := range tests {
+		l := lookupLanguage(tt.lang)
+		if got := l.cardinal(tt.n, noGender); got != tt.want {
+			t.Errorf("%s cardinal(%d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
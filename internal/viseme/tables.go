@@ -0,0 +1,103 @@
+package viseme
+
+import (
+	"strings"
+	"unicode"
+)
+
+// table maps the graphemes of one language to visemes. A grapheme mapped to
+// "" is silent and extends the neighbouring visemes.
+type table struct {
+	graphemes map[string]string
+	prefixes  map[string]bool // proper prefixes of multi-letter graphemes
+	maxLen    int             // longest grapheme, in characters
+}
+
+func newTable(graphemes map[string]string) *table {
+	t := &table{graphemes: graphemes, prefixes: make(map[string]bool)}
+	for g := range graphemes {
+		runes := []rune(g)
+		t.maxLen = max(t.maxLen, len(runes))
+		for i := 1; i < len(runes); i++ {
+			t.prefixes[string(runes[:i])] = true
+		}
+	}
+	return t
+}
+
+// match returns the number of timings forming the longest grapheme at the
+// start of timings and its viseme.
+func (t *table) match(timings []Timing) (int, string) {
+	for n := min(t.maxLen, len(timings)); n > 1; n-- {
+		if v, ok := t.graphemes[joinChars(timings[:n])]; ok {
+			return n, v
+		}
+	}
+	c := strings.ToLower(timings[0].Char)
+	if v, ok := t.graphemes[c]; ok {
+		return 1, v
+	}
+	if strings.IndexFunc(c, unicode.IsPunct) >= 0 {
+		return 1, Sil
+	}
+	return 1, "" // whitespace, digits and unknown letters
+}
+
+// isPrefix reports whether timings spell the beginning of a longer grapheme.
+func (t *table) isPrefix(timings []Timing) bool {
+	return len(timings) < t.maxLen && t.prefixes[joinChars(timings)]
+}
+
+func joinChars(timings []Timing) string {
+	var b strings.Builder
+	for _, t := range timings {
+		b.WriteString(strings.ToLower(t.Char))
+	}
+	return b.String()
+}
+
+// base holds the letters that look alike in all supported languages.
+var base = map[string]string{
+	"a": AA, "e": E, "i": I, "o": O, "u": U, "y": I,
+	"b": PP, "m": PP, "p": PP,
+	"f": FF, "v": FF,
+	"d": DD, "t": DD,
+	"c": KK, "g": KK, "k": KK, "q": KK, "x": KK,
+	"j": CH,
+	"s": SS, "z": SS,
+	"l": NN, "n": NN,
+	"r": RR,
+	"w": U,
+	"h": "",
+}
+
+func extend(overrides map[string]string) map[string]string {
+	m := make(map[string]string, len(base)+len(overrides))
+	for g, v := range base {
+		m[g] = v
+	}
+	for g, v := range overrides {
+		m[g] = v
+	}
+	return m
+}
+
+var tables = map[string]*table{
+	"en": newTable(extend(map[string]string{
+		"th": TH, "sh": CH, "ch": CH, "ph": FF, "ng": NN, "ck": KK, "qu": KK,
+		"wh": U, "ee": I, "ea": I, "oo": U, "ou": AA, "ow": O, "ai": E, "ay": E,
+		"oi": O, "oy": O,
+	})),
+	"de": newTable(extend(map[string]string{
+		"sch": CH, "tsch": CH, "ch": KK, "ph": FF, "th": DD, "ng": NN, "ck": KK,
+		"qu": KK, "tz": SS, "pf": PP, "ei": AA, "ai": AA, "ie": I, "eu": O,
+		"äu": O, "au": AA,
+		"ä": E, "ö": O, "ü": U, "ß": SS, "j": I, "w": FF, "z": SS,
+	})),
+	"pl": newTable(extend(map[string]string{
+		"szcz": CH, "sz": CH, "cz": CH, "rz": CH, "dż": CH, "dź": CH, "dz": SS,
+		"ch": KK, "ci": CH, "si": CH, "zi": CH, "ni": NN,
+		"ś": CH, "ć": CH, "ź": CH, "ż": CH, "ń": NN, "ł": U, "ą": O, "ę": E,
+		"ó": U, "c": SS, "j": I, "w": FF,
+	})),
+}
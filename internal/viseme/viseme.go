@@ -0,0 +1,173 @@
+// Package viseme derives lip-sync mouth shapes from character timings.
+//
+// Visemes follow the Oculus set of 15 shapes (sil, PP, FF, TH, DD, kk, CH,
+// SS, nn, RR, aa, E, I, O, U). A Mapper converts timed characters into
+// visemes using per-language grapheme tables for English, German and
+// Polish, so "sch" in German or "sz" in Polish becomes a single CH. When no
+// character timings are available, Estimate spreads the text over the audio
+// duration instead.
+package viseme
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The Oculus viseme set.
+const (
+	Sil = "sil"
+	PP  = "PP" // p, b, m
+	FF  = "FF" // f, v
+	TH  = "TH" // th
+	DD  = "DD" // t, d
+	KK  = "kk" // k, g
+	CH  = "CH" // ch, sh, j
+	SS  = "SS" // s, z
+	NN  = "nn" // n, l
+	RR  = "RR" // r
+	AA  = "aa"
+	E   = "E"
+	I   = "I"
+	O   = "O"
+	U   = "U"
+)
+
+// Timing is when one character is spoken.
+type Timing struct {
+	Char       string
+	Start, End time.Duration
+}
+
+// Event is one mouth shape held from Start to End.
+type Event struct {
+	Viseme     string
+	Start, End time.Duration
+}
+
+// Mapper turns a stream of character timings into viseme events. Characters
+// that may begin a multi-letter grapheme are held back until the next Push or
+// Flush decides it. Markup tags in the text produce no visemes.
+type Mapper struct {
+	table   *table
+	pending []Timing
+	inTag   bool
+}
+
+// NewMapper returns a Mapper for the language code lang ("en", "de-AT",
+// "pl"); unsupported languages use the English table.
+func NewMapper(lang string) *Mapper {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	t, ok := tables[lang]
+	if !ok {
+		t = tables["en"]
+	}
+	return &Mapper{table: t}
+}
+
+// Push adds timings and returns the events they complete.
+func (m *Mapper) Push(timings ...Timing) []Event {
+	for _, t := range timings {
+		switch {
+		case t.Char == "<":
+			m.inTag = true
+		case t.Char == ">":
+			m.inTag = false
+		case !m.inTag:
+			m.pending = append(m.pending, t)
+		}
+	}
+	return m.drain(false)
+}
+
+// Flush returns the events for all held-back characters.
+func (m *Mapper) Flush() []Event {
+	return m.drain(true)
+}
+
+func (m *Mapper) drain(final bool) []Event {
+	var events []Event
+	i := 0
+	for i < len(m.pending) {
+		rest := m.pending[i:]
+		if !final && m.table.isPrefix(rest) {
+			break // a later character may extend the grapheme
+		}
+		n, viseme := m.table.match(rest)
+		g := rest[:n]
+		if viseme != "" {
+			events = appendEvent(events, Event{Viseme: viseme, Start: g[0].Start, End: g[n-1].End})
+		}
+		i += n
+	}
+	m.pending = append(m.pending[:0], m.pending[i:]...)
+	return events
+}
+
+// appendEvent adds e, extending the previous event when it has the same
+// viseme.
+func appendEvent(events []Event, e Event) []Event {
+	if n := len(events); n > 0 && events[n-1].Viseme == e.Viseme {
+		events[n-1].End = e.End
+		return events
+	}
+	return append(events, e)
+}
+
+// DefaultCharsPerSecond is the speaking rate Estimate assumes when the audio
+// duration is unknown.
+const DefaultCharsPerSecond = 14
+
+// Estimate spreads text over duration in proportion to the characters,
+// giving punctuation extra weight for the pause it causes. A zero duration
+// is estimated from DefaultCharsPerSecond. Markup tags are skipped.
+func Estimate(text string, duration time.Duration) []Timing {
+	type weighted struct {
+		char   string
+		weight float64
+	}
+	var chars []weighted
+	var total float64
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+			continue
+		case r == '>':
+			inTag = false
+			continue
+		case inTag:
+			continue
+		}
+		w := 1.0
+		switch {
+		case strings.ContainsRune(".!?…", r):
+			w = 4
+		case strings.ContainsRune(",;:–—", r):
+			w = 2
+		case unicode.IsSpace(r):
+			w = 0.5
+		}
+		chars = append(chars, weighted{string(r), w})
+		total += w
+	}
+	if total == 0 {
+		return nil
+	}
+	if duration <= 0 {
+		duration = time.Duration(float64(len(chars)) / DefaultCharsPerSecond * float64(time.Second))
+	}
+
+	timings := make([]Timing, len(chars))
+	var acc float64
+	for i, c := range chars {
+		start := time.Duration(acc / total * float64(duration))
+		acc += c.weight
+		timings[i] = Timing{Char: c.char, Start: start, End: time.Duration(acc / total * float64(duration))}
+	}
+	return timings
+}
@@ -0,0 +1,123 @@
+package viseme
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// timed gives each character of text 100ms.
+func timed(text string) []Timing {
+	var timings []Timing
+	for i, r := range []rune(text) {
+		start := time.Duration(i) * 100 * time.Millisecond
+		timings = append(timings, Timing{Char: string(r), Start: start, End: start + 100*time.Millisecond})
+	}
+	return timings
+}
+
+func visemes(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Viseme)
+	}
+	return out
+}
+
+func TestMapper(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		in   string
+		want []string
+	}{
+		{"en", "en", "the mouth", []string{TH, E, PP, AA, TH}},
+		{"en_punct", "en-US", "Fish, chips.", []string{FF, I, CH, Sil, CH, I, PP, SS, Sil}},
+		{"en_merge", "en", "see", []string{SS, I}},
+		{"de", "de", "Schule", []string{CH, U, NN, E}},
+		{"de_umlaut", "de-AT", "Bücher", []string{PP, U, KK, E, RR}},
+		{"pl", "pl", "szczaw", []string{CH, AA, FF}},
+		{"pl_digraphs", "pl-PL", "rzeka", []string{CH, E, KK, AA}},
+		{"unknown_lang", "xx", "map", []string{PP, AA, PP}},
+		{"tags", "en", `a <break time="1s" /> o`, []string{AA, O}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMapper(tt.lang)
+			got := append(m.Push(timed(tt.in)...), m.Flush()...)
+			if !reflect.DeepEqual(visemes(got), tt.want) {
+				t.Errorf("visemes(%q) = %q, want %q", tt.in, visemes(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMapperTimes(t *testing.T) {
+	m := NewMapper("en")
+	got := append(m.Push(timed("thee")...), m.Flush()...)
+	want := []Event{
+		{Viseme: TH, Start: 0, End: 200 * time.Millisecond},
+		{Viseme: I, Start: 200 * time.Millisecond, End: 400 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+func TestMapperHoldsGraphemePrefix(t *testing.T) {
+	timings := timed("Schade")
+	m := NewMapper("de")
+
+	// "Sc" may still become "Sch": nothing is decided yet.
+	if got := m.Push(timings[:2]...); len(got) != 0 {
+		t.Fatalf("Push(Sc) = %+v, want nothing", got)
+	}
+	got := m.Push(timings[2:4]...)
+	if want := []string{CH}; !reflect.DeepEqual(visemes(got), want) {
+		t.Fatalf("Push(ha) = %q, want %q", visemes(got), want)
+	}
+	if got[0].Start != 0 || got[0].End != 300*time.Millisecond {
+		t.Errorf("CH at %v-%v, want 0s-300ms", got[0].Start, got[0].End)
+	}
+	rest := append(got[1:], m.Push(timings[4:]...)...)
+	rest = append(rest, m.Flush()...)
+	if want := []string{AA, DD, E}; !reflect.DeepEqual(visemes(rest), want) {
+		t.Errorf("remaining visemes = %q, want %q", visemes(rest), want)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	got := Estimate("ab. c", 1000*time.Millisecond)
+	// Weights: a 1, b 1, "." 4, " " 0.5, c 1 — 7.5 in total.
+	want := []Timing{
+		{"a", 0, 133333333},
+		{"b", 133333333, 266666666},
+		{".", 266666666, 800000000},
+		{" ", 800000000, 866666666},
+		{"c", 866666666, 1000000000},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Estimate = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Char != want[i].Char ||
+			(got[i].Start-want[i].Start).Abs() > time.Microsecond ||
+			(got[i].End-want[i].End).Abs() > time.Microsecond {
+			t.Errorf("timing %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEstimateUnknownDuration(t *testing.T) {
+	got := Estimate(`<break time="1s" />Hello there`, 0)
+	if len(got) != len("Hello there") {
+		t.Fatalf("Estimate returned %d timings, want %d", len(got), len("Hello there"))
+	}
+	want := time.Duration(float64(len(got)) / DefaultCharsPerSecond * float64(time.Second))
+	if end := got[len(got)-1].End; (end - want).Abs() > time.Microsecond {
+		t.Errorf("last timing ends at %v, want %v", end, want)
+	}
+	if Estimate("", time.Second) != nil {
+		t.Error("Estimate of empty text should be nil")
+	}
+}